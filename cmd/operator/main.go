@@ -28,12 +28,12 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/sync/errgroup"
-	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	cmo "github.com/openshift/cluster-monitoring-operator/pkg/operator"
+	"github.com/openshift/cluster-monitoring-operator/pkg/telemetry"
 )
 
 type images map[string]string
@@ -79,10 +79,6 @@ func (i *images) Type() string {
 	return "map[string]string"
 }
 
-type telemetryConfig struct {
-	Matches []string `json:"matches"`
-}
-
 func Main() int {
 	flagset := flag.CommandLine
 	klog.InitFlags(flagset)
@@ -93,9 +89,10 @@ func Main() int {
 	kubeconfigPath := flagset.String("kubeconfig", "", "The path to the kubeconfig to connect to the apiserver with.")
 	apiserver := flagset.String("apiserver", "", "The address of the apiserver to talk to.")
 	releaseVersion := flagset.String("release-version", "", "Currently targeted release version to be reconciled against.")
-	telemetryConfigFile := flagset.String("telemetry-config", "/etc/cluster-monitoring-operator/telemetry/metrics.yaml", "Path to telemetry-config.")
+	telemetryConfigFile := flagset.String("telemetry-config", "/etc/cluster-monitoring-operator/telemetry/metrics.yaml", "Path to telemetry-config. The matches are updated from the telemetry-config ConfigMap at runtime.")
 	remoteWrite := flagset.Bool("enabled-remote-write", false, "Wether to use legacy telemetry write protocol or Prometheus remote write.")
 	assetsPath := flagset.String("assets", "/assets", "The path to the assets directory.")
+	lintRules := flagset.Bool("lint-rules", false, "Check the PrometheusRule assets at startup and report the problems in the Degraded condition.")
 	images := images{}
 	flag.Var(&images, "images", "Images to use for containers managed by the cluster-monitoring-operator.")
 	flag.Parse()
@@ -106,8 +103,7 @@ func Main() int {
 		return 1
 	}
 
-	telemetryConfig := telemetryConfig{}
-	err = yaml.NewYAMLOrJSONDecoder(f, 100).Decode(&telemetryConfig)
+	telemetryConfig, err := manifests.NewTelemetryConfig(f)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Could not parse telemetry config file: %v", err)
 		return 1
@@ -190,6 +186,10 @@ func Main() int {
 		return 1
 	}
 
+	if *lintRules {
+		o.LintRules()
+	}
+
 	o.RegisterMetrics(r)
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.HandlerFor(r, promhttp.HandlerOpts{}))
@@ -198,6 +198,15 @@ func Main() int {
 	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
 	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// The preview is served behind kube-rbac-proxy which requires the get
+	// verb on the /telemetry/preview non-resource URL, granted by the
+	// cluster-monitoring-view cluster role.
+	if q, err := telemetry.NewPrometheusQuerier(*namespace); err != nil {
+		klog.Warningf("telemetry preview disabled: %v", err)
+	} else {
+		mux.Handle("/telemetry/preview", telemetry.NewHandler(q, o.TelemetryConfig))
+	}
 	go http.ListenAndServe("127.0.0.1:8080", mux)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -205,7 +214,7 @@ func Main() int {
 
 	wg.Go(func() error { return o.Run(ctx.Done()) })
 
-	term := make(chan os.Signal)
+	term := make(chan os.Signal, 1)
 	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
 
 	select {
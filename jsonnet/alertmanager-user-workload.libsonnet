@@ -0,0 +1,222 @@
+// Alertmanager dedicated to user workload monitoring. It is deployed in the
+// user workload monitoring namespace and only receives alerts from the user
+// workload Prometheus and Thanos Ruler instances.
+//
+// The API is exposed through kube-rbac-proxy which authorizes clients against
+// the non-resource URL of the request, the alerts senders are granted access
+// via the alertmanager-user-workload-alerts-sender ClusterRole.
+
+function(params)
+  local cfg = params;
+  local fullName = 'alertmanager-' + cfg.name;
+  local tlsSecretName = fullName + '-tls';
+
+  local labels = {
+    'app.kubernetes.io/name': 'alertmanager',
+    'app.kubernetes.io/component': 'alert-router',
+    'app.kubernetes.io/version': cfg.version,
+  } + cfg.commonLabels;
+
+  local selectorLabels = {
+    alertmanager: cfg.name,
+    app: 'alertmanager',
+  };
+
+  {
+    serviceAccount: {
+      apiVersion: 'v1',
+      kind: 'ServiceAccount',
+      metadata: {
+        name: fullName,
+        namespace: cfg.namespace,
+        labels: labels { alertmanager: cfg.name },
+      },
+    },
+
+    // Default configuration, it is created once and owned by the cluster
+    // admins afterwards.
+    secret: {
+      apiVersion: 'v1',
+      kind: 'Secret',
+      type: 'Opaque',
+      metadata: {
+        name: fullName,
+        namespace: cfg.namespace,
+        labels: labels { alertmanager: cfg.name },
+      },
+      stringData: {
+        'alertmanager.yaml': std.manifestYamlDoc({
+          route: {
+            receiver: 'Default',
+            group_by: ['namespace'],
+          },
+          receivers: [{ name: 'Default' }],
+        }),
+      },
+    },
+
+    service: {
+      apiVersion: 'v1',
+      kind: 'Service',
+      metadata: {
+        name: fullName,
+        namespace: cfg.namespace,
+        labels: labels { alertmanager: cfg.name },
+        annotations: {
+          'service.beta.openshift.io/serving-cert-secret-name': tlsSecretName,
+        },
+      },
+      spec: {
+        ports: [
+          { name: 'web', port: 9095, targetPort: 'web' },
+        ],
+        selector: selectorLabels + cfg.commonLabels {
+          'app.kubernetes.io/component': 'alert-router',
+          'app.kubernetes.io/name': 'alertmanager',
+        },
+        sessionAffinity: 'ClientIP',
+        type: 'ClusterIP',
+      },
+    },
+
+    // Permissions required by kube-rbac-proxy to authenticate and authorize
+    // requests.
+    clusterRole: {
+      apiVersion: 'rbac.authorization.k8s.io/v1',
+      kind: 'ClusterRole',
+      metadata: {
+        name: fullName,
+        labels: labels,
+      },
+      rules: [
+        {
+          apiGroups: ['authentication.k8s.io'],
+          resources: ['tokenreviews'],
+          verbs: ['create'],
+        },
+        {
+          apiGroups: ['authorization.k8s.io'],
+          resources: ['subjectaccessreviews'],
+          verbs: ['create'],
+        },
+      ],
+    },
+
+    clusterRoleBinding: {
+      apiVersion: 'rbac.authorization.k8s.io/v1',
+      kind: 'ClusterRoleBinding',
+      metadata: {
+        name: fullName,
+        labels: labels,
+      },
+      roleRef: {
+        apiGroup: 'rbac.authorization.k8s.io',
+        kind: 'ClusterRole',
+        name: fullName,
+      },
+      subjects: [{
+        kind: 'ServiceAccount',
+        name: fullName,
+        namespace: cfg.namespace,
+      }],
+    },
+
+    clusterRoleAlertsSender: {
+      apiVersion: 'rbac.authorization.k8s.io/v1',
+      kind: 'ClusterRole',
+      metadata: {
+        name: fullName + '-alerts-sender',
+        labels: labels,
+      },
+      rules: [{
+        nonResourceURLs: ['/api/v2/alerts'],
+        verbs: ['create'],
+      }],
+    },
+
+    clusterRoleBindingAlertsSender: {
+      apiVersion: 'rbac.authorization.k8s.io/v1',
+      kind: 'ClusterRoleBinding',
+      metadata: {
+        name: fullName + '-alerts-sender',
+        labels: labels,
+      },
+      roleRef: {
+        apiGroup: 'rbac.authorization.k8s.io',
+        kind: 'ClusterRole',
+        name: fullName + '-alerts-sender',
+      },
+      subjects: [
+        {
+          kind: 'ServiceAccount',
+          name: 'prometheus-user-workload',
+          namespace: cfg.namespace,
+        },
+        {
+          kind: 'ServiceAccount',
+          name: 'thanos-ruler',
+          namespace: cfg.namespace,
+        },
+      ],
+    },
+
+    alertmanager: {
+      apiVersion: 'monitoring.coreos.com/v1',
+      kind: 'Alertmanager',
+      metadata: {
+        name: cfg.name,
+        namespace: cfg.namespace,
+        labels: labels { alertmanager: cfg.name },
+      },
+      spec: {
+        replicas: 2,
+        version: cfg.version,
+        image: cfg.image,
+        listenLocal: true,
+        serviceAccountName: fullName,
+        priorityClassName: 'openshift-user-critical',
+        nodeSelector: { 'kubernetes.io/os': 'linux' },
+        podMetadata: {
+          labels: labels,
+        },
+        resources: {
+          requests: { cpu: '4m', memory: '40Mi' },
+        },
+        securityContext: {
+          fsGroup: 65534,
+          runAsNonRoot: true,
+          runAsUser: 65534,
+        },
+        secrets: [tlsSecretName],
+        containers: [
+          {
+            name: 'kube-rbac-proxy',
+            image: 'quay.io/coreos/kube-rbac-proxy:v0.8.0',  //FIXME(paulfantom)
+            args: [
+              '--secure-listen-address=0.0.0.0:9095',
+              '--upstream=http://127.0.0.1:9093',
+              '--tls-cert-file=/etc/tls/private/tls.crt',
+              '--tls-private-key-file=/etc/tls/private/tls.key',
+              '--tls-cipher-suites=TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305',  //FIXME(paulfantom)
+              '--logtostderr=true',
+            ],
+            ports: [{ containerPort: 9095, name: 'web' }],
+            resources: {
+              requests: { cpu: '1m', memory: '20Mi' },
+            },
+            terminationMessagePolicy: 'FallbackToLogsOnError',
+            volumeMounts: [{
+              mountPath: '/etc/tls/private',
+              name: 'secret-' + tlsSecretName,
+            }],
+          },
+          {
+            name: 'config-reloader',
+            resources: {
+              requests: { cpu: '1m', memory: '10Mi' },
+            },
+          },
+        ],
+      },
+    },
+  }
@@ -107,11 +107,19 @@ function(params) {
     metadata: {
       name: 'cluster-monitoring-view',
     },
-    rules: [{
-      apiGroups: [''],
-      resources: ['namespaces'],
-      verbs: ['get'],
-    }],
+    rules: [
+      {
+        apiGroups: [''],
+        resources: ['namespaces'],
+        verbs: ['get'],
+      },
+      {
+        // Required by kube-rbac-proxy in front of the operator to serve
+        // the telemetry preview.
+        nonResourceURLs: ['/telemetry/preview'],
+        verbs: ['get'],
+      },
+    ],
   },
 
   monitoringEditClusterRole: {
@@ -153,6 +161,19 @@ function(params) {
     }],
   },
 
+  alertRoutingEditClusterRole: {
+    apiVersion: 'rbac.authorization.k8s.io/v1',
+    kind: 'ClusterRole',
+    metadata: {
+      name: 'alert-routing-edit',
+    },
+    rules: [{
+      apiGroups: ['monitoring.coreos.com'],
+      resources: ['alertmanagerconfigs'],
+      verbs: ['*'],
+    }],
+  },
+
   userWorkloadConfigEditRole: {
     apiVersion: 'rbac.authorization.k8s.io/v1',
     kind: 'Role',
@@ -16,6 +16,7 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"net/url"
 	"reflect"
 	"strings"
@@ -39,6 +40,7 @@ import (
 	admissionv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	extensionsobj "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
@@ -48,6 +50,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -73,6 +76,8 @@ type Client struct {
 	mclient               monitoring.Interface
 	eclient               apiextensionsclient.Interface
 	aggclient             aggregatorclient.Interface
+	dclient               dynamic.Interface
+	events                *EventRecorder
 }
 
 func New(cfg *rest.Config, version string, namespace, userWorkloadNamespace string, namespaceSelector string) (*Client, error) {
@@ -116,6 +121,11 @@ func New(cfg *rest.Config, version string, namespace, userWorkloadNamespace stri
 		return nil, errors.Wrap(err, "creating kubernetes aggregator")
 	}
 
+	dclient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating dynamic client")
+	}
+
 	return &Client{
 		version:               version,
 		namespace:             namespace,
@@ -128,6 +138,8 @@ func New(cfg *rest.Config, version string, namespace, userWorkloadNamespace stri
 		mclient:               mclient,
 		eclient:               eclient,
 		aggclient:             aggclient,
+		dclient:               dclient,
+		events:                NewEventRecorder(kclient, namespace),
 	}, nil
 }
 
@@ -135,6 +147,14 @@ func (c *Client) KubernetesInterface() kubernetes.Interface {
 	return c.kclient
 }
 
+func (c *Client) EventRecorder() *EventRecorder {
+	return c.events
+}
+
+func (c *Client) MonitoringInterface() monitoring.Interface {
+	return c.mclient
+}
+
 func (c *Client) Namespace() string {
 	return c.namespace
 }
@@ -147,6 +167,26 @@ func (c *Client) SecretListWatchForNamespace(ns string) *cache.ListWatch {
 	return cache.NewListWatchFromClient(c.kclient.CoreV1().RESTClient(), "secrets", ns, fields.Everything())
 }
 
+func (c *Client) NamespaceListWatch() *cache.ListWatch {
+	return cache.NewListWatchFromClient(c.kclient.CoreV1().RESTClient(), "namespaces", "", fields.Everything())
+}
+
+func (c *Client) ServiceMonitorListWatchForNamespace(ns string) *cache.ListWatch {
+	return cache.NewListWatchFromClient(c.mclient.MonitoringV1().RESTClient(), "servicemonitors", ns, fields.Everything())
+}
+
+func (c *Client) PodMonitorListWatchForNamespace(ns string) *cache.ListWatch {
+	return cache.NewListWatchFromClient(c.mclient.MonitoringV1().RESTClient(), "podmonitors", ns, fields.Everything())
+}
+
+func (c *Client) ProbeListWatchForNamespace(ns string) *cache.ListWatch {
+	return cache.NewListWatchFromClient(c.mclient.MonitoringV1().RESTClient(), "probes", ns, fields.Everything())
+}
+
+func (c *Client) PrometheusRuleListWatchForNamespace(ns string) *cache.ListWatch {
+	return cache.NewListWatchFromClient(c.mclient.MonitoringV1().RESTClient(), "prometheusrules", ns, fields.Everything())
+}
+
 func (c *Client) InfrastructureListWatchForResource(ctx context.Context, resource string) *cache.ListWatch {
 	infrastructure := c.oscclient.ConfigV1().Infrastructures()
 
@@ -195,8 +235,12 @@ func (c *Client) CreateOrUpdateValidatingWebhookConfiguration(w *admissionv1.Val
 	admclient := c.kclient.AdmissionregistrationV1().ValidatingWebhookConfigurations()
 	existing, err := admclient.Get(context.TODO(), w.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := admclient.Create(context.TODO(), w, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating ValidatingWebhookConfiguration object failed")
+		created, err := admclient.Create(context.TODO(), w, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating ValidatingWebhookConfiguration object failed")
+		}
+		c.objectCreated("ValidatingWebhookConfiguration", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving ValidatingWebhookConfiguration object failed")
@@ -204,16 +248,24 @@ func (c *Client) CreateOrUpdateValidatingWebhookConfiguration(w *admissionv1.Val
 
 	required := w.DeepCopy()
 	required.ResourceVersion = existing.ResourceVersion
-	_, err = admclient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating ValidatingWebhookConfiguration object failed")
+	updated, err := admclient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating ValidatingWebhookConfiguration object failed")
+	}
+	c.objectUpdated("ValidatingWebhookConfiguration", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateOrUpdateSecurityContextConstraints(s *secv1.SecurityContextConstraints) error {
 	sccclient := c.ossclient.SecurityV1().SecurityContextConstraints()
 	existing, err := sccclient.Get(context.TODO(), s.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := sccclient.Create(context.TODO(), s, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating SecurityContextConstraints object failed")
+		created, err := sccclient.Create(context.TODO(), s, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating SecurityContextConstraints object failed")
+		}
+		c.objectCreated("SecurityContextConstraints", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving SecurityContextConstraints object failed")
@@ -224,16 +276,24 @@ func (c *Client) CreateOrUpdateSecurityContextConstraints(s *secv1.SecurityConte
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 	required.ResourceVersion = existing.ResourceVersion
 
-	_, err = sccclient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating SecurityContextConstraints object failed")
+	updated, err := sccclient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating SecurityContextConstraints object failed")
+	}
+	c.objectUpdated("SecurityContextConstraints", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateRouteIfNotExists(r *routev1.Route) error {
 	rclient := c.osrclient.RouteV1().Routes(r.GetNamespace())
 	_, err := rclient.Get(context.TODO(), r.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := rclient.Create(context.TODO(), r, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating Route object failed")
+		created, err := rclient.Create(context.TODO(), r, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating Route object failed")
+		}
+		c.objectCreated("Route", created)
+		return nil
 	}
 	return nil
 }
@@ -293,12 +353,39 @@ func (c *Client) NamespacesToMonitor() ([]string, error) {
 	return namespaceNames, nil
 }
 
+// NamespacesSelected returns the names of the namespaces which match the
+// label selector.
+func (c *Client) NamespacesSelected(selector *metav1.LabelSelector) ([]string, error) {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid label selector")
+	}
+
+	namespaces, err := c.kclient.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: s.String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing namespaces failed")
+	}
+
+	namespaceNames := make([]string, len(namespaces.Items))
+	for i, namespace := range namespaces.Items {
+		namespaceNames[i] = namespace.Name
+	}
+
+	return namespaceNames, nil
+}
+
 func (c *Client) CreateOrUpdatePrometheus(p *monv1.Prometheus) error {
 	pclient := c.mclient.MonitoringV1().Prometheuses(p.GetNamespace())
 	existing, err := pclient.Get(context.TODO(), p.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := pclient.Create(context.TODO(), p, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating Prometheus object failed")
+		created, err := pclient.Create(context.TODO(), p, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating Prometheus object failed")
+		}
+		c.objectCreated("Prometheus", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving Prometheus object failed")
@@ -308,16 +395,24 @@ func (c *Client) CreateOrUpdatePrometheus(p *monv1.Prometheus) error {
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
 	required.ResourceVersion = existing.ResourceVersion
-	_, err = pclient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating Prometheus object failed")
+	updated, err := pclient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating Prometheus object failed")
+	}
+	c.objectUpdated("Prometheus", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateOrUpdatePrometheusRule(p *monv1.PrometheusRule) error {
 	pclient := c.mclient.MonitoringV1().PrometheusRules(p.GetNamespace())
 	existing, err := pclient.Get(context.TODO(), p.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := pclient.Create(context.TODO(), p, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating PrometheusRule object failed")
+		created, err := pclient.Create(context.TODO(), p, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating PrometheusRule object failed")
+		}
+		c.objectCreated("PrometheusRule", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving PrometheusRule object failed")
@@ -328,16 +423,24 @@ func (c *Client) CreateOrUpdatePrometheusRule(p *monv1.PrometheusRule) error {
 
 	required.ResourceVersion = existing.ResourceVersion
 
-	_, err = pclient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating PrometheusRule object failed")
+	updated, err := pclient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating PrometheusRule object failed")
+	}
+	c.objectUpdated("PrometheusRule", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateOrUpdateAlertmanager(a *monv1.Alertmanager) error {
 	aclient := c.mclient.MonitoringV1().Alertmanagers(a.GetNamespace())
 	existing, err := aclient.Get(context.TODO(), a.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := aclient.Create(context.TODO(), a, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating Alertmanager object failed")
+		created, err := aclient.Create(context.TODO(), a, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating Alertmanager object failed")
+		}
+		c.objectCreated("Alertmanager", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving Alertmanager object failed")
@@ -348,16 +451,24 @@ func (c *Client) CreateOrUpdateAlertmanager(a *monv1.Alertmanager) error {
 
 	required.ResourceVersion = existing.ResourceVersion
 
-	_, err = aclient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating Alertmanager object failed")
+	updated, err := aclient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating Alertmanager object failed")
+	}
+	c.objectUpdated("Alertmanager", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateOrUpdateThanosRuler(t *monv1.ThanosRuler) error {
 	trclient := c.mclient.MonitoringV1().ThanosRulers(t.GetNamespace())
 	existing, err := trclient.Get(context.TODO(), t.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := trclient.Create(context.TODO(), t, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating Thanos Ruler object failed")
+		created, err := trclient.Create(context.TODO(), t, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating Thanos Ruler object failed")
+		}
+		c.objectCreated("ThanosRuler", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving Thanos Ruler object failed")
@@ -367,8 +478,12 @@ func (c *Client) CreateOrUpdateThanosRuler(t *monv1.ThanosRuler) error {
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 	required.ResourceVersion = existing.ResourceVersion
 
-	_, err = trclient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating Thanos Ruler object failed")
+	updated, err := trclient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating Thanos Ruler object failed")
+	}
+	c.objectUpdated("ThanosRuler", existing, updated)
+	return nil
 }
 
 func (c *Client) DeleteConfigMap(cm *v1.ConfigMap) error {
@@ -376,8 +491,12 @@ func (c *Client) DeleteConfigMap(cm *v1.ConfigMap) error {
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("ConfigMap", cm)
+	return nil
 }
 
 // DeleteHashedConfigMap deletes all configmaps in the given namespace which have
@@ -396,6 +515,7 @@ func (c *Client) DeleteHashedConfigMap(namespace, prefix, newHash string) error
 		if err != nil {
 			return errors.Wrapf(err, "error deleting configmap: %s/%s", namespace, cm.Name)
 		}
+		c.objectDeleted("ConfigMap", &cm)
 	}
 
 	return nil
@@ -417,6 +537,7 @@ func (c *Client) DeleteHashedSecret(namespace, prefix, newHash string) error {
 		if err != nil {
 			return errors.Wrapf(err, "error deleting secret: %s/%s", namespace, s.Name)
 		}
+		c.objectDeleted("Secret", &s)
 	}
 
 	return nil
@@ -427,18 +548,30 @@ func (c *Client) DeleteValidatingWebhook(w *admissionv1.ValidatingWebhookConfigu
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("ValidatingWebhookConfiguration", w)
+	return nil
 }
 
 func (c *Client) DeleteDeployment(d *appsv1.Deployment) error {
-	p := metav1.DeletePropagationForeground
-	err := c.kclient.AppsV1().Deployments(d.GetNamespace()).Delete(context.TODO(), d.GetName(), metav1.DeleteOptions{PropagationPolicy: &p})
+	err := c.deleteDeployment(d)
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("Deployment", d)
+	return nil
+}
+
+func (c *Client) deleteDeployment(d *appsv1.Deployment) error {
+	p := metav1.DeletePropagationForeground
+	return c.kclient.AppsV1().Deployments(d.GetNamespace()).Delete(context.TODO(), d.GetName(), metav1.DeleteOptions{PropagationPolicy: &p})
 }
 
 func (c *Client) DeletePrometheus(p *monv1.Prometheus) error {
@@ -448,6 +581,9 @@ func (c *Client) DeletePrometheus(p *monv1.Prometheus) error {
 	if err != nil && !apierrors.IsNotFound(err) {
 		return errors.Wrap(err, "deleting Prometheus object failed")
 	}
+	if err == nil {
+		c.objectDeleted("Prometheus", p)
+	}
 
 	var lastErr error
 	if err := wait.Poll(time.Second*10, time.Minute*10, func() (bool, error) {
@@ -478,6 +614,9 @@ func (c *Client) DeleteThanosRuler(tr *monv1.ThanosRuler) error {
 	if err != nil && !apierrors.IsNotFound(err) {
 		return errors.Wrap(err, "deleting Thanos Ruler object failed")
 	}
+	if err == nil {
+		c.objectDeleted("ThanosRuler", tr)
+	}
 
 	var lastErr error
 	if err := wait.Poll(time.Second*10, time.Minute*10, func() (bool, error) {
@@ -501,14 +640,55 @@ func (c *Client) DeleteThanosRuler(tr *monv1.ThanosRuler) error {
 	return nil
 }
 
+func (c *Client) DeleteAlertmanager(a *monv1.Alertmanager) error {
+	aclient := c.mclient.MonitoringV1().Alertmanagers(a.GetNamespace())
+
+	err := aclient.Delete(context.TODO(), a.GetName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "deleting Alertmanager object failed")
+	}
+	if err == nil {
+		c.objectDeleted("Alertmanager", a)
+	}
+
+	var lastErr error
+	if err := wait.Poll(time.Second*10, time.Minute*10, func() (bool, error) {
+		pods, err := c.KubernetesInterface().CoreV1().Pods(a.GetNamespace()).List(context.TODO(), alertmanager.ListOptions(a.GetName()))
+		if err != nil {
+			return false, errors.Wrap(err, "retrieving pods during polling failed")
+		}
+
+		klog.V(6).Infof("waiting for %d Pods to be deleted", len(pods.Items))
+		klog.V(6).Infof("done waiting? %t", len(pods.Items) == 0)
+
+		lastErr = errors.Errorf("%d pods still present", len(pods.Items))
+		return len(pods.Items) == 0, nil
+	}); err != nil {
+		if err == wait.ErrWaitTimeout && lastErr != nil {
+			err = lastErr
+		}
+		return errors.Wrapf(err, "waiting for Alertmanager %s/%s deletion", a.GetNamespace(), a.GetName())
+	}
+
+	return nil
+}
+
 func (c *Client) DeleteDaemonSet(d *appsv1.DaemonSet) error {
-	orphanDependents := false
-	err := c.kclient.AppsV1().DaemonSets(d.GetNamespace()).Delete(context.TODO(), d.GetName(), metav1.DeleteOptions{OrphanDependents: &orphanDependents})
+	err := c.deleteDaemonSet(d)
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("DaemonSet", d)
+	return nil
+}
+
+func (c *Client) deleteDaemonSet(d *appsv1.DaemonSet) error {
+	orphanDependents := false
+	return c.kclient.AppsV1().DaemonSets(d.GetNamespace()).Delete(context.TODO(), d.GetName(), metav1.DeleteOptions{OrphanDependents: &orphanDependents})
 }
 
 func (c *Client) DeleteServiceMonitor(sm *monv1.ServiceMonitor) error {
@@ -523,6 +703,9 @@ func (c *Client) DeleteServiceMonitorByNamespaceAndName(namespace, name string)
 	if err != nil && !apierrors.IsNotFound(err) {
 		return errors.Wrap(err, "deleting ServiceMonitor object failed")
 	}
+	if err == nil {
+		c.objectDeleted("ServiceMonitor", &metav1.ObjectMeta{Namespace: namespace, Name: name})
+	}
 
 	return nil
 }
@@ -532,8 +715,12 @@ func (c *Client) DeleteServiceAccount(sa *v1.ServiceAccount) error {
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("ServiceAccount", sa)
+	return nil
 }
 
 func (c *Client) DeleteClusterRole(cr *rbacv1.ClusterRole) error {
@@ -541,8 +728,12 @@ func (c *Client) DeleteClusterRole(cr *rbacv1.ClusterRole) error {
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("ClusterRole", cr)
+	return nil
 }
 
 func (c *Client) DeleteClusterRoleBinding(crb *rbacv1.ClusterRoleBinding) error {
@@ -550,8 +741,12 @@ func (c *Client) DeleteClusterRoleBinding(crb *rbacv1.ClusterRoleBinding) error
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("ClusterRoleBinding", crb)
+	return nil
 }
 
 func (c *Client) DeleteService(svc *v1.Service) error {
@@ -559,8 +754,12 @@ func (c *Client) DeleteService(svc *v1.Service) error {
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("Service", svc)
+	return nil
 }
 
 func (c *Client) DeleteRoute(r *routev1.Route) error {
@@ -568,7 +767,12 @@ func (c *Client) DeleteRoute(r *routev1.Route) error {
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
-	return err
+	if err != nil {
+		return err
+	}
+
+	c.objectDeleted("Route", r)
+	return nil
 }
 
 func (c *Client) DeletePrometheusRule(rule *monv1.PrometheusRule) error {
@@ -583,6 +787,9 @@ func (c *Client) DeletePrometheusRuleByNamespaceAndName(namespace, name string)
 	if err != nil && !apierrors.IsNotFound(err) {
 		return errors.Wrap(err, "deleting PrometheusRule object failed")
 	}
+	if err == nil {
+		c.objectDeleted("PrometheusRule", &metav1.ObjectMeta{Namespace: namespace, Name: name})
+	}
 
 	return nil
 }
@@ -592,10 +799,16 @@ func (c *Client) DeleteSecret(s *v1.Secret) error {
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("Secret", s)
+	return nil
 }
 
+// WaitForPrometheus waits until all the replicas of every Prometheus shard
+// are updated and ready.
 func (c *Client) WaitForPrometheus(p *monv1.Prometheus) error {
 	var lastErr error
 	if err := wait.Poll(time.Second*10, time.Minute*5, func() (bool, error) {
@@ -603,21 +816,32 @@ func (c *Client) WaitForPrometheus(p *monv1.Prometheus) error {
 		if err != nil {
 			return false, errors.Wrap(err, "retrieving Prometheus object failed")
 		}
-		status, _, err := prometheusoperator.PrometheusStatus(context.TODO(), c.kclient.(*kubernetes.Clientset), p)
-		if err != nil {
-			return false, errors.Wrap(err, "retrieving Prometheus status failed")
-		}
 
 		expectedReplicas := *p.Spec.Replicas
-		if expectedReplicas != status.UpdatedReplicas {
-			lastErr = errors.Errorf("expected %d replicas, got %d updated replicas",
-				expectedReplicas, status.UpdatedReplicas)
-			return false, nil
-		}
-		if status.AvailableReplicas < expectedReplicas {
-			lastErr = errors.Errorf("expected %d replicas, got %d available replicas",
-				expectedReplicas, status.AvailableReplicas)
-			return false, nil
+		for _, name := range prometheusStatefulSetNames(p) {
+			sts, err := c.kclient.AppsV1().StatefulSets(p.GetNamespace()).Get(context.TODO(), name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				lastErr = errors.Errorf("statefulset %s not found", name)
+				return false, nil
+			}
+			if err != nil {
+				return false, errors.Wrap(err, "retrieving StatefulSet object failed")
+			}
+
+			if sts.Status.ObservedGeneration < sts.Generation {
+				lastErr = errors.Errorf("statefulset %s: generation %d not observed yet", name, sts.Generation)
+				return false, nil
+			}
+			if expectedReplicas != sts.Status.UpdatedReplicas {
+				lastErr = errors.Errorf("statefulset %s: expected %d replicas, got %d updated replicas",
+					name, expectedReplicas, sts.Status.UpdatedReplicas)
+				return false, nil
+			}
+			if sts.Status.ReadyReplicas < expectedReplicas {
+				lastErr = errors.Errorf("statefulset %s: expected %d replicas, got %d ready replicas",
+					name, expectedReplicas, sts.Status.ReadyReplicas)
+				return false, nil
+			}
 		}
 		return true, nil
 	}); err != nil {
@@ -629,6 +853,25 @@ func (c *Client) WaitForPrometheus(p *monv1.Prometheus) error {
 	return nil
 }
 
+// prometheusStatefulSetNames returns the names of the StatefulSets created by
+// the Prometheus operator for each shard of the Prometheus object.
+func prometheusStatefulSetNames(p *monv1.Prometheus) []string {
+	shards := int32(1)
+	if p.Spec.Shards != nil && *p.Spec.Shards > 1 {
+		shards = *p.Spec.Shards
+	}
+
+	names := make([]string, 0, shards)
+	for i := int32(0); i < shards; i++ {
+		name := "prometheus-" + p.GetName()
+		if i > 0 {
+			name = fmt.Sprintf("%s-shard-%d", name, i)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
 func (c *Client) WaitForAlertmanager(a *monv1.Alertmanager) error {
 	var lastErr error
 	if err := wait.Poll(time.Second*10, time.Minute*5, func() (bool, error) {
@@ -700,7 +943,11 @@ func (c *Client) CreateOrUpdateDeployment(dep *appsv1.Deployment) error {
 
 	if apierrors.IsNotFound(err) {
 		err = c.CreateDeployment(dep)
-		return errors.Wrap(err, "creating Deployment object failed")
+		if err != nil {
+			return errors.Wrap(err, "creating Deployment object failed")
+		}
+		c.objectCreated("Deployment", dep)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving Deployment object failed")
@@ -713,22 +960,25 @@ func (c *Client) CreateOrUpdateDeployment(dep *appsv1.Deployment) error {
 	required := dep.DeepCopy()
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
-	err = c.UpdateDeployment(required)
+	updated, err := c.updateDeployment(required)
 	if err != nil {
 		uErr, ok := err.(*apierrors.StatusError)
 		if ok && uErr.ErrStatus.Code == 422 && uErr.ErrStatus.Reason == metav1.StatusReasonInvalid {
 			// try to delete Deployment
-			err = c.DeleteDeployment(existing)
-			if err != nil {
+			err = c.deleteDeployment(existing)
+			if err != nil && !apierrors.IsNotFound(err) {
 				return errors.Wrap(err, "deleting Deployment object failed")
 			}
 			err = c.CreateDeployment(required)
 			if err != nil {
 				return errors.Wrap(err, "creating Deployment object failed after update failed")
 			}
+			c.objectRecreated("Deployment", required)
+			return nil
 		}
 		return errors.Wrap(err, "updating Deployment object failed")
 	}
+	c.objectUpdated("Deployment", existing, updated)
 	return nil
 }
 
@@ -742,12 +992,19 @@ func (c *Client) CreateDeployment(dep *appsv1.Deployment) error {
 }
 
 func (c *Client) UpdateDeployment(dep *appsv1.Deployment) error {
+	_, err := c.updateDeployment(dep)
+	return err
+}
+
+// updateDeployment updates the Deployment and waits for its rollout. It
+// returns the object returned by the update.
+func (c *Client) updateDeployment(dep *appsv1.Deployment) (*appsv1.Deployment, error) {
 	updated, err := c.kclient.AppsV1().Deployments(dep.GetNamespace()).Update(context.TODO(), dep, metav1.UpdateOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return c.WaitForDeploymentRollout(updated)
+	return updated, c.WaitForDeploymentRollout(updated)
 }
 
 func (c *Client) WaitForDeploymentRollout(dep *appsv1.Deployment) error {
@@ -881,7 +1138,11 @@ func (c *Client) CreateOrUpdateDaemonSet(ds *appsv1.DaemonSet) error {
 	existing, err := c.kclient.AppsV1().DaemonSets(ds.GetNamespace()).Get(context.TODO(), ds.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
 		err = c.CreateDaemonSet(ds)
-		return errors.Wrap(err, "creating DaemonSet object failed")
+		if err != nil {
+			return errors.Wrap(err, "creating DaemonSet object failed")
+		}
+		c.objectCreated("DaemonSet", ds)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving DaemonSet object failed")
@@ -890,22 +1151,25 @@ func (c *Client) CreateOrUpdateDaemonSet(ds *appsv1.DaemonSet) error {
 	required := ds.DeepCopy()
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
-	err = c.UpdateDaemonSet(required)
+	updated, err := c.updateDaemonSet(required)
 	if err != nil {
 		uErr, ok := err.(*apierrors.StatusError)
 		if ok && uErr.ErrStatus.Code == 422 && uErr.ErrStatus.Reason == metav1.StatusReasonInvalid {
 			// try to delete DaemonSet
-			err = c.DeleteDaemonSet(existing)
-			if err != nil {
+			err = c.deleteDaemonSet(existing)
+			if err != nil && !apierrors.IsNotFound(err) {
 				return errors.Wrap(err, "deleting DaemonSet object failed")
 			}
 			err = c.CreateDaemonSet(required)
 			if err != nil {
 				return errors.Wrap(err, "creating DaemonSet object failed after update failed")
 			}
+			c.objectRecreated("DaemonSet", required)
+			return nil
 		}
 		return errors.Wrap(err, "updating DaemonSet object failed")
 	}
+	c.objectUpdated("DaemonSet", existing, updated)
 	return nil
 }
 
@@ -919,12 +1183,19 @@ func (c *Client) CreateDaemonSet(ds *appsv1.DaemonSet) error {
 }
 
 func (c *Client) UpdateDaemonSet(ds *appsv1.DaemonSet) error {
+	_, err := c.updateDaemonSet(ds)
+	return err
+}
+
+// updateDaemonSet updates the DaemonSet and waits for its rollout. It returns
+// the object returned by the update.
+func (c *Client) updateDaemonSet(ds *appsv1.DaemonSet) (*appsv1.DaemonSet, error) {
 	updated, err := c.kclient.AppsV1().DaemonSets(ds.GetNamespace()).Update(context.TODO(), ds, metav1.UpdateOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return c.WaitForDaemonSetRollout(updated)
+	return updated, c.WaitForDaemonSetRollout(updated)
 }
 
 func (c *Client) WaitForDaemonSetRollout(ds *appsv1.DaemonSet) error {
@@ -963,8 +1234,12 @@ func (c *Client) CreateOrUpdateSecret(s *v1.Secret) error {
 	sClient := c.kclient.CoreV1().Secrets(s.GetNamespace())
 	existing, err := sClient.Get(context.TODO(), s.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := sClient.Create(context.TODO(), s, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating Secret object failed")
+		created, err := sClient.Create(context.TODO(), s, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating Secret object failed")
+		}
+		c.objectCreated("Secret", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving Secret object failed")
@@ -973,16 +1248,24 @@ func (c *Client) CreateOrUpdateSecret(s *v1.Secret) error {
 	required := s.DeepCopy()
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
-	_, err = sClient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating Secret object failed")
+	updated, err := sClient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating Secret object failed")
+	}
+	c.objectUpdated("Secret", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateIfNotExistSecret(s *v1.Secret) error {
 	sClient := c.kclient.CoreV1().Secrets(s.GetNamespace())
 	_, err := sClient.Get(context.TODO(), s.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := sClient.Create(context.TODO(), s, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating Secret object failed")
+		created, err := sClient.Create(context.TODO(), s, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating Secret object failed")
+		}
+		c.objectCreated("Secret", created)
+		return nil
 	}
 
 	return errors.Wrap(err, "retrieving Secret object failed")
@@ -1002,8 +1285,12 @@ func (c *Client) CreateOrUpdateConfigMap(cm *v1.ConfigMap) error {
 	cmClient := c.kclient.CoreV1().ConfigMaps(cm.GetNamespace())
 	existing, err := cmClient.Get(context.TODO(), cm.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := cmClient.Create(context.TODO(), cm, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating ConfigMap object failed")
+		created, err := cmClient.Create(context.TODO(), cm, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating ConfigMap object failed")
+		}
+		c.objectCreated("ConfigMap", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving ConfigMap object failed")
@@ -1012,8 +1299,52 @@ func (c *Client) CreateOrUpdateConfigMap(cm *v1.ConfigMap) error {
 	required := cm.DeepCopy()
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
-	_, err = cmClient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating ConfigMap object failed")
+	updated, err := cmClient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating ConfigMap object failed")
+	}
+	c.objectUpdated("ConfigMap", existing, updated)
+	return nil
+}
+
+func (c *Client) CreateOrUpdatePodDisruptionBudget(pdb *policyv1beta1.PodDisruptionBudget) error {
+	pdbClient := c.kclient.PolicyV1beta1().PodDisruptionBudgets(pdb.GetNamespace())
+	existing, err := pdbClient.Get(context.TODO(), pdb.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		created, err := pdbClient.Create(context.TODO(), pdb, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating PodDisruptionBudget object failed")
+		}
+		c.objectCreated("PodDisruptionBudget", created)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "retrieving PodDisruptionBudget object failed")
+	}
+
+	required := pdb.DeepCopy()
+	required.ResourceVersion = existing.ResourceVersion
+	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
+
+	updated, err := pdbClient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating PodDisruptionBudget object failed")
+	}
+	c.objectUpdated("PodDisruptionBudget", existing, updated)
+	return nil
+}
+
+func (c *Client) DeletePodDisruptionBudget(pdb *policyv1beta1.PodDisruptionBudget) error {
+	err := c.kclient.PolicyV1beta1().PodDisruptionBudgets(pdb.GetNamespace()).Delete(context.TODO(), pdb.GetName(), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "deleting PodDisruptionBudget object failed")
+	}
+
+	c.objectDeleted("PodDisruptionBudget", pdb)
+	return nil
 }
 
 func (c *Client) DeleteIfExists(nsName string) error {
@@ -1028,7 +1359,12 @@ func (c *Client) DeleteIfExists(nsName string) error {
 	}
 
 	err = nClient.Delete(context.TODO(), nsName, metav1.DeleteOptions{})
-	return errors.Wrap(err, "deleting ConfigMap object failed")
+	if err != nil {
+		return errors.Wrap(err, "deleting ConfigMap object failed")
+	}
+
+	c.objectDeleted("Namespace", &metav1.ObjectMeta{Name: nsName})
+	return nil
 }
 
 func (c *Client) CreateIfNotExistConfigMap(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
@@ -1039,6 +1375,7 @@ func (c *Client) CreateIfNotExistConfigMap(cm *v1.ConfigMap) (*v1.ConfigMap, err
 		if err != nil {
 			return nil, errors.Wrap(err, "creating ConfigMap object failed")
 		}
+		c.objectCreated("ConfigMap", res)
 		return res, nil
 	}
 	if err != nil {
@@ -1051,8 +1388,12 @@ func (c *Client) CreateOrUpdateService(svc *v1.Service) error {
 	sclient := c.kclient.CoreV1().Services(svc.GetNamespace())
 	existing, err := sclient.Get(context.TODO(), svc.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err = sclient.Create(context.TODO(), svc, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating Service object failed")
+		created, err := sclient.Create(context.TODO(), svc, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating Service object failed")
+		}
+		c.objectCreated("Service", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving Service object failed")
@@ -1070,16 +1411,24 @@ func (c *Client) CreateOrUpdateService(svc *v1.Service) error {
 
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
-	_, err = sclient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating Service object failed")
+	updated, err := sclient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating Service object failed")
+	}
+	c.objectUpdated("Service", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateOrUpdateRoleBinding(rb *rbacv1.RoleBinding) error {
 	rbClient := c.kclient.RbacV1().RoleBindings(rb.GetNamespace())
 	existing, err := rbClient.Get(context.TODO(), rb.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := rbClient.Create(context.TODO(), rb, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating RoleBinding object failed")
+		created, err := rbClient.Create(context.TODO(), rb, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating RoleBinding object failed")
+		}
+		c.objectCreated("RoleBinding", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving RoleBinding object failed")
@@ -1093,16 +1442,24 @@ func (c *Client) CreateOrUpdateRoleBinding(rb *rbacv1.RoleBinding) error {
 	required := rb.DeepCopy()
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
-	_, err = rbClient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating RoleBinding object failed")
+	updated, err := rbClient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating RoleBinding object failed")
+	}
+	c.objectUpdated("RoleBinding", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateOrUpdateRole(r *rbacv1.Role) error {
 	rClient := c.kclient.RbacV1().Roles(r.GetNamespace())
 	existing, err := rClient.Get(context.TODO(), r.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := rClient.Create(context.TODO(), r, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating Role object failed")
+		created, err := rClient.Create(context.TODO(), r, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating Role object failed")
+		}
+		c.objectCreated("Role", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving Role object failed")
@@ -1111,16 +1468,24 @@ func (c *Client) CreateOrUpdateRole(r *rbacv1.Role) error {
 	required := r.DeepCopy()
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
-	_, err = rClient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating Role object failed")
+	updated, err := rClient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating Role object failed")
+	}
+	c.objectUpdated("Role", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateOrUpdateClusterRole(cr *rbacv1.ClusterRole) error {
 	crClient := c.kclient.RbacV1().ClusterRoles()
 	existing, err := crClient.Get(context.TODO(), cr.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := crClient.Create(context.TODO(), cr, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating ClusterRole object failed")
+		created, err := crClient.Create(context.TODO(), cr, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating ClusterRole object failed")
+		}
+		c.objectCreated("ClusterRole", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving ClusterRole object failed")
@@ -1129,16 +1494,24 @@ func (c *Client) CreateOrUpdateClusterRole(cr *rbacv1.ClusterRole) error {
 	required := cr.DeepCopy()
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
-	_, err = crClient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating ClusterRole object failed")
+	updated, err := crClient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating ClusterRole object failed")
+	}
+	c.objectUpdated("ClusterRole", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateOrUpdateClusterRoleBinding(crb *rbacv1.ClusterRoleBinding) error {
 	crbClient := c.kclient.RbacV1().ClusterRoleBindings()
 	existing, err := crbClient.Get(context.TODO(), crb.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := crbClient.Create(context.TODO(), crb, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating ClusterRoleBinding object failed")
+		created, err := crbClient.Create(context.TODO(), crb, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating ClusterRoleBinding object failed")
+		}
+		c.objectCreated("ClusterRoleBinding", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving ClusterRoleBinding object failed")
@@ -1157,16 +1530,24 @@ func (c *Client) CreateOrUpdateClusterRoleBinding(crb *rbacv1.ClusterRoleBinding
 		return errors.Wrap(err, "deleting ClusterRoleBinding object failed")
 	}
 
-	_, err = crbClient.Create(context.TODO(), required, metav1.CreateOptions{})
-	return errors.Wrap(err, "updating ClusterRoleBinding object failed")
+	created, err := crbClient.Create(context.TODO(), required, metav1.CreateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating ClusterRoleBinding object failed")
+	}
+	c.objectRecreated("ClusterRoleBinding", created)
+	return nil
 }
 
 func (c *Client) CreateOrUpdateServiceAccount(sa *v1.ServiceAccount) error {
 	sClient := c.kclient.CoreV1().ServiceAccounts(sa.GetNamespace())
 	_, err := sClient.Get(context.TODO(), sa.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := sClient.Create(context.TODO(), sa, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating ServiceAccount object failed")
+		created, err := sClient.Create(context.TODO(), sa, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating ServiceAccount object failed")
+		}
+		c.objectCreated("ServiceAccount", created)
+		return nil
 	}
 	return errors.Wrap(err, "retrieving ServiceAccount object failed")
 
@@ -1192,8 +1573,12 @@ func (c *Client) CreateOrUpdateServiceMonitor(sm *monv1.ServiceMonitor) error {
 	smClient := c.mclient.MonitoringV1().ServiceMonitors(sm.GetNamespace())
 	existing, err := smClient.Get(context.TODO(), sm.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err := smClient.Create(context.TODO(), sm, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating ServiceMonitor object failed")
+		created, err := smClient.Create(context.TODO(), sm, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating ServiceMonitor object failed")
+		}
+		c.objectCreated("ServiceMonitor", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving ServiceMonitor object failed")
@@ -1203,16 +1588,24 @@ func (c *Client) CreateOrUpdateServiceMonitor(sm *monv1.ServiceMonitor) error {
 	mergeMetadata(&required.ObjectMeta, existing.ObjectMeta)
 
 	required.ResourceVersion = existing.ResourceVersion
-	_, err = smClient.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating ServiceMonitor object failed")
+	updated, err := smClient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating ServiceMonitor object failed")
+	}
+	c.objectUpdated("ServiceMonitor", existing, updated)
+	return nil
 }
 
 func (c *Client) CreateOrUpdateAPIService(apiService *apiregistrationv1.APIService) error {
 	apsc := c.aggclient.ApiregistrationV1().APIServices()
 	existing, err := apsc.Get(context.TODO(), apiService.GetName(), metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		_, err = apsc.Create(context.TODO(), apiService, metav1.CreateOptions{})
-		return errors.Wrap(err, "creating APIService object failed")
+		created, err := apsc.Create(context.TODO(), apiService, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating APIService object failed")
+		}
+		c.objectCreated("APIService", created)
+		return nil
 	}
 	if err != nil {
 		return errors.Wrap(err, "retrieving APIService object failed")
@@ -1223,8 +1616,12 @@ func (c *Client) CreateOrUpdateAPIService(apiService *apiregistrationv1.APIServi
 	if len(existing.Spec.CABundle) > 0 {
 		required.Spec.CABundle = existing.Spec.CABundle
 	}
-	_, err = apsc.Update(context.TODO(), required, metav1.UpdateOptions{})
-	return errors.Wrap(err, "updating APIService object failed")
+	updated, err := apsc.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating APIService object failed")
+	}
+	c.objectUpdated("APIService", existing, updated)
+	return nil
 
 }
 
@@ -1265,8 +1662,12 @@ func (c *Client) DeleteRoleBinding(binding *rbacv1.RoleBinding) error {
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("RoleBinding", binding)
+	return nil
 }
 
 func (c *Client) DeleteRole(role *rbacv1.Role) error {
@@ -1274,8 +1675,12 @@ func (c *Client) DeleteRole(role *rbacv1.Role) error {
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	c.objectDeleted("Role", role)
+	return nil
 }
 
 // mergeMetadata merges labels and annotations from `existing` map into `required` one where `required` has precedence
@@ -31,6 +31,8 @@ import (
 
 	ossfake "github.com/openshift/client-go/security/clientset/versioned/fake"
 	monfake "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 )
 
 const (
@@ -1568,3 +1570,61 @@ func TestCreateOrUpdateAlertmanager(t *testing.T) {
 		})
 	}
 }
+
+func TestPrometheusStatefulSetNames(t *testing.T) {
+	for _, tc := range []struct {
+		shards   *int32
+		expected []string
+	}{
+		{
+			expected: []string{"prometheus-user-workload"},
+		},
+		{
+			shards:   func(i int32) *int32 { return &i }(1),
+			expected: []string{"prometheus-user-workload"},
+		},
+		{
+			shards:   func(i int32) *int32 { return &i }(3),
+			expected: []string{"prometheus-user-workload", "prometheus-user-workload-shard-1", "prometheus-user-workload-shard-2"},
+		},
+	} {
+		p := &monv1.Prometheus{
+			ObjectMeta: metav1.ObjectMeta{Name: "user-workload", Namespace: nsUWM},
+			Spec:       monv1.PrometheusSpec{Shards: tc.shards},
+		}
+		if got := prometheusStatefulSetNames(p); !reflect.DeepEqual(tc.expected, got) {
+			t.Errorf("expected %v, got %v", tc.expected, got)
+		}
+	}
+}
+
+func TestNormalizeSpec(t *testing.T) {
+	cm, err := manifests.NewClusterMonitoringFromConfigMap("prometheusK8s:\n  replicas: 3\n  retention: 15d\n  enforcedSampleLimit: 1.5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The API server decodes the integers as int64.
+	existing := map[string]interface{}{
+		"prometheusK8s": map[string]interface{}{
+			"replicas":            int64(3),
+			"retention":           "15d",
+			"enforcedSampleLimit": float64(1.5),
+		},
+	}
+
+	got, err := normalizeSpec(cm.Object["spec"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := normalizeSpec(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %#v, got %#v", expected, got)
+	}
+
+	if got, _ := normalizeSpec(nil); !reflect.DeepEqual(map[string]interface{}{}, got) {
+		t.Errorf("expected empty spec, got %#v", got)
+	}
+}
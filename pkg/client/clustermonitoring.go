@@ -0,0 +1,161 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+// ClusterMonitoringListWatch returns a ListWatch for the singleton
+// ClusterMonitoring resource.
+func (c *Client) ClusterMonitoringListWatch() *cache.ListWatch {
+	rclient := c.dclient.Resource(manifests.ClusterMonitoringGVR)
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", manifests.ClusterMonitoringName).String()
+
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return rclient.List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return rclient.Watch(context.TODO(), options)
+		},
+	}
+}
+
+// CreateOrUpdateClusterMonitoring creates the ClusterMonitoring resource or
+// updates its metadata and spec.
+func (c *Client) CreateOrUpdateClusterMonitoring(cm *unstructured.Unstructured) error {
+	rclient := c.dclient.Resource(manifests.ClusterMonitoringGVR)
+	existing, err := rclient.Get(context.TODO(), cm.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		created, err := rclient.Create(context.TODO(), cm, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "creating ClusterMonitoring object failed")
+		}
+		c.objectCreated(manifests.ClusterMonitoringKind, created)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "retrieving ClusterMonitoring object failed")
+	}
+
+	// The spec decoded from YAML and the spec returned by the API server
+	// don't use the same types for numbers hence both are normalized before
+	// being compared.
+	existingSpec, err := normalizeSpec(existing.Object["spec"])
+	if err != nil {
+		return errors.Wrap(err, "normalizing the existing ClusterMonitoring spec failed")
+	}
+	requiredSpec, err := normalizeSpec(cm.Object["spec"])
+	if err != nil {
+		return errors.Wrap(err, "normalizing the ClusterMonitoring spec failed")
+	}
+	if reflect.DeepEqual(existingSpec, requiredSpec) &&
+		reflect.DeepEqual(existing.GetAnnotations(), cm.GetAnnotations()) {
+		return nil
+	}
+
+	required := existing.DeepCopy()
+	required.SetAnnotations(cm.GetAnnotations())
+	required.Object["spec"] = requiredSpec
+
+	updated, err := rclient.Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating ClusterMonitoring object failed")
+	}
+	c.objectUpdated(manifests.ClusterMonitoringKind, existing, updated)
+	return nil
+}
+
+// normalizeSpec round-trips the spec through JSON like the API machinery
+// does: integers are decoded as int64 and other numbers as float64.
+func normalizeSpec(spec interface{}) (interface{}, error) {
+	if spec == nil {
+		return map[string]interface{}{}, nil
+	}
+	b, err := utiljson.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var res interface{}
+	if err := utiljson.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdateClusterMonitoringStatus sets the observed generation and the
+// conditions of the ClusterMonitoring resource.
+func (c *Client) UpdateClusterMonitoringStatus(observedGeneration int64, conditions []metav1.Condition) error {
+	rclient := c.dclient.Resource(manifests.ClusterMonitoringGVR)
+	cm, err := rclient.Get(context.TODO(), manifests.ClusterMonitoringName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "retrieving ClusterMonitoring object failed")
+	}
+
+	var existing []metav1.Condition
+	items, _, _ := unstructured.NestedSlice(cm.Object, "status", "conditions")
+	for _, item := range items {
+		u, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var cond metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u, &cond); err != nil {
+			return errors.Wrap(err, "parsing ClusterMonitoring conditions failed")
+		}
+		existing = append(existing, cond)
+	}
+
+	for _, cond := range conditions {
+		meta.SetStatusCondition(&existing, cond)
+	}
+
+	raw := make([]interface{}, 0, len(existing))
+	for i := range existing {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&existing[i])
+		if err != nil {
+			return errors.Wrap(err, "converting ClusterMonitoring conditions failed")
+		}
+		raw = append(raw, u)
+	}
+
+	status := map[string]interface{}{
+		"observedGeneration": observedGeneration,
+		"conditions":         raw,
+	}
+	if err := unstructured.SetNestedField(cm.Object, status, "status"); err != nil {
+		return errors.Wrap(err, "setting ClusterMonitoring status failed")
+	}
+
+	_, err = rclient.UpdateStatus(context.TODO(), cm, metav1.UpdateOptions{})
+	return errors.Wrap(err, "updating ClusterMonitoring status failed")
+}
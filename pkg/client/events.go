@@ -0,0 +1,221 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	eventComponent = "cluster-monitoring-operator"
+	// eventDedupPeriod is the period during which identical reconcile
+	// events are emitted only once.
+	eventDedupPeriod = time.Hour
+
+	reconcileSucceededReason = "ReconcileSucceeded"
+)
+
+// EventRecorder emits Kubernetes events about the reconciliation of the
+// monitoring stack and about the objects managed by the operator.
+//
+// Reconcile events are deduplicated: an event identical to one emitted less
+// than an hour ago is dropped, warnings included. A failing reconcile
+// following a successful one resets the normal events and vice versa. Object events are only emitted when the object effectively
+// changes, hence they aren't deduplicated.
+//
+// A nil *EventRecorder is valid and discards all events.
+type EventRecorder struct {
+	recorder record.EventRecorder
+	operator *v1.ObjectReference
+
+	mtx     sync.Mutex
+	seen    map[string]time.Time
+	failing bool
+	now     func() time.Time
+}
+
+// NewEventRecorder returns an EventRecorder which reports reconcile events on
+// the operator's Deployment in the given namespace.
+func NewEventRecorder(kclient kubernetes.Interface, namespace string) *EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kclient.CoreV1().Events("")})
+
+	return newEventRecorder(
+		broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventComponent}),
+		namespace,
+	)
+}
+
+func newEventRecorder(recorder record.EventRecorder, namespace string) *EventRecorder {
+	return &EventRecorder{
+		recorder: recorder,
+		operator: &v1.ObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Namespace:  namespace,
+			Name:       eventComponent,
+		},
+		seen: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// ReconcileStarted records the start of a reconciliation.
+func (r *EventRecorder) ReconcileStarted() {
+	r.reconcileEvent(v1.EventTypeNormal, "ReconcileStarted", "Rolling out the monitoring stack.")
+}
+
+// ReconcileSucceeded records the successful completion of a reconciliation.
+func (r *EventRecorder) ReconcileSucceeded() {
+	r.reconcileEvent(v1.EventTypeNormal, reconcileSucceededReason, "Successfully rolled out the monitoring stack.")
+}
+
+// ReconcileFailed records the failure of a reconciliation.
+func (r *EventRecorder) ReconcileFailed(reason string, err error) {
+	r.reconcileEvent(v1.EventTypeWarning, reason, fmt.Sprintf("Failed to roll out the monitoring stack: %v", err))
+}
+
+func (r *EventRecorder) reconcileEvent(eventtype, reason, message string) {
+	if r == nil {
+		return
+	}
+
+	r.mtx.Lock()
+	now := r.now()
+	for k, t := range r.seen {
+		if now.Sub(t) >= eventDedupPeriod {
+			delete(r.seen, k)
+		}
+	}
+	// Switching between failing and succeeding reconciles makes the events
+	// of the previous state stale: they should be emitted again the next
+	// time the state is entered.
+	switch {
+	case eventtype == v1.EventTypeWarning && !r.failing:
+		r.failing = true
+		r.forget(v1.EventTypeNormal)
+	case reason == reconcileSucceededReason && r.failing:
+		r.failing = false
+		r.forget(v1.EventTypeWarning)
+	}
+	key := eventtype + "/" + reason + "/" + message
+	_, found := r.seen[key]
+	if !found {
+		r.seen[key] = now
+	}
+	r.mtx.Unlock()
+
+	if found {
+		return
+	}
+	r.recorder.Event(r.operator, eventtype, reason, message)
+}
+
+// forget drops the deduplication keys of the given event type. It must be
+// called with the mutex held.
+func (r *EventRecorder) forget(eventtype string) {
+	for k := range r.seen {
+		if strings.HasPrefix(k, eventtype+"/") {
+			delete(r.seen, k)
+		}
+	}
+}
+
+// objectEvent records an event on a managed object. The reason is built from
+// the object's kind and the action, e.g. "DeploymentUpdated".
+func (r *EventRecorder) objectEvent(kind string, obj metav1.Object, action string) {
+	if r == nil {
+		return
+	}
+
+	ref := &v1.ObjectReference{
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		UID:       obj.GetUID(),
+	}
+	name := obj.GetName()
+	if ns := obj.GetNamespace(); ns != "" {
+		name = ns + "/" + name
+	}
+
+	r.recorder.Eventf(ref, v1.EventTypeNormal, kind+action, "%s %s %s", action, kind, name)
+}
+
+// NamespaceQuotaExceeded records that a namespace goes over its user workload
+// monitoring quota. The event is reported on the namespace so that its users
+// can see it.
+func (r *EventRecorder) NamespaceQuotaExceeded(namespace, message string) {
+	if r == nil {
+		return
+	}
+
+	ref := &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Namespace",
+		Namespace:  namespace,
+		Name:       namespace,
+	}
+	r.recorder.Event(ref, v1.EventTypeWarning, "UserWorkloadQuotaExceeded", message)
+}
+
+// UserWorkloadResourceRejected records that a user-defined resource is
+// ignored by user workload monitoring. The event is reported on the resource
+// so that its owners can see it.
+func (r *EventRecorder) UserWorkloadResourceRejected(kind string, obj metav1.Object, message string) {
+	if r == nil {
+		return
+	}
+
+	ref := &v1.ObjectReference{
+		APIVersion: "monitoring.coreos.com/v1",
+		Kind:       kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+	}
+	r.recorder.Event(ref, v1.EventTypeWarning, "UserWorkloadResourceRejected", message)
+}
+
+func (c *Client) objectCreated(kind string, obj metav1.Object) {
+	c.events.objectEvent(kind, obj, "Created")
+}
+
+// objectUpdated records an event only when the update modified the object,
+// which is detected by a change of the resource version.
+func (c *Client) objectUpdated(kind string, existing, updated metav1.Object) {
+	if existing.GetResourceVersion() == updated.GetResourceVersion() {
+		return
+	}
+	c.events.objectEvent(kind, updated, "Updated")
+}
+
+func (c *Client) objectRecreated(kind string, obj metav1.Object) {
+	c.events.objectEvent(kind, obj, "Recreated")
+}
+
+func (c *Client) objectDeleted(kind string, obj metav1.Object) {
+	c.events.objectEvent(kind, obj, "Deleted")
+}
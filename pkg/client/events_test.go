@@ -0,0 +1,211 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func drainEvents(r *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case e := <-r.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestEventRecorderReconcileDedup(t *testing.T) {
+	fr := record.NewFakeRecorder(100)
+	r := newEventRecorder(fr, ns)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	// Steady state: only the first reconcile emits events.
+	r.ReconcileStarted()
+	r.ReconcileSucceeded()
+	r.ReconcileStarted()
+	r.ReconcileSucceeded()
+
+	expected := []string{
+		"Normal ReconcileStarted Rolling out the monitoring stack.",
+		"Normal ReconcileSucceeded Successfully rolled out the monitoring stack.",
+	}
+	if got := drainEvents(fr); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+
+	// A failure is always reported and resets the steady state.
+	r.ReconcileStarted()
+	r.ReconcileFailed("UpdatingGrafanaFailed", errors.New("foo"))
+	r.ReconcileStarted()
+	r.ReconcileSucceeded()
+
+	expected = []string{
+		"Warning UpdatingGrafanaFailed Failed to roll out the monitoring stack: foo",
+		"Normal ReconcileStarted Rolling out the monitoring stack.",
+		"Normal ReconcileSucceeded Successfully rolled out the monitoring stack.",
+	}
+	if got := drainEvents(fr); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+
+	// Repeated failures are only reported once until the next success.
+	r.ReconcileStarted()
+	r.ReconcileFailed("UpdatingGrafanaFailed", errors.New("foo"))
+	r.ReconcileStarted()
+	r.ReconcileFailed("UpdatingGrafanaFailed", errors.New("foo"))
+	r.ReconcileStarted()
+	r.ReconcileFailed("UpdatingGrafanaFailed", errors.New("foo"))
+	r.ReconcileStarted()
+	r.ReconcileFailed("UpdatingGrafanaFailed", errors.New("bar"))
+
+	expected = []string{
+		"Warning UpdatingGrafanaFailed Failed to roll out the monitoring stack: foo",
+		"Normal ReconcileStarted Rolling out the monitoring stack.",
+		"Warning UpdatingGrafanaFailed Failed to roll out the monitoring stack: bar",
+	}
+	if got := drainEvents(fr); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+
+	r.ReconcileStarted()
+	r.ReconcileSucceeded()
+	r.ReconcileStarted()
+	r.ReconcileFailed("UpdatingGrafanaFailed", errors.New("foo"))
+
+	expected = []string{
+		"Normal ReconcileSucceeded Successfully rolled out the monitoring stack.",
+		"Warning UpdatingGrafanaFailed Failed to roll out the monitoring stack: foo",
+	}
+	if got := drainEvents(fr); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+
+	// Events are emitted again once the deduplication period has elapsed.
+	now = now.Add(eventDedupPeriod)
+	r.ReconcileStarted()
+
+	expected = []string{
+		"Normal ReconcileStarted Rolling out the monitoring stack.",
+	}
+	if got := drainEvents(fr); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+}
+
+func TestEventRecorderNil(t *testing.T) {
+	var r *EventRecorder
+	r.ReconcileStarted()
+	r.ReconcileSucceeded()
+	r.ReconcileFailed("Foo", errors.New("foo"))
+	r.objectEvent("ConfigMap", &metav1.ObjectMeta{Name: "foo"}, "Created")
+}
+
+func TestObjectEvents(t *testing.T) {
+	fr := record.NewFakeRecorder(100)
+	c := Client{
+		kclient: fake.NewSimpleClientset(),
+		events:  newEventRecorder(fr, ns),
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: ns,
+		},
+		Data: map[string]string{"foo": "bar"},
+	}
+
+	if err := c.CreateOrUpdateConfigMap(cm); err != nil {
+		t.Fatal(err)
+	}
+	// The fake clientset doesn't bump the resource version, which is
+	// equivalent to an update without change.
+	if err := c.CreateOrUpdateConfigMap(cm); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteConfigMap(cm); err != nil {
+		t.Fatal(err)
+	}
+	// Deleting a missing object doesn't emit any event.
+	if err := c.DeleteConfigMap(cm); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"Normal ConfigMapCreated Created ConfigMap openshift-monitoring/foo",
+		"Normal ConfigMapDeleted Deleted ConfigMap openshift-monitoring/foo",
+	}
+	if got := drainEvents(fr); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+}
+
+func TestDaemonSetUpdatedEvent(t *testing.T) {
+	fr := record.NewFakeRecorder(100)
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-exporter", Namespace: ns, ResourceVersion: "1"},
+	}
+	kclient := fake.NewSimpleClientset(ds)
+	// The fake clientset doesn't bump the resource version on updates.
+	kclient.PrependReactor("update", "daemonsets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		updated := action.(clienttesting.UpdateAction).GetObject().(*appsv1.DaemonSet).DeepCopy()
+		updated.ResourceVersion = "2"
+		return true, updated, nil
+	})
+	c := Client{kclient: kclient, events: newEventRecorder(fr, ns)}
+
+	if err := c.CreateOrUpdateDaemonSet(ds); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"Normal DaemonSetUpdated Updated DaemonSet openshift-monitoring/node-exporter",
+	}
+	if got := drainEvents(fr); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+}
+
+func TestObjectUpdated(t *testing.T) {
+	fr := record.NewFakeRecorder(100)
+	c := Client{events: newEventRecorder(fr, ns)}
+
+	existing := &metav1.ObjectMeta{Name: "foo", Namespace: ns, ResourceVersion: "1"}
+	c.objectUpdated("Secret", existing, &metav1.ObjectMeta{Name: "foo", Namespace: ns, ResourceVersion: "1"})
+	c.objectUpdated("Secret", existing, &metav1.ObjectMeta{Name: "foo", Namespace: ns, ResourceVersion: "2"})
+
+	expected := []string{
+		"Normal SecretUpdated Updated Secret openshift-monitoring/foo",
+	}
+	if got := drainEvents(fr); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+}
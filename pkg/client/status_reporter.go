@@ -16,6 +16,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/openshift/cluster-monitoring-operator/pkg/strings"
@@ -24,9 +25,28 @@ import (
 	clientv1 "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
-const unavailableMessage string = "Rollout of the monitoring stack failed and is degraded. Please investigate the degraded status error."
+const (
+	unavailableMessage string = "Rollout of the monitoring stack failed and is degraded. Please investigate the degraded status error."
+	degradedMessage    string = "Rollout of optional components of the monitoring stack failed. Please investigate the degraded status error."
+)
+
+// ComponentStatus describes the health of a single component of the
+// monitoring stack after a reconciliation.
+type ComponentStatus struct {
+	Name     string `json:"name"`
+	Healthy  bool   `json:"healthy"`
+	Critical bool   `json:"critical"`
+	Message  string `json:"message,omitempty"`
+}
+
+// statusExtension is the structure stored in the extension field of the
+// ClusterOperator status.
+type statusExtension struct {
+	Components []ComponentStatus `json:"components"`
+}
 
 type StatusReporter struct {
 	client                clientv1.ClusterOperatorInterface
@@ -62,7 +82,21 @@ func (r *StatusReporter) relatedObjects() []v1.ObjectReference {
 	}
 }
 
-func (r *StatusReporter) SetDone() error {
+// SetDone reports the successful rollout of the stack. The operator version
+// is always reported first, followed by the given operand versions.
+// The components, when not nil, are recorded in the status extension.
+func (r *StatusReporter) SetDone(operands []v1.OperandVersion, components []ComponentStatus) error {
+	return r.setDone(operands, components, nil, "")
+}
+
+// SetDegraded reports a rollout of the stack which completed but left it
+// degraded for the given reason. The versions are reported like with
+// SetDone since all the components have been rolled out.
+func (r *StatusReporter) SetDegraded(statusErr error, reason string, operands []v1.OperandVersion, components []ComponentStatus) error {
+	return r.setDone(operands, components, statusErr, reason)
+}
+
+func (r *StatusReporter) setDone(operands []v1.OperandVersion, components []ComponentStatus, degradedErr error, reason string) error {
 	co, err := r.client.Get(context.TODO(), r.clusterOperatorName, metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
 		co = r.newClusterOperator()
@@ -77,24 +111,32 @@ func (r *StatusReporter) SetDone() error {
 	conditions := newConditions(co.Status, r.version, time)
 	conditions.setCondition(v1.OperatorAvailable, v1.ConditionTrue, "Successfully rolled out the stack.", "RollOutDone", time)
 	conditions.setCondition(v1.OperatorProgressing, v1.ConditionFalse, "", "", time)
-	conditions.setCondition(v1.OperatorDegraded, v1.ConditionFalse, "", "", time)
-	conditions.setCondition(v1.OperatorUpgradeable, v1.ConditionTrue, "", "", time)
+	if degradedErr != nil {
+		reason = strings.ToPascalCase(reason)
+		conditions.setCondition(v1.OperatorDegraded, v1.ConditionTrue, fmt.Sprintf("Rolled out the stack with errors. Error: %v", degradedErr), reason, time)
+	} else {
+		conditions.setCondition(v1.OperatorDegraded, v1.ConditionFalse, "", "", time)
+	}
 	co.Status.Conditions = conditions.entries()
 
 	// If we have reached "level" for the operator, report that we are at the version
 	// injected into us during update. We require that all components be rolled out
 	// and available at the new version before reporting this value.
 	if len(r.version) > 0 {
-		co.Status.Versions = []v1.OperandVersion{
+		co.Status.Versions = append([]v1.OperandVersion{
 			{
 				Name:    "operator",
 				Version: r.version,
 			},
-		}
+		}, operands...)
 	} else {
 		co.Status.Versions = nil
 	}
 
+	if err := setComponents(co, components); err != nil {
+		return err
+	}
+
 	_, err = r.client.UpdateStatus(context.TODO(), co, metav1.UpdateOptions{})
 	return err
 }
@@ -120,11 +162,6 @@ func (r *StatusReporter) SetInProgress() error {
 	reasonInProgress := "RollOutInProgress"
 	conditions := newConditions(co.Status, r.version, time)
 	conditions.setCondition(v1.OperatorProgressing, v1.ConditionTrue, "Rolling out the stack.", reasonInProgress, time)
-	conditions.setCondition(v1.OperatorUpgradeable, v1.ConditionTrue,
-		"Rollout of the monitoring stack is in progress. Please wait until it finishes.",
-		reasonInProgress,
-		time,
-	)
 	co.Status.Conditions = conditions.entries()
 	co.Status.RelatedObjects = r.relatedObjects()
 
@@ -132,11 +169,84 @@ func (r *StatusReporter) SetInProgress() error {
 	return err
 }
 
+// SetUpgradeable sets the OperatorUpgradeable condition. The other conditions
+// are left untouched.
+func (r *StatusReporter) SetUpgradeable(cond v1.ConditionStatus, message, reason string) error {
+	co, err := r.client.Get(context.TODO(), r.clusterOperatorName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		co = r.newClusterOperator()
+		co, err = r.client.Create(context.TODO(), co, metav1.CreateOptions{})
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	time := metav1.Now()
+	conditions := newConditions(co.Status, r.version, time)
+	conditions.setCondition(v1.OperatorUpgradeable, cond, message, reason, time)
+	co.Status.Conditions = conditions.entries()
+
+	_, err = r.client.UpdateStatus(context.TODO(), co, metav1.UpdateOptions{})
+	return err
+}
+
+// SetDegradedCondition sets the OperatorDegraded condition to true for a
+// problem found outside of the rollout of the stack. The condition is left
+// untouched when it is already true for another reason, the problems of the
+// rollout come first. The other conditions are left untouched.
+func (r *StatusReporter) SetDegradedCondition(statusErr error, reason string) error {
+	return r.setDegradedCondition(v1.ConditionTrue, fmt.Sprintf("Rolled out the stack with errors. Error: %v", statusErr), reason)
+}
+
+// ClearDegradedCondition sets the OperatorDegraded condition to false if it
+// is true for the given reason. The other conditions are left untouched.
+func (r *StatusReporter) ClearDegradedCondition(reason string) error {
+	return r.setDegradedCondition(v1.ConditionFalse, "", reason)
+}
+
+func (r *StatusReporter) setDegradedCondition(status v1.ConditionStatus, message, reason string) error {
+	co, err := r.client.Get(context.TODO(), r.clusterOperatorName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		co = r.newClusterOperator()
+		co, err = r.client.Create(context.TODO(), co, metav1.CreateOptions{})
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	reason = strings.ToPascalCase(reason)
+	var current v1.ClusterOperatorStatusCondition
+	for _, c := range co.Status.Conditions {
+		if c.Type == v1.OperatorDegraded {
+			current = c
+		}
+	}
+	switch {
+	case current.Status == v1.ConditionTrue && current.Reason != reason:
+		return nil
+	case status == v1.ConditionFalse && current.Status != v1.ConditionTrue:
+		return nil
+	case status == v1.ConditionFalse:
+		reason = ""
+	}
+
+	time := metav1.Now()
+	conditions := newConditions(co.Status, r.version, time)
+	conditions.setCondition(v1.OperatorDegraded, status, message, reason, time)
+	co.Status.Conditions = conditions.entries()
+
+	_, err = r.client.UpdateStatus(context.TODO(), co, metav1.UpdateOptions{})
+	return err
+}
+
 func (r *StatusReporter) Get() (*v1.ClusterOperator, error) {
 	return r.client.Get(context.TODO(), r.clusterOperatorName, metav1.GetOptions{})
 }
 
-func (r *StatusReporter) SetFailed(statusErr error, reason string) error {
+// SetFailed reports a failed rollout of the stack. The components, when not
+// nil, are recorded in the status extension. The stack is reported as
+// unavailable unless the failure is limited to optional components.
+func (r *StatusReporter) SetFailed(statusErr error, reason string, components []ComponentStatus) error {
 	co, err := r.client.Get(context.TODO(), r.clusterOperatorName, metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
 		co = r.newClusterOperator()
@@ -151,12 +261,20 @@ func (r *StatusReporter) SetFailed(statusErr error, reason string) error {
 	reason = strings.ToPascalCase(reason)
 
 	conditions := newConditions(co.Status, r.version, time)
-	conditions.setCondition(v1.OperatorAvailable, v1.ConditionFalse, unavailableMessage, reason, time)
-	conditions.setCondition(v1.OperatorProgressing, v1.ConditionFalse, unavailableMessage, reason, time)
+	if criticalFailure(components) {
+		conditions.setCondition(v1.OperatorAvailable, v1.ConditionFalse, unavailableMessage, reason, time)
+		conditions.setCondition(v1.OperatorProgressing, v1.ConditionFalse, unavailableMessage, reason, time)
+	} else {
+		conditions.setCondition(v1.OperatorAvailable, v1.ConditionTrue, degradedMessage, reason, time)
+		conditions.setCondition(v1.OperatorProgressing, v1.ConditionFalse, degradedMessage, reason, time)
+	}
 	conditions.setCondition(v1.OperatorDegraded, v1.ConditionTrue, fmt.Sprintf("Failed to rollout the stack. Error: %v", statusErr), reason, time)
-	conditions.setCondition(v1.OperatorUpgradeable, v1.ConditionTrue, unavailableMessage, reason, time)
 	co.Status.Conditions = conditions.entries()
 
+	if err := setComponents(co, components); err != nil {
+		return err
+	}
+
 	_, err = r.client.UpdateStatus(context.TODO(), co, metav1.UpdateOptions{})
 	return err
 }
@@ -179,3 +297,35 @@ func (r *StatusReporter) newClusterOperator() *v1.ClusterOperator {
 
 	return co
 }
+
+// criticalFailure returns true if any of the critical components is unhealthy.
+// Without any component information, the failure is assumed to be critical.
+func criticalFailure(components []ComponentStatus) bool {
+	if components == nil {
+		return true
+	}
+
+	for _, c := range components {
+		if c.Critical && !c.Healthy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setComponents records the components' health in the extension field of the
+// ClusterOperator status. The extension is left untouched if components is nil.
+func setComponents(co *v1.ClusterOperator, components []ComponentStatus) error {
+	if components == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(statusExtension{Components: components})
+	if err != nil {
+		return fmt.Errorf("marshaling status extension failed: %v", err)
+	}
+
+	co.Status.Extension = runtime.RawExtension{Raw: b}
+	return nil
+}
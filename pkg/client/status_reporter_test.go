@@ -64,7 +64,7 @@ func TestStatusReporterSetDone(t *testing.T) {
 					"Available", "True",
 					"Degraded", "False",
 					"Progressing", "False",
-					"Upgradeable", "True",
+					"Upgradeable", "Unknown",
 				),
 			},
 		},
@@ -91,10 +91,38 @@ func TestStatusReporterSetDone(t *testing.T) {
 					"Available", "True",
 					"Degraded", "False",
 					"Progressing", "False",
-					"Upgradeable", "True",
+					"Upgradeable", "Unknown",
 				),
 			},
 		},
+		{
+			name: "operands and components",
+
+			given: givenStatusReporter{
+				operatorName:          "foo",
+				namespace:             "bar",
+				userWorkloadNamespace: "fred",
+				version:               "1.0",
+				operands: []v1.OperandVersion{
+					{Name: "prometheus", Version: "2.24.0"},
+					{Name: "alertmanager", Version: "0.21.0"},
+				},
+				components: []ComponentStatus{
+					{Name: "Updating Prometheus-k8s", Healthy: true, Critical: true},
+				},
+			},
+
+			when: []whenFunc{
+				getReturnsClusterOperator(&v1.ClusterOperator{}),
+				updateStatusReturnsError(nil),
+			},
+
+			check: []checkFunc{
+				hasUpdatedStatus(true),
+				hasUpdatedStatusVersions("1.0", "2.24.0", "0.21.0"),
+				hasUpdatedStatusComponents(`{"components":[{"name":"Updating Prometheus-k8s","healthy":true,"critical":true}]}`),
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			mock := &clusterOperatorMock{}
@@ -111,7 +139,130 @@ func TestStatusReporterSetDone(t *testing.T) {
 				w(mock)
 			}
 
-			got := sr.SetDone()
+			got := sr.SetDone(tc.given.operands, tc.given.components)
+
+			for _, check := range tc.check {
+				if err := check(mock, got); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusReporterSetDegraded(t *testing.T) {
+	mock := &clusterOperatorMock{}
+	sr := NewStatusReporter(mock, "foo", "bar", "fred", "1.0")
+	getReturnsClusterOperator(&v1.ClusterOperator{})(mock)
+	updateStatusReturnsError(nil)(mock)
+
+	got := sr.SetDegraded(
+		errors.New("lint failed"),
+		"PrometheusRulesLintFailed",
+		[]v1.OperandVersion{{Name: "prometheus", Version: "2.24.0"}},
+		[]ComponentStatus{{Name: "Updating Prometheus-k8s", Healthy: true, Critical: true}},
+	)
+
+	for _, check := range []checkFunc{
+		hasUpdatedStatus(true),
+		hasUpdatedStatusVersions("1.0", "2.24.0"),
+		hasUpdatedStatusConditions(
+			"Available", "True",
+			"Degraded", "True",
+			"Progressing", "False",
+			"Upgradeable", "Unknown",
+		),
+	} {
+		if err := check(mock, got); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestStatusReporterDegradedCondition(t *testing.T) {
+	degraded := func(status v1.ConditionStatus, reason string) *v1.ClusterOperator {
+		return &v1.ClusterOperator{
+			Status: v1.ClusterOperatorStatus{
+				Conditions: []v1.ClusterOperatorStatusCondition{
+					{Type: v1.OperatorAvailable, Status: v1.ConditionTrue},
+					{Type: v1.OperatorDegraded, Status: status, Reason: reason},
+					{Type: v1.OperatorProgressing, Status: v1.ConditionFalse},
+					{Type: v1.OperatorUpgradeable, Status: v1.ConditionTrue},
+				},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		current *v1.ClusterOperator
+		clear   bool
+		check   []checkFunc
+	}{
+		{
+			name:    "set",
+			current: degraded(v1.ConditionFalse, ""),
+			check: []checkFunc{
+				hasUpdatedStatus(true),
+				hasUpdatedStatusConditions(
+					"Available", "True",
+					"Degraded", "True",
+					"Progressing", "False",
+					"Upgradeable", "True",
+				),
+				hasDegradedReason("VolumeExpansionFailed"),
+			},
+		},
+		{
+			name:    "degraded by the rollout",
+			current: degraded(v1.ConditionTrue, "UpdatingPrometheusK8sFailed"),
+			check: []checkFunc{
+				hasUpdatedStatus(false),
+			},
+		},
+		{
+			name:    "clear",
+			current: degraded(v1.ConditionTrue, "VolumeExpansionFailed"),
+			clear:   true,
+			check: []checkFunc{
+				hasUpdatedStatus(true),
+				hasUpdatedStatusConditions(
+					"Available", "True",
+					"Degraded", "False",
+					"Progressing", "False",
+					"Upgradeable", "True",
+				),
+			},
+		},
+		{
+			name:    "clear degraded by the rollout",
+			current: degraded(v1.ConditionTrue, "UpdatingPrometheusK8sFailed"),
+			clear:   true,
+			check: []checkFunc{
+				hasUpdatedStatus(false),
+			},
+		},
+		{
+			name:    "clear not degraded",
+			current: degraded(v1.ConditionFalse, ""),
+			clear:   true,
+			check: []checkFunc{
+				hasUpdatedStatus(false),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &clusterOperatorMock{}
+			sr := NewStatusReporter(mock, "foo", "bar", "fred", "1.0")
+			getReturnsClusterOperator(tc.current)(mock)
+			updateStatusReturnsError(nil)(mock)
+
+			var got error
+			if tc.clear {
+				got = sr.ClearDegradedCondition("VolumeExpansionFailed")
+			} else {
+				got = sr.SetDegradedCondition(errors.New("volume expansion failed"), "VolumeExpansionFailed")
+			}
 
 			for _, check := range tc.check {
 				if err := check(mock, got); err != nil {
@@ -155,7 +306,7 @@ func TestStatusReporterSetInProgress(t *testing.T) {
 					"Available", "Unknown",
 					"Degraded", "Unknown",
 					"Progressing", "True",
-					"Upgradeable", "True",
+					"Upgradeable", "Unknown",
 				),
 			},
 		},
@@ -182,7 +333,7 @@ func TestStatusReporterSetInProgress(t *testing.T) {
 					"Available", "Unknown",
 					"Degraded", "Unknown",
 					"Progressing", "True",
-					"Upgradeable", "True",
+					"Upgradeable", "Unknown",
 				),
 			},
 		},
@@ -249,7 +400,7 @@ func TestStatusReporterSetFailed(t *testing.T) {
 					"Available", "False",
 					"Degraded", "True",
 					"Progressing", "False",
-					"Upgradeable", "True",
+					"Upgradeable", "Unknown",
 				),
 				hasUnavailableMessage(),
 			},
@@ -277,7 +428,70 @@ func TestStatusReporterSetFailed(t *testing.T) {
 					"Available", "False",
 					"Degraded", "True",
 					"Progressing", "False",
-					"Upgradeable", "True",
+					"Upgradeable", "Unknown",
+				),
+				hasUnavailableMessage(),
+			},
+		},
+		{
+			name: "optional component failed",
+
+			given: givenStatusReporter{
+				operatorName:          "foo",
+				namespace:             "bar",
+				userWorkloadNamespace: "fred",
+				version:               "1.0",
+				err:                   failedErr,
+				components: []ComponentStatus{
+					{Name: "Updating Grafana", Healthy: false, Message: "foo"},
+					{Name: "Updating Prometheus-k8s", Healthy: true, Critical: true},
+				},
+			},
+
+			when: []whenFunc{
+				getReturnsClusterOperator(&v1.ClusterOperator{}),
+				updateStatusReturnsError(nil),
+			},
+
+			check: []checkFunc{
+				hasUpdatedStatus(true),
+				hasUpdatedStatusVersions(),
+				hasUpdatedStatusConditions(
+					"Available", "True",
+					"Degraded", "True",
+					"Progressing", "False",
+					"Upgradeable", "Unknown",
+				),
+				hasUpdatedStatusComponents(`{"components":[{"name":"Updating Grafana","healthy":false,"critical":false,"message":"foo"},{"name":"Updating Prometheus-k8s","healthy":true,"critical":true}]}`),
+			},
+		},
+		{
+			name: "critical component failed",
+
+			given: givenStatusReporter{
+				operatorName:          "foo",
+				namespace:             "bar",
+				userWorkloadNamespace: "fred",
+				version:               "1.0",
+				err:                   failedErr,
+				components: []ComponentStatus{
+					{Name: "Updating Grafana", Healthy: true},
+					{Name: "Updating Prometheus-k8s", Healthy: false, Critical: true, Message: "foo"},
+				},
+			},
+
+			when: []whenFunc{
+				getReturnsClusterOperator(&v1.ClusterOperator{}),
+				updateStatusReturnsError(nil),
+			},
+
+			check: []checkFunc{
+				hasUpdatedStatus(true),
+				hasUpdatedStatusConditions(
+					"Available", "False",
+					"Degraded", "True",
+					"Progressing", "False",
+					"Upgradeable", "Unknown",
 				),
 				hasUnavailableMessage(),
 			},
@@ -298,7 +512,106 @@ func TestStatusReporterSetFailed(t *testing.T) {
 				w(mock)
 			}
 
-			got := sr.SetFailed(tc.given.err, "")
+			got := sr.SetFailed(tc.given.err, "", tc.given.components)
+
+			for _, check := range tc.check {
+				if err := check(mock, got); err != nil {
+					t.Errorf("test case name '%s' failed with error: %v", tc.name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusReporterSetUpgradeable(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		given givenStatusReporter
+		when  []whenFunc
+		check []checkFunc
+	}{
+		{
+			name: "not found",
+
+			given: givenStatusReporter{
+				operatorName:          "foo",
+				namespace:             "bar",
+				userWorkloadNamespace: "fred",
+				version:               "1.0",
+			},
+
+			when: []whenFunc{
+				getReturnsError(&apierrors.StatusError{
+					ErrStatus: metav1.Status{Reason: metav1.StatusReasonNotFound},
+				}),
+				createReturnsError(nil),
+				updateStatusReturnsError(nil),
+			},
+
+			check: []checkFunc{
+				hasCreated(true),
+				hasUpdatedStatus(true),
+				hasUpdatedStatusConditions(
+					"Available", "Unknown",
+					"Degraded", "Unknown",
+					"Progressing", "Unknown",
+					"Upgradeable", "False",
+				),
+			},
+		},
+		{
+			name: "found",
+
+			given: givenStatusReporter{
+				operatorName:          "foo",
+				namespace:             "bar",
+				userWorkloadNamespace: "fred",
+				version:               "1.0",
+			},
+
+			when: []whenFunc{
+				getReturnsClusterOperator(&v1.ClusterOperator{
+					Status: v1.ClusterOperatorStatus{
+						Conditions: []v1.ClusterOperatorStatusCondition{
+							{Type: v1.OperatorAvailable, Status: v1.ConditionTrue},
+							{Type: v1.OperatorDegraded, Status: v1.ConditionFalse},
+							{Type: v1.OperatorProgressing, Status: v1.ConditionFalse},
+							{Type: v1.OperatorUpgradeable, Status: v1.ConditionTrue},
+						},
+					},
+				}),
+				updateStatusReturnsError(nil),
+			},
+
+			check: []checkFunc{
+				hasCreated(false),
+				hasUpdatedStatus(true),
+				hasUpdatedStatusConditions(
+					"Available", "True",
+					"Degraded", "False",
+					"Progressing", "False",
+					"Upgradeable", "False",
+				),
+				hasUpgradeableReason("FooBar"),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &clusterOperatorMock{}
+
+			sr := NewStatusReporter(
+				mock,
+				tc.given.operatorName,
+				tc.given.namespace,
+				tc.given.userWorkloadNamespace,
+				tc.given.version,
+			)
+
+			for _, w := range tc.when {
+				w(mock)
+			}
+
+			got := sr.SetUpgradeable(v1.ConditionFalse, "foo", "FooBar")
 
 			for _, check := range tc.check {
 				if err := check(mock, got); err != nil {
@@ -312,6 +625,8 @@ func TestStatusReporterSetFailed(t *testing.T) {
 type givenStatusReporter struct {
 	operatorName, namespace, userWorkloadNamespace, version string
 	err                                                     error
+	operands                                                []v1.OperandVersion
+	components                                              []ComponentStatus
 }
 
 type checkFunc func(*clusterOperatorMock, error) error
@@ -362,6 +677,37 @@ func hasUpdatedStatusConditions(want ...string) checkFunc {
 	}
 }
 
+func hasUpdatedStatusComponents(want string) checkFunc {
+	return func(mock *clusterOperatorMock, _ error) error {
+		if got := string(mock.statusUpdated.Status.Extension.Raw); got != want {
+			return fmt.Errorf("want status extension %s, got %s", want, got)
+		}
+		return nil
+	}
+}
+
+func hasUpgradeableReason(want string) checkFunc {
+	return func(mock *clusterOperatorMock, _ error) error {
+		for _, c := range mock.statusUpdated.Status.Conditions {
+			if c.Type == v1.OperatorUpgradeable && c.Reason != want {
+				return fmt.Errorf("want upgradeable reason %q, got %q", want, c.Reason)
+			}
+		}
+		return nil
+	}
+}
+
+func hasDegradedReason(want string) checkFunc {
+	return func(mock *clusterOperatorMock, _ error) error {
+		for _, c := range mock.statusUpdated.Status.Conditions {
+			if c.Type == v1.OperatorDegraded && c.Reason != want {
+				return fmt.Errorf("want degraded reason %q, got %q", want, c.Reason)
+			}
+		}
+		return nil
+	}
+}
+
 func hasUnavailableMessage() checkFunc {
 	return func(mock *clusterOperatorMock, _ error) error {
 		sort.Sort(byType(mock.statusUpdated.Status.Conditions))
@@ -0,0 +1,285 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// ExpandPrometheusVolumes grows the volumes of all the Prometheus shards to
+// the size requested by the volume claim template.
+func (c *Client) ExpandPrometheusVolumes(p *monv1.Prometheus) error {
+	return c.expandVolumes(p.GetNamespace(), prometheusStatefulSetNames(p), p.Spec.Storage)
+}
+
+// ExpandAlertmanagerVolumes grows the volumes of the Alertmanager to the size
+// requested by the volume claim template.
+func (c *Client) ExpandAlertmanagerVolumes(a *monv1.Alertmanager) error {
+	return c.expandVolumes(a.GetNamespace(), []string{"alertmanager-" + a.GetName()}, a.Spec.Storage)
+}
+
+// ExpandThanosRulerVolumes grows the volumes of the Thanos Ruler to the size
+// requested by the volume claim template.
+func (c *Client) ExpandThanosRulerVolumes(t *monv1.ThanosRuler) error {
+	return c.expandVolumes(t.GetNamespace(), []string{"thanos-ruler-" + t.GetName()}, t.Spec.Storage)
+}
+
+// expandVolumes increases the storage request of the bound PVCs created by
+// the StatefulSets when it is lower than the request of the volume claim
+// template. The volumeClaimTemplates of a StatefulSet are immutable so a
+// larger size only applies to new PVCs otherwise.
+func (c *Client) expandVolumes(namespace string, statefulSets []string, storage *monv1.StorageSpec) error {
+	if storage == nil {
+		return nil
+	}
+	size, found := storage.VolumeClaimTemplate.Spec.Resources.Requests[v1.ResourceStorage]
+	if !found || size.IsZero() {
+		return nil
+	}
+
+	for _, name := range statefulSets {
+		pvcs, err := c.statefulSetVolumeClaims(namespace, name)
+		if err != nil {
+			return err
+		}
+
+		for i := range pvcs {
+			if err := c.expandVolumeClaim(&pvcs[i], size); err != nil {
+				return errors.Wrapf(err, "expanding PersistentVolumeClaim %s/%s failed", namespace, pvcs[i].Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// statefulSetVolumeClaims returns the PVCs created from the volume claim
+// templates of the StatefulSet. It returns nothing if the StatefulSet doesn't
+// exist yet.
+func (c *Client) statefulSetVolumeClaims(namespace, name string) ([]v1.PersistentVolumeClaim, error) {
+	sts, err := c.kclient.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving StatefulSet object failed")
+	}
+
+	return c.volumeClaims(sts)
+}
+
+// volumeClaims returns the PVCs created from the volume claim templates of
+// the StatefulSet.
+func (c *Client) volumeClaims(sts *appsv1.StatefulSet) ([]v1.PersistentVolumeClaim, error) {
+	selector, err := metav1.LabelSelectorAsSelector(sts.Spec.Selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid selector of StatefulSet %s/%s", sts.Namespace, sts.Name)
+	}
+	pvcs, err := c.kclient.CoreV1().PersistentVolumeClaims(sts.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing PersistentVolumeClaim objects failed")
+	}
+
+	var res []v1.PersistentVolumeClaim
+	for _, pvc := range pvcs.Items {
+		if volumeClaimTemplate(sts, pvc.Name) != nil {
+			res = append(res, pvc)
+		}
+	}
+	return res, nil
+}
+
+// volumeClaimTemplate returns the volume claim template of the StatefulSet
+// from which the PVC was created, nil if there is none.
+func volumeClaimTemplate(sts *appsv1.StatefulSet, pvcName string) *v1.PersistentVolumeClaim {
+	for i, tpl := range sts.Spec.VolumeClaimTemplates {
+		// The StatefulSet controller names the PVCs
+		// <template>-<statefulset>-<ordinal>.
+		ordinal := strings.TrimPrefix(pvcName, tpl.Name+"-"+sts.Name+"-")
+		if ordinal != pvcName && isDigits(ordinal) {
+			return &sts.Spec.VolumeClaimTemplates[i]
+		}
+	}
+	return nil
+}
+
+// expandVolumeClaim updates the storage request of the PVC if it is bound and
+// requests less than size. Shrinking isn't supported by Kubernetes so smaller
+// sizes are ignored. The PVC is skipped when its storage class doesn't allow
+// volume expansion, PendingVolumeExpansions reports it.
+func (c *Client) expandVolumeClaim(pvc *v1.PersistentVolumeClaim, size resource.Quantity) error {
+	if pvc.Status.Phase != v1.ClaimBound {
+		return nil
+	}
+	current := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	if current.Cmp(size) >= 0 {
+		return nil
+	}
+
+	blocked, err := c.volumeExpansionBlocked(pvc)
+	if err != nil {
+		return err
+	}
+	if blocked != "" {
+		klog.Warningf("Not expanding PersistentVolumeClaim %s/%s to %s: %s", pvc.Namespace, pvc.Name, size.String(), blocked)
+		return nil
+	}
+
+	required := pvc.DeepCopy()
+	if required.Spec.Resources.Requests == nil {
+		required.Spec.Resources.Requests = v1.ResourceList{}
+	}
+	required.Spec.Resources.Requests[v1.ResourceStorage] = size
+
+	updated, err := c.kclient.CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(context.TODO(), required, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "updating PersistentVolumeClaim object failed")
+	}
+	c.objectUpdated("PersistentVolumeClaim", pvc, updated)
+	return nil
+}
+
+// volumeExpansionBlocked returns why the storage class of the PVC doesn't
+// allow volume expansion, an empty string if it does.
+func (c *Client) volumeExpansionBlocked(pvc *v1.PersistentVolumeClaim) (string, error) {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return "no storage class, volume expansion isn't possible", nil
+	}
+	sc, err := c.kclient.StorageV1().StorageClasses().Get(context.TODO(), *pvc.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "retrieving StorageClass object failed")
+	}
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		return fmt.Sprintf("storage class %q doesn't allow volume expansion", sc.Name), nil
+	}
+	return "", nil
+}
+
+// VolumeExpansion is a PVC of a monitoring component which doesn't have the
+// size requested by its volume claim template yet.
+type VolumeExpansion struct {
+	// Claim is the namespace/name of the PVC.
+	Claim string
+	// Blocked tells why the PVC can't be expanded, it is empty while the
+	// expansion is pending or in progress.
+	Blocked string
+}
+
+// PendingVolumeExpansions returns the volume expansions which haven't
+// completed in the given namespaces, sorted by claim. Only the PVCs of the
+// StatefulSets of the Prometheus, Alertmanager and ThanosRuler resources are
+// considered.
+func (c *Client) PendingVolumeExpansions(namespaces ...string) ([]VolumeExpansion, error) {
+	var res []VolumeExpansion
+	for _, ns := range namespaces {
+		stss, err := c.kclient.AppsV1().StatefulSets(ns).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "listing StatefulSet objects failed")
+		}
+
+		for i := range stss.Items {
+			sts := &stss.Items[i]
+			if !isMonitoringStatefulSet(sts) {
+				continue
+			}
+
+			pvcs, err := c.volumeClaims(sts)
+			if err != nil {
+				return nil, err
+			}
+			for i := range pvcs {
+				pvc := &pvcs[i]
+				if pvc.Status.Phase != v1.ClaimBound {
+					continue
+				}
+
+				claim := ns + "/" + pvc.Name
+				size := volumeClaimTemplate(sts, pvc.Name).Spec.Resources.Requests[v1.ResourceStorage]
+				current := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+				if current.Cmp(size) < 0 {
+					blocked, err := c.volumeExpansionBlocked(pvc)
+					if err != nil {
+						return nil, errors.Wrapf(err, "checking PersistentVolumeClaim %s failed", claim)
+					}
+					res = append(res, VolumeExpansion{Claim: claim, Blocked: blocked})
+					continue
+				}
+				if isResizing(pvc) {
+					res = append(res, VolumeExpansion{Claim: claim})
+				}
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Claim < res[j].Claim
+	})
+	return res, nil
+}
+
+// isMonitoringStatefulSet returns true if the StatefulSet is controlled by a
+// Prometheus, Alertmanager or ThanosRuler resource.
+func isMonitoringStatefulSet(sts *appsv1.StatefulSet) bool {
+	ref := metav1.GetControllerOf(sts)
+	if ref == nil || ref.APIVersion != monv1.SchemeGroupVersion.String() {
+		return false
+	}
+	switch ref.Kind {
+	case monv1.PrometheusesKind, monv1.AlertmanagersKind, monv1.ThanosRulerKind:
+		return true
+	}
+	return false
+}
+
+// isResizing returns true if the capacity of the bound PVC is lower than its
+// request or if a resize condition is set.
+func isResizing(pvc *v1.PersistentVolumeClaim) bool {
+	if pvc.Status.Phase != v1.ClaimBound {
+		return false
+	}
+	for _, cond := range pvc.Status.Conditions {
+		if (cond.Type == v1.PersistentVolumeClaimResizing || cond.Type == v1.PersistentVolumeClaimFileSystemResizePending) && cond.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+
+	request := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	capacity, found := pvc.Status.Capacity[v1.ResourceStorage]
+	return found && capacity.Cmp(request) < 0
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
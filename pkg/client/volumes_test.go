@@ -0,0 +1,253 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestExpandPrometheusVolumes(t *testing.T) {
+	labels := map[string]string{"prometheus": "k8s"}
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "prometheus-k8s", Namespace: ns},
+		Spec: appsv1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			VolumeClaimTemplates: []v1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "prometheus-k8s-db"}},
+			},
+		},
+	}
+	pvc := func(name, class, size string, phase v1.PersistentVolumeClaimPhase) *v1.PersistentVolumeClaim {
+		return &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: labels},
+			Spec: v1.PersistentVolumeClaimSpec{
+				StorageClassName: &class,
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)},
+				},
+			},
+			Status: v1.PersistentVolumeClaimStatus{Phase: phase},
+		}
+	}
+	storageClass := func(name string, allowExpansion bool) *storagev1.StorageClass {
+		return &storagev1.StorageClass{
+			ObjectMeta:           metav1.ObjectMeta{Name: name},
+			AllowVolumeExpansion: &allowExpansion,
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		size     string
+		objects  []runtime.Object
+		expected map[string]string
+	}{
+		{
+			name: "expansion",
+			size: "20Gi",
+			objects: []runtime.Object{
+				sts,
+				storageClass("standard", true),
+				pvc("prometheus-k8s-db-prometheus-k8s-0", "standard", "10Gi", v1.ClaimBound),
+				pvc("prometheus-k8s-db-prometheus-k8s-1", "standard", "10Gi", v1.ClaimBound),
+			},
+			expected: map[string]string{
+				"prometheus-k8s-db-prometheus-k8s-0": "20Gi",
+				"prometheus-k8s-db-prometheus-k8s-1": "20Gi",
+			},
+		},
+		{
+			name: "no shrinking",
+			size: "5Gi",
+			objects: []runtime.Object{
+				sts,
+				storageClass("standard", true),
+				pvc("prometheus-k8s-db-prometheus-k8s-0", "standard", "10Gi", v1.ClaimBound),
+			},
+			expected: map[string]string{
+				"prometheus-k8s-db-prometheus-k8s-0": "10Gi",
+			},
+		},
+		{
+			name: "pending and unrelated claims",
+			size: "20Gi",
+			objects: []runtime.Object{
+				sts,
+				storageClass("standard", false),
+				pvc("prometheus-k8s-db-prometheus-k8s-0", "standard", "10Gi", v1.ClaimPending),
+				pvc("prometheus-k8s-db-prometheus-k8s-shard-1-0", "standard", "10Gi", v1.ClaimBound),
+			},
+			expected: map[string]string{
+				"prometheus-k8s-db-prometheus-k8s-0":         "10Gi",
+				"prometheus-k8s-db-prometheus-k8s-shard-1-0": "10Gi",
+			},
+		},
+		{
+			name: "expansion not allowed",
+			size: "20Gi",
+			objects: []runtime.Object{
+				sts,
+				storageClass("standard", false),
+				pvc("prometheus-k8s-db-prometheus-k8s-0", "standard", "10Gi", v1.ClaimBound),
+			},
+			expected: map[string]string{
+				"prometheus-k8s-db-prometheus-k8s-0": "10Gi",
+			},
+		},
+		{
+			name: "no statefulset",
+			size: "20Gi",
+			objects: []runtime.Object{
+				storageClass("standard", true),
+				pvc("prometheus-k8s-db-prometheus-k8s-0", "standard", "10Gi", v1.ClaimBound),
+			},
+			expected: map[string]string{
+				"prometheus-k8s-db-prometheus-k8s-0": "10Gi",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := Client{kclient: fake.NewSimpleClientset(tc.objects...)}
+			p := &monv1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: ns},
+				Spec: monv1.PrometheusSpec{
+					Storage: &monv1.StorageSpec{
+						VolumeClaimTemplate: monv1.EmbeddedPersistentVolumeClaim{
+							Spec: v1.PersistentVolumeClaimSpec{
+								Resources: v1.ResourceRequirements{
+									Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(tc.size)},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			if err := c.ExpandPrometheusVolumes(p); err != nil {
+				t.Fatal(err)
+			}
+
+			pvcs, err := c.kclient.CoreV1().PersistentVolumeClaims(ns).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for _, pvc := range pvcs.Items {
+				size := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+				got[pvc.Name] = size.String()
+			}
+			if !reflect.DeepEqual(tc.expected, got) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPendingVolumeExpansions(t *testing.T) {
+	statefulSet := func(name, namespace, ownerKind, size string) *appsv1.StatefulSet {
+		controller := true
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: monv1.SchemeGroupVersion.String(),
+					Kind:       ownerKind,
+					Name:       name,
+					Controller: &controller,
+				}},
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+				VolumeClaimTemplates: []v1.PersistentVolumeClaim{{
+					ObjectMeta: metav1.ObjectMeta{Name: "db"},
+					Spec: v1.PersistentVolumeClaimSpec{
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)},
+						},
+					},
+				}},
+			},
+		}
+	}
+	pvc := func(sts *appsv1.StatefulSet, ordinal, class, request, capacity string, conditions ...v1.PersistentVolumeClaimConditionType) *v1.PersistentVolumeClaim {
+		pvc := &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "db-" + sts.Name + "-" + ordinal,
+				Namespace: sts.Namespace,
+				Labels:    sts.Spec.Selector.MatchLabels,
+			},
+			Spec: v1.PersistentVolumeClaimSpec{
+				StorageClassName: &class,
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(request)},
+				},
+			},
+			Status: v1.PersistentVolumeClaimStatus{
+				Phase:    v1.ClaimBound,
+				Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse(capacity)},
+			},
+		}
+		for _, cond := range conditions {
+			pvc.Status.Conditions = append(pvc.Status.Conditions, v1.PersistentVolumeClaimCondition{Type: cond, Status: v1.ConditionTrue})
+		}
+		return pvc
+	}
+	allowExpansion, denyExpansion := true, false
+
+	prometheus := statefulSet("prometheus-k8s", ns, monv1.PrometheusesKind, "20Gi")
+	alertmanager := statefulSet("alertmanager-main", ns, monv1.AlertmanagersKind, "20Gi")
+	thanosRuler := statefulSet("thanos-ruler-user-workload", nsUWM, monv1.ThanosRulerKind, "20Gi")
+	other := statefulSet("other", nsUWM, "Other", "20Gi")
+
+	c := Client{
+		kclient: fake.NewSimpleClientset(
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "standard"}, AllowVolumeExpansion: &allowExpansion},
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fixed"}, AllowVolumeExpansion: &denyExpansion},
+			prometheus, alertmanager, thanosRuler, other,
+			pvc(prometheus, "0", "standard", "20Gi", "20Gi"),
+			pvc(prometheus, "1", "standard", "20Gi", "10Gi", v1.PersistentVolumeClaimResizing),
+			pvc(alertmanager, "0", "standard", "10Gi", "10Gi"),
+			pvc(alertmanager, "1", "fixed", "10Gi", "10Gi"),
+			pvc(thanosRuler, "0", "standard", "20Gi", "20Gi", v1.PersistentVolumeClaimFileSystemResizePending),
+			pvc(other, "0", "standard", "20Gi", "10Gi"),
+		),
+	}
+
+	got, err := c.PendingVolumeExpansions(ns, nsUWM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []VolumeExpansion{
+		{Claim: ns + "/db-alertmanager-main-0"},
+		{Claim: ns + "/db-alertmanager-main-1", Blocked: `storage class "fixed" doesn't allow volume expansion`},
+		{Claim: ns + "/db-prometheus-k8s-1"},
+		{Claim: nsUWM + "/db-thanos-ruler-user-workload-0"},
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
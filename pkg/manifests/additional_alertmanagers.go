@@ -0,0 +1,241 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AdditionalAlertmanagerConfigsKey is the key of the additional
+	// Alertmanager configurations in the Prometheus Secrets.
+	AdditionalAlertmanagerConfigsKey = "alertmanager-configs.yaml"
+
+	// ThanosRulerAlertmanagersConfigKey is the key of the Alertmanager
+	// configuration in the Thanos Ruler Secret.
+	ThanosRulerAlertmanagersConfigKey = "alertmanagers.yaml"
+
+	// Prometheus operator mounts the Prometheus secrets under this path, the
+	// same path is used for Thanos Ruler.
+	alertmanagerSecretsMountPath = "/etc/prometheus/secrets"
+)
+
+type alertmanagerHTTPClientConfig struct {
+	BasicAuth       *alertmanagerBasicAuthConfig `yaml:"basic_auth,omitempty"`
+	BearerTokenFile string                       `yaml:"bearer_token_file,omitempty"`
+	TLSConfig       *alertmanagerTLSFileConfig   `yaml:"tls_config,omitempty"`
+}
+
+type alertmanagerBasicAuthConfig struct {
+	Username     string `yaml:"username,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"`
+}
+
+type alertmanagerTLSFileConfig struct {
+	CAFile             string `yaml:"ca_file,omitempty"`
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	ServerName         string `yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// prometheusAlertmanagerConfig is an entry of the alerting.alertmanagers
+// section of the Prometheus configuration.
+type prometheusAlertmanagerConfig struct {
+	Scheme                       string `yaml:"scheme,omitempty"`
+	PathPrefix                   string `yaml:"path_prefix,omitempty"`
+	Timeout                      string `yaml:"timeout,omitempty"`
+	APIVersion                   string `yaml:"api_version,omitempty"`
+	alertmanagerHTTPClientConfig `yaml:",inline"`
+	StaticConfigs                []prometheusStaticConfig `yaml:"static_configs,omitempty"`
+}
+
+type prometheusStaticConfig struct {
+	Targets []string `yaml:"targets"`
+}
+
+// thanosAlertmanagersConfig is the --alertmanagers.config file of Thanos
+// Ruler.
+type thanosAlertmanagersConfig struct {
+	Alertmanagers []thanosAlertmanagerConfig `yaml:"alertmanagers"`
+}
+
+type thanosAlertmanagerConfig struct {
+	APIVersion    string                       `yaml:"api_version,omitempty"`
+	HTTPConfig    alertmanagerHTTPClientConfig `yaml:"http_config,omitempty"`
+	PathPrefix    string                       `yaml:"path_prefix,omitempty"`
+	Scheme        string                       `yaml:"scheme,omitempty"`
+	StaticConfigs []string                     `yaml:"static_configs"`
+	Timeout       string                       `yaml:"timeout,omitempty"`
+}
+
+// validateAdditionalAlertmanagerConfig returns an error if the configuration
+// can't be rendered.
+func validateAdditionalAlertmanagerConfig(c AdditionalAlertmanagerConfig) error {
+	if len(c.StaticAddresses) == 0 {
+		return errors.New("at least one static address is required")
+	}
+
+	if c.BearerToken != nil && c.BasicAuth != nil {
+		return errors.New("bearerToken and basicAuth are mutually exclusive")
+	}
+
+	if c.BasicAuth != nil && (c.BasicAuth.Username == "" || c.BasicAuth.Password == nil) {
+		return errors.New("basicAuth requires both username and password")
+	}
+
+	for _, s := range alertmanagerConfigSecretKeys(c) {
+		if s.Name == "" || s.Key == "" {
+			return errors.New("secret references require both name and key")
+		}
+	}
+
+	return nil
+}
+
+// alertmanagerConfigSecretKeys returns the Secret keys referenced by the
+// configuration.
+func alertmanagerConfigSecretKeys(c AdditionalAlertmanagerConfig) []*v1.SecretKeySelector {
+	var keys []*v1.SecretKeySelector
+	if c.BearerToken != nil {
+		keys = append(keys, c.BearerToken)
+	}
+	if c.BasicAuth != nil && c.BasicAuth.Password != nil {
+		keys = append(keys, c.BasicAuth.Password)
+	}
+	if c.TLSConfig != nil {
+		for _, s := range []*v1.SecretKeySelector{c.TLSConfig.CA, c.TLSConfig.Cert, c.TLSConfig.Key} {
+			if s != nil {
+				keys = append(keys, s)
+			}
+		}
+	}
+	return keys
+}
+
+// alertmanagerConfigSecrets returns the sorted names of the Secrets which
+// need to be mounted for the given configurations.
+func alertmanagerConfigSecrets(cfgs []AdditionalAlertmanagerConfig) []string {
+	found := map[string]struct{}{}
+	for _, c := range cfgs {
+		for _, s := range alertmanagerConfigSecretKeys(c) {
+			found[s.Name] = struct{}{}
+		}
+	}
+
+	secrets := make([]string, 0, len(found))
+	for name := range found {
+		secrets = append(secrets, name)
+	}
+	sort.Strings(secrets)
+
+	return secrets
+}
+
+func alertmanagerSecretPath(s *v1.SecretKeySelector) string {
+	if s == nil {
+		return ""
+	}
+	return path.Join(alertmanagerSecretsMountPath, s.Name, s.Key)
+}
+
+func newAlertmanagerHTTPClientConfig(c AdditionalAlertmanagerConfig) alertmanagerHTTPClientConfig {
+	hc := alertmanagerHTTPClientConfig{
+		BearerTokenFile: alertmanagerSecretPath(c.BearerToken),
+	}
+
+	if c.BasicAuth != nil {
+		hc.BasicAuth = &alertmanagerBasicAuthConfig{
+			Username:     c.BasicAuth.Username,
+			PasswordFile: alertmanagerSecretPath(c.BasicAuth.Password),
+		}
+	}
+
+	if c.TLSConfig != nil {
+		hc.TLSConfig = &alertmanagerTLSFileConfig{
+			CAFile:             alertmanagerSecretPath(c.TLSConfig.CA),
+			CertFile:           alertmanagerSecretPath(c.TLSConfig.Cert),
+			KeyFile:            alertmanagerSecretPath(c.TLSConfig.Key),
+			ServerName:         c.TLSConfig.ServerName,
+			InsecureSkipVerify: c.TLSConfig.InsecureSkipVerify,
+		}
+	}
+
+	return hc
+}
+
+// renderPrometheusAlertmanagerConfigs returns the additional Alertmanager
+// configurations in the format expected by the Prometheus operator.
+func renderPrometheusAlertmanagerConfigs(cfgs []AdditionalAlertmanagerConfig) (string, error) {
+	res := make([]prometheusAlertmanagerConfig, 0, len(cfgs))
+	for i, c := range cfgs {
+		if err := validateAdditionalAlertmanagerConfig(c); err != nil {
+			return "", errors.Wrap(err, fmt.Sprintf("invalid additional Alertmanager configuration #%d", i))
+		}
+
+		res = append(res, prometheusAlertmanagerConfig{
+			Scheme:                       c.Scheme,
+			PathPrefix:                   c.PathPrefix,
+			Timeout:                      c.Timeout,
+			APIVersion:                   c.APIVersion,
+			alertmanagerHTTPClientConfig: newAlertmanagerHTTPClientConfig(c),
+			StaticConfigs:                []prometheusStaticConfig{{Targets: c.StaticAddresses}},
+		})
+	}
+
+	b, err := yaml.Marshal(res)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling additional Alertmanager configurations failed")
+	}
+
+	return string(b), nil
+}
+
+// appendThanosAlertmanagerConfigs adds the additional Alertmanager
+// configurations to the given Thanos Ruler alertmanagers configuration.
+func appendThanosAlertmanagerConfigs(content string, cfgs []AdditionalAlertmanagerConfig) (string, error) {
+	var tc thanosAlertmanagersConfig
+	if err := yaml.UnmarshalStrict([]byte(content), &tc); err != nil {
+		return "", errors.Wrap(err, "unmarshaling Thanos Ruler alertmanagers configuration failed")
+	}
+
+	for i, c := range cfgs {
+		if err := validateAdditionalAlertmanagerConfig(c); err != nil {
+			return "", errors.Wrap(err, fmt.Sprintf("invalid additional Alertmanager configuration #%d", i))
+		}
+
+		tc.Alertmanagers = append(tc.Alertmanagers, thanosAlertmanagerConfig{
+			APIVersion:    c.APIVersion,
+			HTTPConfig:    newAlertmanagerHTTPClientConfig(c),
+			PathPrefix:    c.PathPrefix,
+			Scheme:        c.Scheme,
+			StaticConfigs: c.StaticAddresses,
+			Timeout:       c.Timeout,
+		})
+	}
+
+	b, err := yaml.Marshal(tc)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling Thanos Ruler alertmanagers configuration failed")
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+)
+
+const severityLabel = "severity"
+
+// PlatformPrometheusRules lists the PrometheusRule assets shipped with the
+// platform stack, the alert overrides can only reference alerts defined
+// there.
+var PlatformPrometheusRules = []string{
+	AlertmanagerPrometheusRule,
+	ClusterMonitoringOperatorPrometheusRule,
+	ControlPlaneEtcdPrometheusRule,
+	ControlPlanePrometheusRule,
+	KubeStateMetricsPrometheusRule,
+	NodeExporterPrometheusRule,
+	PrometheusK8sPrometheusRule,
+	PrometheusOperatorPrometheusRule,
+	ThanosQuerierPrometheusRule,
+	ThanosRulerPrometheusRule,
+}
+
+// ValidateAlertOverrides returns an error if an alert override is invalid or
+// references an alert which isn't shipped with the platform stack.
+func (f *Factory) ValidateAlertOverrides() error {
+	overrides := f.config.ClusterMonitoringConfiguration.AlertOverrides
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	alerts := map[string]struct{}{}
+	for _, asset := range PlatformPrometheusRules {
+		r, err := NewPrometheusRule(f.assets.MustNewAssetReader(asset))
+		if err != nil {
+			return errors.Wrapf(err, "loading %s failed", asset)
+		}
+		for _, g := range r.Spec.Groups {
+			for _, rule := range g.Rules {
+				if rule.Alert != "" {
+					alerts[rule.Alert] = struct{}{}
+				}
+			}
+		}
+	}
+
+	seen := map[string]struct{}{}
+	for _, o := range overrides {
+		if o.Alert == "" {
+			return errors.New("alert override without alert name")
+		}
+		if _, found := seen[o.Alert]; found {
+			return fmt.Errorf("alert %q is overridden more than once", o.Alert)
+		}
+		seen[o.Alert] = struct{}{}
+
+		if _, found := alerts[o.Alert]; !found {
+			return fmt.Errorf("alert %q isn't a platform alert", o.Alert)
+		}
+
+		if o.For != "" {
+			if _, err := model.ParseDuration(o.For); err != nil {
+				return errors.Wrapf(err, "invalid 'for' duration of alert %q", o.Alert)
+			}
+		}
+
+		if _, found := o.Labels[severityLabel]; found {
+			return fmt.Errorf("the severity of alert %q must be overridden with the severity field", o.Alert)
+		}
+		for name := range o.Labels {
+			if !model.LabelName(name).IsValid() {
+				return fmt.Errorf("invalid label name %q for alert %q", name, o.Alert)
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyAlertOverrides modifies the alerting rules according to the alert
+// overrides. Groups left without rules are removed.
+func (f *Factory) applyAlertOverrides(p *monv1.PrometheusRule) {
+	overrides := f.config.ClusterMonitoringConfiguration.AlertOverrides
+	if len(overrides) == 0 {
+		return
+	}
+
+	byAlert := make(map[string]AlertOverride, len(overrides))
+	for _, o := range overrides {
+		byAlert[o.Alert] = o
+	}
+
+	groups := p.Spec.Groups[:0]
+	for _, g := range p.Spec.Groups {
+		rules := g.Rules[:0]
+		for _, rule := range g.Rules {
+			o, found := byAlert[rule.Alert]
+			if rule.Alert == "" || !found {
+				rules = append(rules, rule)
+				continue
+			}
+
+			if o.Disabled {
+				continue
+			}
+
+			if o.For != "" {
+				rule.For = o.For
+			}
+
+			if o.Severity != "" || len(o.Labels) > 0 {
+				labels := make(map[string]string, len(rule.Labels)+len(o.Labels)+1)
+				for k, v := range rule.Labels {
+					labels[k] = v
+				}
+				for k, v := range o.Labels {
+					labels[k] = v
+				}
+				if o.Severity != "" {
+					labels[severityLabel] = o.Severity
+				}
+				rule.Labels = labels
+			}
+
+			rules = append(rules, rule)
+		}
+
+		if len(rules) == 0 {
+			continue
+		}
+		g.Rules = rules
+		groups = append(groups, g)
+	}
+	p.Spec.Groups = groups
+}
@@ -0,0 +1,290 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	amconfig "github.com/prometheus/alertmanager/config"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AlertmanagerConfigKey is the key of the Alertmanager configuration
+	// in the alertmanager-main Secret.
+	AlertmanagerConfigKey = "alertmanager.yaml"
+
+	watchdogAlertName = "Watchdog"
+	watchdogReceiver  = "Watchdog"
+)
+
+// AlertmanagerConfiguration is the structured configuration of the platform
+// Alertmanager. The json tags define the fields of the cluster monitoring
+// config while the yaml tags map them to the Alertmanager configuration
+// file.
+//
+// Credentials are references to keys of Secrets in the namespace of the
+// platform Alertmanager so that they never appear in the cluster monitoring
+// config nor in the ClusterMonitoring resource. The *Value fields can't be
+// configured, they receive the content of the Secrets at render time.
+type AlertmanagerConfiguration struct {
+	Global       *AlertmanagerGlobalConfig `json:"global" yaml:"global,omitempty"`
+	Route        *AlertmanagerRouteConfig  `json:"route" yaml:"route,omitempty"`
+	InhibitRules []AlertmanagerInhibitRule `json:"inhibitRules" yaml:"inhibit_rules,omitempty"`
+	Receivers    []AlertmanagerReceiver    `json:"receivers" yaml:"receivers,omitempty"`
+	Templates    []string                  `json:"templates" yaml:"templates,omitempty"`
+}
+
+type AlertmanagerGlobalConfig struct {
+	ResolveTimeout   string `json:"resolveTimeout" yaml:"resolve_timeout,omitempty"`
+	SMTPFrom         string `json:"smtpFrom" yaml:"smtp_from,omitempty"`
+	SMTPHello        string `json:"smtpHello" yaml:"smtp_hello,omitempty"`
+	SMTPSmarthost    string `json:"smtpSmarthost" yaml:"smtp_smarthost,omitempty"`
+	SMTPAuthUsername string `json:"smtpAuthUsername" yaml:"smtp_auth_username,omitempty"`
+	// SMTPAuthPassword references the Secret key holding the SMTP password.
+	SMTPAuthPassword      *v1.SecretKeySelector `json:"smtpAuthPassword" yaml:"-"`
+	SMTPAuthPasswordValue string                `json:"-" yaml:"smtp_auth_password,omitempty"`
+	SMTPAuthIdentity      string                `json:"smtpAuthIdentity" yaml:"smtp_auth_identity,omitempty"`
+	SMTPRequireTLS        *bool                 `json:"smtpRequireTLS" yaml:"smtp_require_tls,omitempty"`
+	// SlackAPIURL references the Secret key holding the Slack webhook URL.
+	SlackAPIURL      *v1.SecretKeySelector `json:"slackAPIURL" yaml:"-"`
+	SlackAPIURLValue string                `json:"-" yaml:"slack_api_url,omitempty"`
+	PagerdutyURL     string                `json:"pagerdutyURL" yaml:"pagerduty_url,omitempty"`
+}
+
+type AlertmanagerRouteConfig struct {
+	Receiver       string                    `json:"receiver" yaml:"receiver,omitempty"`
+	GroupBy        []string                  `json:"groupBy" yaml:"group_by,omitempty"`
+	GroupWait      string                    `json:"groupWait" yaml:"group_wait,omitempty"`
+	GroupInterval  string                    `json:"groupInterval" yaml:"group_interval,omitempty"`
+	RepeatInterval string                    `json:"repeatInterval" yaml:"repeat_interval,omitempty"`
+	Match          map[string]string         `json:"match" yaml:"match,omitempty"`
+	MatchRE        map[string]string         `json:"matchRE" yaml:"match_re,omitempty"`
+	Continue       bool                      `json:"continue" yaml:"continue,omitempty"`
+	Routes         []AlertmanagerRouteConfig `json:"routes" yaml:"routes,omitempty"`
+}
+
+type AlertmanagerInhibitRule struct {
+	SourceMatch   map[string]string `json:"sourceMatch" yaml:"source_match,omitempty"`
+	SourceMatchRE map[string]string `json:"sourceMatchRE" yaml:"source_match_re,omitempty"`
+	TargetMatch   map[string]string `json:"targetMatch" yaml:"target_match,omitempty"`
+	TargetMatchRE map[string]string `json:"targetMatchRE" yaml:"target_match_re,omitempty"`
+	Equal         []string          `json:"equal" yaml:"equal,omitempty"`
+}
+
+type AlertmanagerReceiver struct {
+	Name             string                        `json:"name" yaml:"name"`
+	EmailConfigs     []AlertmanagerEmailConfig     `json:"emailConfigs" yaml:"email_configs,omitempty"`
+	PagerdutyConfigs []AlertmanagerPagerdutyConfig `json:"pagerdutyConfigs" yaml:"pagerduty_configs,omitempty"`
+	SlackConfigs     []AlertmanagerSlackConfig     `json:"slackConfigs" yaml:"slack_configs,omitempty"`
+	WebhookConfigs   []AlertmanagerWebhookConfig   `json:"webhookConfigs" yaml:"webhook_configs,omitempty"`
+}
+
+type AlertmanagerEmailConfig struct {
+	SendResolved *bool  `json:"sendResolved" yaml:"send_resolved,omitempty"`
+	To           string `json:"to" yaml:"to,omitempty"`
+	From         string `json:"from" yaml:"from,omitempty"`
+	Hello        string `json:"hello" yaml:"hello,omitempty"`
+	Smarthost    string `json:"smarthost" yaml:"smarthost,omitempty"`
+	AuthUsername string `json:"authUsername" yaml:"auth_username,omitempty"`
+	// AuthPassword references the Secret key holding the SMTP password.
+	AuthPassword      *v1.SecretKeySelector `json:"authPassword" yaml:"-"`
+	AuthPasswordValue string                `json:"-" yaml:"auth_password,omitempty"`
+	AuthIdentity      string                `json:"authIdentity" yaml:"auth_identity,omitempty"`
+	Headers           map[string]string     `json:"headers" yaml:"headers,omitempty"`
+	HTML              string                `json:"html" yaml:"html,omitempty"`
+	Text              string                `json:"text" yaml:"text,omitempty"`
+	RequireTLS        *bool                 `json:"requireTLS" yaml:"require_tls,omitempty"`
+}
+
+type AlertmanagerPagerdutyConfig struct {
+	SendResolved *bool `json:"sendResolved" yaml:"send_resolved,omitempty"`
+	// RoutingKey references the Secret key holding the PagerDuty
+	// integration key when using the Events API v2.
+	RoutingKey      *v1.SecretKeySelector `json:"routingKey" yaml:"-"`
+	RoutingKeyValue string                `json:"-" yaml:"routing_key,omitempty"`
+	// ServiceKey references the Secret key holding the PagerDuty
+	// integration key when using the integration type "Prometheus".
+	ServiceKey      *v1.SecretKeySelector `json:"serviceKey" yaml:"-"`
+	ServiceKeyValue string                `json:"-" yaml:"service_key,omitempty"`
+	URL             string                `json:"url" yaml:"url,omitempty"`
+	Client          string                `json:"client" yaml:"client,omitempty"`
+	ClientURL       string                `json:"clientURL" yaml:"client_url,omitempty"`
+	Description     string                `json:"description" yaml:"description,omitempty"`
+	Severity        string                `json:"severity" yaml:"severity,omitempty"`
+	Details         map[string]string     `json:"details" yaml:"details,omitempty"`
+}
+
+type AlertmanagerSlackConfig struct {
+	SendResolved *bool `json:"sendResolved" yaml:"send_resolved,omitempty"`
+	// APIURL references the Secret key holding the Slack webhook URL.
+	APIURL      *v1.SecretKeySelector `json:"apiURL" yaml:"-"`
+	APIURLValue string                `json:"-" yaml:"api_url,omitempty"`
+	Channel     string                `json:"channel" yaml:"channel,omitempty"`
+	Username    string                `json:"username" yaml:"username,omitempty"`
+	Color       string                `json:"color" yaml:"color,omitempty"`
+	Title       string                `json:"title" yaml:"title,omitempty"`
+	TitleLink   string                `json:"titleLink" yaml:"title_link,omitempty"`
+	Text        string                `json:"text" yaml:"text,omitempty"`
+	IconEmoji   string                `json:"iconEmoji" yaml:"icon_emoji,omitempty"`
+	IconURL     string                `json:"iconURL" yaml:"icon_url,omitempty"`
+}
+
+type AlertmanagerWebhookConfig struct {
+	SendResolved *bool  `json:"sendResolved" yaml:"send_resolved,omitempty"`
+	URL          string `json:"url" yaml:"url"`
+	MaxAlerts    uint64 `json:"maxAlerts" yaml:"max_alerts,omitempty"`
+}
+
+// SecretResolver returns the value of a key of a Secret.
+type SecretResolver func(*v1.SecretKeySelector) (string, error)
+
+// RenderAlertmanagerConfig returns the Alertmanager configuration file for
+// the given structured configuration. The credentials are read with the
+// resolver, the platform Watchdog route is always evaluated first and the
+// resulting configuration is validated with the Alertmanager configuration
+// parser.
+func RenderAlertmanagerConfig(c *AlertmanagerConfiguration, resolve SecretResolver) (string, error) {
+	// The Watchdog route is nested in the root route, without it the
+	// Watchdog alert wouldn't reach its receiver.
+	if c.Route == nil {
+		return "", errors.New("invalid Alertmanager configuration: the root route is required")
+	}
+
+	// Work on a deep copy to not leak the credentials into the
+	// configuration.
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "copying Alertmanager configuration failed")
+	}
+	cfg := &AlertmanagerConfiguration{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return "", errors.Wrap(err, "copying Alertmanager configuration failed")
+	}
+
+	for _, ref := range cfg.secretRefs() {
+		if ref.selector == nil {
+			continue
+		}
+		if ref.selector.Name == "" || ref.selector.Key == "" {
+			return "", errors.New("invalid Alertmanager configuration: secret references require a name and a key")
+		}
+		*ref.value, err = resolve(ref.selector)
+		if err != nil {
+			return "", errors.Wrapf(err, "reading secret %q failed", ref.selector.Name)
+		}
+	}
+
+	b, err = yaml.Marshal(withWatchdogRoute(cfg))
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling Alertmanager configuration failed")
+	}
+
+	if _, err := amconfig.Load(string(b)); err != nil {
+		return "", errors.Wrap(err, "invalid Alertmanager configuration")
+	}
+
+	return string(b), nil
+}
+
+// AlertmanagerConfigSecrets returns the names of the Secrets referenced by
+// the structured Alertmanager configuration.
+func AlertmanagerConfigSecrets(c *AlertmanagerConfiguration) []string {
+	if c == nil {
+		return nil
+	}
+
+	var (
+		res  []string
+		seen = map[string]bool{}
+	)
+	for _, ref := range c.secretRefs() {
+		if ref.selector == nil || seen[ref.selector.Name] {
+			continue
+		}
+		seen[ref.selector.Name] = true
+		res = append(res, ref.selector.Name)
+	}
+	return res
+}
+
+// secretRef associates a Secret reference with the field receiving its
+// value.
+type secretRef struct {
+	selector *v1.SecretKeySelector
+	value    *string
+}
+
+func (c *AlertmanagerConfiguration) secretRefs() []secretRef {
+	var res []secretRef
+	if c.Global != nil {
+		res = append(res,
+			secretRef{c.Global.SMTPAuthPassword, &c.Global.SMTPAuthPasswordValue},
+			secretRef{c.Global.SlackAPIURL, &c.Global.SlackAPIURLValue},
+		)
+	}
+	for i := range c.Receivers {
+		r := &c.Receivers[i]
+		for j := range r.EmailConfigs {
+			ec := &r.EmailConfigs[j]
+			res = append(res, secretRef{ec.AuthPassword, &ec.AuthPasswordValue})
+		}
+		for j := range r.PagerdutyConfigs {
+			pc := &r.PagerdutyConfigs[j]
+			res = append(res,
+				secretRef{pc.RoutingKey, &pc.RoutingKeyValue},
+				secretRef{pc.ServiceKey, &pc.ServiceKeyValue},
+			)
+		}
+		for j := range r.SlackConfigs {
+			sc := &r.SlackConfigs[j]
+			res = append(res, secretRef{sc.APIURL, &sc.APIURLValue})
+		}
+	}
+	return res
+}
+
+// withWatchdogRoute returns a copy of the configuration where the Watchdog
+// alert is routed to the Watchdog receiver before any other route. The
+// Watchdog receiver can be customized, it is created empty otherwise. The
+// configuration must have a root route.
+func withWatchdogRoute(c *AlertmanagerConfiguration) *AlertmanagerConfiguration {
+	cfg := *c
+
+	route := *cfg.Route
+	route.Routes = []AlertmanagerRouteConfig{{
+		Receiver: watchdogReceiver,
+		Match:    map[string]string{"alertname": watchdogAlertName},
+	}}
+	for _, r := range c.Route.Routes {
+		if len(r.Match) == 1 && r.Match["alertname"] == watchdogAlertName {
+			// Superseded by the platform route.
+			continue
+		}
+		route.Routes = append(route.Routes, r)
+	}
+	cfg.Route = &route
+
+	for _, r := range cfg.Receivers {
+		if r.Name == watchdogReceiver {
+			return &cfg
+		}
+	}
+	cfg.Receivers = append(append([]AlertmanagerReceiver{}, c.Receivers...), AlertmanagerReceiver{Name: watchdogReceiver})
+
+	return &cfg
+}
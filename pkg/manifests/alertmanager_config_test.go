@@ -0,0 +1,268 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	amconfig "github.com/prometheus/alertmanager/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestAlertmanagerStructuredConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		err    string
+		check  func(*testing.T, *amconfig.Config)
+	}{
+		{
+			name: "watchdog route added",
+			config: `alertmanagerMain:
+  config:
+    global:
+      resolveTimeout: 10m
+    route:
+      receiver: default
+      groupBy: [namespace]
+      routes:
+      - receiver: pager
+        match:
+          severity: critical
+    receivers:
+    - name: default
+    - name: pager
+      pagerdutyConfigs:
+      - routingKey:
+          name: alertmanager-credentials
+          key: pagerduty
+`,
+			check: func(t *testing.T, c *amconfig.Config) {
+				if len(c.Route.Routes) != 2 {
+					t.Fatalf("expected 2 routes, got %d", len(c.Route.Routes))
+				}
+				if c.Route.Routes[0].Receiver != "Watchdog" || c.Route.Routes[0].Match["alertname"] != "Watchdog" {
+					t.Fatalf("expected the Watchdog route first, got %+v", c.Route.Routes[0])
+				}
+				if c.Route.Routes[1].Receiver != "pager" {
+					t.Fatalf("expected the pager route second, got %+v", c.Route.Routes[1])
+				}
+				if c.Global.ResolveTimeout.String() != "10m" {
+					t.Fatalf("expected resolve timeout 10m, got %s", c.Global.ResolveTimeout)
+				}
+				if string(c.Receivers[1].PagerdutyConfigs[0].RoutingKey) != "pagerduty-key" {
+					t.Fatal("expected the PagerDuty routing key to be read from the secret")
+				}
+				if c.Receivers[2].Name != "Watchdog" {
+					t.Fatalf("expected an empty Watchdog receiver, got %+v", c.Receivers)
+				}
+			},
+		},
+		{
+			name: "custom watchdog receiver",
+			config: `alertmanagerMain:
+  config:
+    route:
+      receiver: default
+      routes:
+      - receiver: snitch
+        match:
+          alertname: Watchdog
+    receivers:
+    - name: default
+    - name: Watchdog
+      webhookConfigs:
+      - url: https://example.com/snitch
+`,
+			check: func(t *testing.T, c *amconfig.Config) {
+				if len(c.Route.Routes) != 1 || c.Route.Routes[0].Receiver != "Watchdog" {
+					t.Fatalf("expected only the platform Watchdog route, got %+v", c.Route.Routes)
+				}
+				if len(c.Receivers) != 2 || len(c.Receivers[1].WebhookConfigs) != 1 {
+					t.Fatalf("expected the custom Watchdog receiver to be preserved, got %+v", c.Receivers)
+				}
+			},
+		},
+		{
+			name: "credentials from secrets",
+			config: `alertmanagerMain:
+  config:
+    global:
+      smtpAuthPassword:
+        name: alertmanager-credentials
+        key: smtp
+      slackAPIURL:
+        name: alertmanager-credentials
+        key: slack
+    route:
+      receiver: default
+    receivers:
+    - name: default
+      emailConfigs:
+      - to: admin@example.com
+        from: alertmanager@example.com
+        smarthost: smtp.example.com:587
+        authUsername: alertmanager
+        authPassword:
+          name: alertmanager-credentials
+          key: smtp
+      slackConfigs:
+      - channel: '#alerts'
+        apiURL:
+          name: alertmanager-credentials
+          key: slack
+`,
+			check: func(t *testing.T, c *amconfig.Config) {
+				if string(c.Global.SMTPAuthPassword) != "smtp-password" {
+					t.Fatal("expected the global SMTP password to be read from the secret")
+				}
+				if c.Global.SlackAPIURL.String() != "https://slack.example.com/hook" {
+					t.Fatalf("expected the global Slack URL to be read from the secret, got %s", c.Global.SlackAPIURL)
+				}
+				if string(c.Receivers[0].EmailConfigs[0].AuthPassword) != "smtp-password" {
+					t.Fatal("expected the email password to be read from the secret")
+				}
+				if c.Receivers[0].SlackConfigs[0].APIURL.String() != "https://slack.example.com/hook" {
+					t.Fatalf("expected the Slack URL to be read from the secret, got %s", c.Receivers[0].SlackConfigs[0].APIURL)
+				}
+			},
+		},
+		{
+			name: "missing secret key",
+			config: `alertmanagerMain:
+  config:
+    route:
+      receiver: default
+    receivers:
+    - name: default
+      pagerdutyConfigs:
+      - routingKey:
+          name: alertmanager-credentials
+          key: missing
+`,
+			err: `key "missing" not found`,
+		},
+		{
+			name: "undefined receiver",
+			config: `alertmanagerMain:
+  config:
+    route:
+      receiver: missing
+`,
+			err: `undefined receiver "missing"`,
+		},
+		{
+			name: "missing route",
+			config: `alertmanagerMain:
+  config:
+    receivers:
+    - name: default
+`,
+			err: "the root route is required",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+			s, err := f.AlertmanagerConfig(resolveTestSecret)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(s.StringData) != 0 {
+				t.Fatal("expected the default configuration to be replaced")
+			}
+			amc, err := amconfig.Load(string(s.Data[AlertmanagerConfigKey]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, amc)
+
+			// The credentials must not be copied into the configuration.
+			if cfg := c.ClusterMonitoringConfiguration.AlertmanagerMainConfig.Config; cfg.Global != nil && cfg.Global.SMTPAuthPasswordValue != "" {
+				t.Fatal("expected the configuration to be left untouched")
+			}
+			for _, name := range AlertmanagerConfigSecrets(c.ClusterMonitoringConfiguration.AlertmanagerMainConfig.Config) {
+				if name != "alertmanager-credentials" {
+					t.Fatalf("unexpected secret %q", name)
+				}
+			}
+		})
+	}
+}
+
+func TestAlertmanagerConfigSecretRotation(t *testing.T) {
+	c, err := NewConfigFromString(`alertmanagerMain:
+  config:
+    route:
+      receiver: default
+    receivers:
+    - name: default
+      pagerdutyConfigs:
+      - routingKey:
+          name: alertmanager-credentials
+          key: pagerduty
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+
+	routingKey := func(value string) string {
+		t.Helper()
+
+		s, err := f.AlertmanagerConfig(func(*v1.SecretKeySelector) (string, error) { return value, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		amc, err := amconfig.Load(string(s.Data[AlertmanagerConfigKey]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(amc.Receivers[0].PagerdutyConfigs[0].RoutingKey)
+	}
+
+	if got := routingKey("old-key"); got != "old-key" {
+		t.Fatalf("expected routing key %q, got %q", "old-key", got)
+	}
+	if got := routingKey("new-key"); got != "new-key" {
+		t.Fatalf("expected the rotated routing key %q, got %q", "new-key", got)
+	}
+}
+
+func resolveTestSecret(sel *v1.SecretKeySelector) (string, error) {
+	data := map[string]string{
+		"pagerduty": "pagerduty-key",
+		"smtp":      "smtp-password",
+		"slack":     "https://slack.example.com/hook",
+	}
+	v, found := data[sel.Key]
+	if sel.Name != "alertmanager-credentials" || !found {
+		return "", errors.Errorf("key %q not found in secret %q", sel.Key, sel.Name)
+	}
+	return v, nil
+}
@@ -0,0 +1,301 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	ClusterMonitoringGroup    = "monitoring.openshift.io"
+	ClusterMonitoringVersion  = "v1alpha1"
+	ClusterMonitoringKind     = "ClusterMonitoring"
+	ClusterMonitoringResource = "clustermonitorings"
+	// ClusterMonitoringName is the name of the singleton ClusterMonitoring
+	// resource read by the operator.
+	ClusterMonitoringName = "cluster"
+
+	// ConvertedFromConfigMapAnnotation marks a ClusterMonitoring resource
+	// which mirrors the legacy Cluster Monitoring ConfigMap. As long as it is
+	// set, the ConfigMap remains the source of truth and the operator keeps
+	// the resource in sync. Removing the annotation makes the resource the
+	// source of truth.
+	ConvertedFromConfigMapAnnotation = "monitoring.openshift.io/converted-from-configmap"
+)
+
+// ClusterMonitoringGVR is the GroupVersionResource of the ClusterMonitoring
+// custom resource.
+var ClusterMonitoringGVR = schema.GroupVersionResource{
+	Group:    ClusterMonitoringGroup,
+	Version:  ClusterMonitoringVersion,
+	Resource: ClusterMonitoringResource,
+}
+
+// NewConfigFromClusterMonitoring returns the configuration defined by the
+// spec of a ClusterMonitoring resource.
+func NewConfigFromClusterMonitoring(cm *unstructured.Unstructured) (*Config, error) {
+	spec, _, err := unstructured.NestedMap(cm.Object, "spec")
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the ClusterMonitoring spec failed")
+	}
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling the ClusterMonitoring spec failed")
+	}
+
+	return NewConfig(bytes.NewReader(b))
+}
+
+// NewClusterMonitoringFromConfigMap converts the content of the legacy
+// Cluster Monitoring ConfigMap into a ClusterMonitoring resource.
+func NewClusterMonitoringFromConfigMap(content string) (*unstructured.Unstructured, error) {
+	spec := map[string]interface{}{}
+	if err := k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(content), 4096).Decode(&spec); err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "parsing the Cluster Monitoring ConfigMap failed")
+	}
+
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion(ClusterMonitoringGroup + "/" + ClusterMonitoringVersion)
+	cm.SetKind(ClusterMonitoringKind)
+	cm.SetName(ClusterMonitoringName)
+	cm.SetAnnotations(map[string]string{ConvertedFromConfigMapAnnotation: "true"})
+	cm.Object["spec"] = spec
+
+	return cm, nil
+}
+
+// IsConvertedFromConfigMap returns true if the ClusterMonitoring resource
+// mirrors the legacy Cluster Monitoring ConfigMap.
+func IsConvertedFromConfigMap(cm *unstructured.Unstructured) bool {
+	return cm.GetAnnotations()[ConvertedFromConfigMapAnnotation] == "true"
+}
+
+// ClusterMonitoringCRD returns the CustomResourceDefinition of the
+// ClusterMonitoring resource. The schema of the spec is generated from the
+// ClusterMonitoringConfiguration type.
+func ClusterMonitoringCRD() *apiextensionsv1.CustomResourceDefinition {
+	spec := openAPISchema(reflect.TypeOf(ClusterMonitoringConfiguration{}), map[reflect.Type]bool{})
+	spec.Description = "Spec holds the configuration of the platform monitoring stack."
+
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apiextensions.k8s.io/v1",
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ClusterMonitoringResource + "." + ClusterMonitoringGroup,
+			Annotations: map[string]string{
+				"include.release.openshift.io/ibm-cloud-managed":              "true",
+				"include.release.openshift.io/self-managed-high-availability": "true",
+				"include.release.openshift.io/single-node-developer":          "true",
+			},
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: ClusterMonitoringGroup,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:     ClusterMonitoringKind,
+				ListKind: ClusterMonitoringKind + "List",
+				Plural:   ClusterMonitoringResource,
+				Singular: strings.ToLower(ClusterMonitoringKind),
+			},
+			Scope: apiextensionsv1.ClusterScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    ClusterMonitoringVersion,
+					Served:  true,
+					Storage: true,
+					Subresources: &apiextensionsv1.CustomResourceSubresources{
+						Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+					},
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Description: "ClusterMonitoring configures the platform monitoring stack. Only the resource named \"cluster\" is considered.",
+							Type:        "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"apiVersion": {Type: "string"},
+								"kind":       {Type: "string"},
+								"metadata":   {Type: "object"},
+								"spec":       spec,
+								"status":     clusterMonitoringStatusSchema(),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func clusterMonitoringStatusSchema() apiextensionsv1.JSONSchemaProps {
+	return apiextensionsv1.JSONSchemaProps{
+		Description: "Status reports the state of the platform monitoring stack.",
+		Type:        "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"observedGeneration": {
+				Description: "ObservedGeneration is the generation of the spec last reconciled by the operator.",
+				Type:        "integer",
+				Format:      "int64",
+			},
+			"conditions": {
+				Description: "Conditions report the state of each component of the monitoring stack.",
+				Type:        "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1.JSONSchemaProps{
+						Type:     "object",
+						Required: []string{"type", "status", "lastTransitionTime", "reason", "message"},
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"type":               {Type: "string"},
+							"status":             {Type: "string", Enum: []apiextensionsv1.JSON{{Raw: []byte(`"True"`)}, {Raw: []byte(`"False"`)}, {Raw: []byte(`"Unknown"`)}}},
+							"observedGeneration": {Type: "integer", Format: "int64"},
+							"lastTransitionTime": {Type: "string", Format: "date-time"},
+							"reason":             {Type: "string"},
+							"message":            {Type: "string"},
+						},
+					},
+				},
+				XListMapKeys: []string{"type"},
+				XListType:    stringPtr("map"),
+			},
+		},
+	}
+}
+
+var (
+	quantityType    = reflect.TypeOf(resource.Quantity{})
+	intOrStringType = reflect.TypeOf(intstr.IntOrString{})
+	timeType        = reflect.TypeOf(metav1.Time{})
+)
+
+// openAPISchema generates a structural OpenAPI v3 schema from a Go type
+// following the encoding/json conventions. Structural schemas can't be
+// recursive so nested occurrences of a struct being visited accept any
+// field.
+func openAPISchema(t reflect.Type, visiting map[reflect.Type]bool) apiextensionsv1.JSONSchemaProps {
+	switch t {
+	case quantityType, intOrStringType:
+		return apiextensionsv1.JSONSchemaProps{XIntOrString: true, AnyOf: []apiextensionsv1.JSONSchemaProps{{Type: "integer"}, {Type: "string"}}}
+	case timeType:
+		return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := openAPISchema(t.Elem(), visiting)
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return apiextensionsv1.JSONSchemaProps{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return apiextensionsv1.JSONSchemaProps{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return apiextensionsv1.JSONSchemaProps{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return apiextensionsv1.JSONSchemaProps{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return apiextensionsv1.JSONSchemaProps{Type: "number"}
+	case reflect.String:
+		return apiextensionsv1.JSONSchemaProps{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "byte"}
+		}
+		items := openAPISchema(t.Elem(), visiting)
+		return apiextensionsv1.JSONSchemaProps{
+			Type:     "array",
+			Nullable: true,
+			Items:    &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &items},
+		}
+	case reflect.Map:
+		values := openAPISchema(t.Elem(), visiting)
+		return apiextensionsv1.JSONSchemaProps{
+			Type:                 "object",
+			Nullable:             true,
+			AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true, Schema: &values},
+		}
+	case reflect.Struct:
+		if visiting[t] {
+			return apiextensionsv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: boolPtr(true)}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := apiextensionsv1.JSONSchemaProps{
+			Type:       "object",
+			Properties: map[string]apiextensionsv1.JSONSchemaProps{},
+		}
+		addStructProperties(&s, t, visiting)
+		return s
+	}
+
+	// Interfaces and other dynamic types accept any value.
+	return apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: boolPtr(true)}
+}
+
+func addStructProperties(s *apiextensionsv1.JSONSchemaProps, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addStructProperties(s, ft, visiting)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = openAPISchema(f.Type, visiting)
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const clusterMonitoringCRDPath = "../../manifests/0000_50_cluster-monitoring-operator_00_0clustermonitoring-custom-resource-definition.yaml"
+
+func TestClusterMonitoringCRDUpToDate(t *testing.T) {
+	expected, err := ioutil.ReadFile(clusterMonitoringCRDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := yaml.Marshal(ClusterMonitoringCRD())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != string(expected) {
+		t.Fatalf("%s is out of date, run 'make generate'", clusterMonitoringCRDPath)
+	}
+}
+
+func TestClusterMonitoringCRDSchema(t *testing.T) {
+	crd := ClusterMonitoringCRD()
+	spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+
+	typ := reflect.TypeOf(ClusterMonitoringConfiguration{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Tag.Get("json")
+		_, found := spec.Properties[name]
+		if name == "-" {
+			if found {
+				t.Errorf("field %s shouldn't be part of the schema", typ.Field(i).Name)
+			}
+			continue
+		}
+		if !found {
+			t.Errorf("expected field %q in the schema", name)
+		}
+	}
+
+	prom := spec.Properties["prometheusK8s"]
+	if !prom.Nullable || prom.Type != "object" {
+		t.Errorf("expected prometheusK8s to be a nullable object, got %+v", prom)
+	}
+	if typ := prom.Properties["retention"].Type; typ != "string" {
+		t.Errorf("expected prometheusK8s.retention to be a string, got %q", typ)
+	}
+	limits := prom.Properties["resources"].Properties["limits"].AdditionalProperties.Schema
+	if !limits.XIntOrString {
+		t.Errorf("expected resource quantities to be int-or-string, got %+v", limits)
+	}
+	if typ := spec.Properties["enableUserWorkload"].Type; typ != "boolean" {
+		t.Errorf("expected enableUserWorkload to be a boolean, got %q", typ)
+	}
+}
+
+func TestClusterMonitoringConversion(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		check   func(*testing.T, *Config)
+	}{
+		{
+			name:    "empty ConfigMap",
+			content: "",
+			check: func(t *testing.T, c *Config) {
+				if *c.ClusterMonitoringConfiguration.UserWorkloadEnabled {
+					t.Error("expected user workload monitoring to be disabled")
+				}
+			},
+		},
+		{
+			name: "populated ConfigMap",
+			content: `enableUserWorkload: true
+prometheusK8s:
+  retention: 24h
+  externalLabels:
+    region: eu
+`,
+			check: func(t *testing.T, c *Config) {
+				if !*c.ClusterMonitoringConfiguration.UserWorkloadEnabled {
+					t.Error("expected user workload monitoring to be enabled")
+				}
+				if got := c.ClusterMonitoringConfiguration.PrometheusK8sConfig.Retention; got != "24h" {
+					t.Errorf("expected retention 24h, got %q", got)
+				}
+				if got := c.ClusterMonitoringConfiguration.PrometheusK8sConfig.ExternalLabels["region"]; got != "eu" {
+					t.Errorf("expected external label region=eu, got %q", got)
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cm, err := NewClusterMonitoringFromConfigMap(tc.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !IsConvertedFromConfigMap(cm) {
+				t.Fatal("expected the resource to be marked as converted")
+			}
+			if cm.GetName() != ClusterMonitoringName {
+				t.Fatalf("expected name %q, got %q", ClusterMonitoringName, cm.GetName())
+			}
+
+			c, err := NewConfigFromClusterMonitoring(cm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, c)
+		})
+	}
+}
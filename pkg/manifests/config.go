@@ -19,15 +19,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-monitoring-operator/pkg/promqlgen"
+	"github.com/pkg/errors"
 	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 )
 
 const (
 	DefaultRetentionValue = "15d"
+
+	// minThanosObjectStorageRetention is the minimum local retention when
+	// the Thanos sidecar uploads the blocks. It keeps the blocks on the
+	// volume while the object storage is unavailable for a few hours.
+	minThanosObjectStorageRetention = model.Duration(24 * time.Hour)
 )
 
 type Config struct {
@@ -51,6 +61,94 @@ type ClusterMonitoringConfiguration struct {
 	K8sPrometheusAdapter     *K8sPrometheusAdapter        `json:"k8sPrometheusAdapter"`
 	ThanosQuerierConfig      *ThanosQuerierConfig         `json:"thanosQuerier"`
 	UserWorkloadEnabled      *bool                        `json:"enableUserWorkload"`
+	UserWorkloadConfig       *UserWorkloadConfig          `json:"userWorkload"`
+	AlertOverrides           []AlertOverride              `json:"alertOverrides"`
+}
+
+// UserWorkloadConfig holds the user workload monitoring settings which are
+// managed by the cluster administrators.
+type UserWorkloadConfig struct {
+	// NamespaceSelector selects the namespaces monitored by the user workload
+	// stack. The namespaces labeled with
+	// "openshift.io/user-monitoring=false" are always excluded.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector"`
+	// SampleLimit is the maximum enforced sample limit of the user workload
+	// Prometheus. It applies when the user workload configuration doesn't
+	// set a lower limit, 0 means no limit.
+	SampleLimit *uint64 `json:"sampleLimit"`
+	// TargetLimit is the maximum enforced target limit of the user workload
+	// Prometheus. It applies when the user workload configuration doesn't
+	// set a lower limit, 0 means no limit.
+	TargetLimit *uint64 `json:"targetLimit"`
+	// MaxRetention is the maximum retention of the user workload Prometheus.
+	MaxRetention string `json:"maxRetention"`
+	// LabelLimit, LabelNameLengthLimit, LabelValueLengthLimit and
+	// BodySizeLimit aren't supported by the Prometheus operator of this
+	// release, the configuration is rejected when they are set.
+	LabelLimit            *uint64 `json:"labelLimit"`
+	LabelNameLengthLimit  *uint64 `json:"labelNameLengthLimit"`
+	LabelValueLengthLimit *uint64 `json:"labelValueLengthLimit"`
+	BodySizeLimit         string  `json:"bodySizeLimit"`
+	// NamespaceQuota limits the samples scraped from each user namespace.
+	NamespaceQuota *UserWorkloadNamespaceQuota `json:"namespaceQuota"`
+}
+
+// UserWorkloadNamespaceQuota limits the samples scraped from each user
+// namespace. It is enforced by the user workload Prometheus which bounds
+// every ServiceMonitor and PodMonitor endpoint to the quota. The quota is
+// disabled when both values are 0.
+type UserWorkloadNamespaceQuota struct {
+	// MaxSamples is the maximum number of samples scraped from the
+	// namespace per scrape interval.
+	MaxSamples uint64 `json:"maxSamples"`
+	// MaxTargets is the maximum number of targets scraped in the namespace.
+	MaxTargets uint64 `json:"maxTargets"`
+}
+
+// Enabled returns true if the quota applies.
+func (q *UserWorkloadNamespaceQuota) Enabled() bool {
+	return q.MaxSamples > 0 || q.MaxTargets > 0
+}
+
+// ScrapeLimits returns the target limit of each endpoint and the sample limit
+// of each target which keep an endpoint within the quota. It must only be
+// called when the quota is enabled.
+func (q *UserWorkloadNamespaceQuota) ScrapeLimits() (targetLimit, sampleLimit uint64) {
+	return q.MaxTargets, q.MaxSamples / q.MaxTargets
+}
+
+// validate returns an error if only one value is set or if the targets can't
+// ingest at least one sample each.
+// validateUnsupportedLimits returns an error if a limit which can't be
+// enforced on the user workload Prometheus is set.
+func (u *UserWorkloadConfig) validateUnsupportedLimits() error {
+	for _, l := range []struct {
+		name string
+		set  bool
+	}{
+		{"labelLimit", u.LabelLimit != nil},
+		{"labelNameLengthLimit", u.LabelNameLengthLimit != nil},
+		{"labelValueLengthLimit", u.LabelValueLengthLimit != nil},
+		{"bodySizeLimit", u.BodySizeLimit != ""},
+	} {
+		if l.set {
+			return errors.Errorf("%s isn't supported by the user workload Prometheus yet", l.name)
+		}
+	}
+	return nil
+}
+
+func (q *UserWorkloadNamespaceQuota) validate() error {
+	if !q.Enabled() {
+		return nil
+	}
+	if q.MaxSamples == 0 || q.MaxTargets == 0 {
+		return errors.New("maxSamples and maxTargets must be set together")
+	}
+	if q.MaxSamples < q.MaxTargets {
+		return errors.Errorf("maxSamples %d must be greater than or equal to maxTargets %d", q.MaxSamples, q.MaxTargets)
+	}
+	return nil
 }
 
 type Images struct {
@@ -84,33 +182,83 @@ type PrometheusOperatorConfig struct {
 
 type PrometheusK8sConfig struct {
 	LogLevel            string                               `json:"logLevel"`
+	Replicas            *int32                               `json:"replicas"`
 	Retention           string                               `json:"retention"`
+	RetentionSize       string                               `json:"retentionSize"`
 	NodeSelector        map[string]string                    `json:"nodeSelector"`
 	Tolerations         []v1.Toleration                      `json:"tolerations"`
 	Resources           *v1.ResourceRequirements             `json:"resources"`
 	ExternalLabels      map[string]string                    `json:"externalLabels"`
 	VolumeClaimTemplate *monv1.EmbeddedPersistentVolumeClaim `json:"volumeClaimTemplate"`
 	RemoteWrite         []monv1.RemoteWriteSpec              `json:"remoteWrite"`
-	TelemetryMatches    []string                             `json:"-"`
+	AlertmanagerConfigs []AdditionalAlertmanagerConfig       `json:"additionalAlertmanagerConfigs"`
+	// ThanosObjectStorage references the key of a secret in the
+	// openshift-monitoring namespace holding the Thanos object storage
+	// configuration. The Thanos sidecar uploads the blocks when it is set.
+	ThanosObjectStorage *v1.SecretKeySelector `json:"thanosObjectStorage"`
+	TelemetryMatches    []string              `json:"-"`
 }
 
 type AlertmanagerMainConfig struct {
-	NodeSelector        map[string]string                    `json:"nodeSelector"`
-	Tolerations         []v1.Toleration                      `json:"tolerations"`
-	Resources           *v1.ResourceRequirements             `json:"resources"`
-	VolumeClaimTemplate *monv1.EmbeddedPersistentVolumeClaim `json:"volumeClaimTemplate"`
+	NodeSelector                 map[string]string                    `json:"nodeSelector"`
+	Replicas                     *int32                               `json:"replicas"`
+	Tolerations                  []v1.Toleration                      `json:"tolerations"`
+	Resources                    *v1.ResourceRequirements             `json:"resources"`
+	VolumeClaimTemplate          *monv1.EmbeddedPersistentVolumeClaim `json:"volumeClaimTemplate"`
+	Config                       *AlertmanagerConfiguration           `json:"config"`
+	EnableUserAlertmanagerConfig bool                                 `json:"enableUserAlertmanagerConfig"`
 }
 
 type ThanosRulerConfig struct {
 	LogLevel            string                               `json:"logLevel"`
+	Replicas            *int32                               `json:"replicas"`
 	NodeSelector        map[string]string                    `json:"nodeSelector"`
 	Tolerations         []v1.Toleration                      `json:"tolerations"`
 	Resources           *v1.ResourceRequirements             `json:"resources"`
 	VolumeClaimTemplate *monv1.EmbeddedPersistentVolumeClaim `json:"volumeClaimTemplate"`
+	AlertmanagerConfigs []AdditionalAlertmanagerConfig       `json:"additionalAlertmanagerConfigs"`
+}
+
+// AlertOverride customizes the alerting rules shipped with the platform
+// stack. It applies to all the rules defining the alert.
+type AlertOverride struct {
+	Alert    string            `json:"alert"`
+	Disabled bool              `json:"disabled"`
+	For      string            `json:"for"`
+	Severity string            `json:"severity"`
+	Labels   map[string]string `json:"labels"`
+}
+
+// AdditionalAlertmanagerConfig defines an Alertmanager cluster receiving
+// alerts in addition to the in-cluster Alertmanager. The referenced Secrets
+// must exist in the namespace of the component sending the alerts.
+type AdditionalAlertmanagerConfig struct {
+	Scheme          string                 `json:"scheme"`
+	PathPrefix      string                 `json:"pathPrefix"`
+	Timeout         string                 `json:"timeout"`
+	APIVersion      string                 `json:"apiVersion"`
+	TLSConfig       *AlertmanagerTLSConfig `json:"tlsConfig"`
+	BearerToken     *v1.SecretKeySelector  `json:"bearerToken"`
+	BasicAuth       *AlertmanagerBasicAuth `json:"basicAuth"`
+	StaticAddresses []string               `json:"staticConfigs"`
+}
+
+type AlertmanagerTLSConfig struct {
+	CA                 *v1.SecretKeySelector `json:"ca"`
+	Cert               *v1.SecretKeySelector `json:"cert"`
+	Key                *v1.SecretKeySelector `json:"key"`
+	ServerName         string                `json:"serverName"`
+	InsecureSkipVerify bool                  `json:"insecureSkipVerify"`
+}
+
+type AlertmanagerBasicAuth struct {
+	Username string                `json:"username"`
+	Password *v1.SecretKeySelector `json:"password"`
 }
 
 type ThanosQuerierConfig struct {
 	LogLevel     string                   `json:"logLevel"`
+	Replicas     *int32                   `json:"replicas"`
 	NodeSelector map[string]string        `json:"nodeSelector"`
 	Tolerations  []v1.Toleration          `json:"tolerations"`
 	Resources    *v1.ResourceRequirements `json:"resources"`
@@ -118,6 +266,7 @@ type ThanosQuerierConfig struct {
 
 type GrafanaConfig struct {
 	NodeSelector map[string]string `json:"nodeSelector"`
+	Replicas     *int32            `json:"replicas"`
 	Tolerations  []v1.Toleration   `json:"tolerations"`
 }
 
@@ -133,6 +282,7 @@ type OpenShiftStateMetricsConfig struct {
 
 type K8sPrometheusAdapter struct {
 	NodeSelector map[string]string `json:"nodeSelector"`
+	Replicas     *int32            `json:"replicas"`
 	Tolerations  []v1.Toleration   `json:"tolerations"`
 }
 
@@ -184,6 +334,28 @@ func NewConfig(content io.Reader) (*Config, error) {
 	res.applyDefaults()
 	c.UserWorkloadConfiguration = NewDefaultUserWorkloadMonitoringConfig()
 
+	if _, err := metav1.LabelSelectorAsSelector(res.UserWorkloadNamespaceSelector()); err != nil {
+		return nil, errors.Wrap(err, "invalid user workload namespace selector")
+	}
+	if r := res.ClusterMonitoringConfiguration.UserWorkloadConfig.MaxRetention; r != "" {
+		if _, err := model.ParseDuration(r); err != nil {
+			return nil, errors.Wrap(err, "invalid user workload maximum retention")
+		}
+	}
+	if err := res.ClusterMonitoringConfiguration.UserWorkloadConfig.validateUnsupportedLimits(); err != nil {
+		return nil, errors.Wrap(err, "invalid user workload limits")
+	}
+	if err := res.ClusterMonitoringConfiguration.UserWorkloadConfig.NamespaceQuota.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid user workload namespace quota")
+	}
+	p := res.ClusterMonitoringConfiguration.PrometheusK8sConfig
+	if err := validateRetentionSize(p.RetentionSize, p.VolumeClaimTemplate); err != nil {
+		return nil, errors.Wrap(err, "invalid Prometheus configuration")
+	}
+	if err := validateThanosObjectStorage(p); err != nil {
+		return nil, errors.Wrap(err, "invalid Prometheus configuration")
+	}
+
 	return res, nil
 }
 
@@ -210,6 +382,12 @@ func (c *Config) applyDefaults() {
 		disable := false
 		c.ClusterMonitoringConfiguration.UserWorkloadEnabled = &disable
 	}
+	if c.ClusterMonitoringConfiguration.UserWorkloadConfig == nil {
+		c.ClusterMonitoringConfiguration.UserWorkloadConfig = &UserWorkloadConfig{}
+	}
+	if c.ClusterMonitoringConfiguration.UserWorkloadConfig.NamespaceQuota == nil {
+		c.ClusterMonitoringConfiguration.UserWorkloadConfig.NamespaceQuota = &UserWorkloadNamespaceQuota{}
+	}
 	if c.ClusterMonitoringConfiguration.ThanosQuerierConfig == nil {
 		c.ClusterMonitoringConfiguration.ThanosQuerierConfig = &ThanosQuerierConfig{}
 	}
@@ -255,6 +433,45 @@ func (c *Config) SetImages(images map[string]string) {
 	c.Images.Thanos = images["thanos"]
 }
 
+// TelemetryConfig is the content of the telemetry configuration which lists
+// the series sent to the Telemeter server.
+type TelemetryConfig struct {
+	Matches []string `json:"matches"`
+}
+
+// NewTelemetryConfig parses and validates the telemetry configuration.
+func NewTelemetryConfig(content io.Reader) (*TelemetryConfig, error) {
+	tc := TelemetryConfig{}
+	err := k8syaml.NewYAMLOrJSONDecoder(content, 100).Decode(&tc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tc.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &tc, nil
+}
+
+// Validate returns an error if the telemetry matches can't be translated to
+// the relabel configs and the recording rule.
+func (tc *TelemetryConfig) Validate() error {
+	if len(tc.Matches) == 0 {
+		return errors.New("no telemetry match")
+	}
+
+	if _, err := promqlgen.LabelSelectorsToRelabelConfig(tc.Matches); err != nil {
+		return errors.Wrap(err, "invalid telemetry matches")
+	}
+
+	if _, err := promqlgen.GroupLabelSelectors(tc.Matches); err != nil {
+		return errors.Wrap(err, "invalid telemetry matches")
+	}
+
+	return nil
+}
+
 func (c *Config) SetTelemetryMatches(matches []string) {
 	c.ClusterMonitoringConfiguration.PrometheusK8sConfig.TelemetryMatches = matches
 }
@@ -310,6 +527,130 @@ func (c *Config) LoadToken(load func() (*v1.Secret, error)) error {
 	return nil
 }
 
+// UserWorkloadAlertmanagerEnabled returns true if the Alertmanager dedicated
+// to user workload monitoring should be deployed.
+func (c *Config) UserWorkloadAlertmanagerEnabled() bool {
+	return *c.ClusterMonitoringConfiguration.UserWorkloadEnabled && c.UserWorkloadConfiguration.Alertmanager.Enabled
+}
+
+// AlertmanagerMainUserConfigEnabled returns true if the platform Alertmanager
+// should process the AlertmanagerConfig resources of user namespaces. It is
+// only the case when user workload monitoring is enabled without its own
+// Alertmanager.
+func (c *Config) AlertmanagerMainUserConfigEnabled() bool {
+	return *c.ClusterMonitoringConfiguration.UserWorkloadEnabled &&
+		!c.UserWorkloadConfiguration.Alertmanager.Enabled &&
+		c.ClusterMonitoringConfiguration.AlertmanagerMainConfig.EnableUserAlertmanagerConfig
+}
+
+// UserWorkloadAlertmanagerConfigEnabled returns true if the user workload
+// Alertmanager should process the AlertmanagerConfig resources of user
+// namespaces.
+func (c *Config) UserWorkloadAlertmanagerConfigEnabled() bool {
+	return c.UserWorkloadAlertmanagerEnabled() && c.UserWorkloadConfiguration.Alertmanager.EnableAlertmanagerConfig
+}
+
+// UserWorkloadNamespaceSelector returns the label selector of the namespaces
+// monitored by the user workload stack. It combines the selector configured
+// by the cluster administrators with the namespace opt-out label.
+func (c *Config) UserWorkloadNamespaceSelector() *metav1.LabelSelector {
+	selector := &metav1.LabelSelector{}
+	if s := c.ClusterMonitoringConfiguration.UserWorkloadConfig.NamespaceSelector; s != nil {
+		selector = s.DeepCopy()
+	}
+
+	selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      UserWorkloadMonitoringNamespaceLabel,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   []string{"false"},
+	})
+
+	return selector
+}
+
+// AlertmanagerConfigNamespaceSelector returns the label selector of the
+// namespaces whose AlertmanagerConfig resources are processed: the namespaces
+// monitored by the user workload stack, except the platform namespaces.
+func (c *Config) AlertmanagerConfigNamespaceSelector() *metav1.LabelSelector {
+	selector := c.UserWorkloadNamespaceSelector()
+	selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      ClusterMonitoringNamespaceLabel,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   []string{"true"},
+	})
+	return selector
+}
+
+// ValidateUserWorkloadLimits returns an error if the user workload
+// configuration exceeds the limits set in the cluster monitoring
+// configuration.
+func (c *Config) ValidateUserWorkloadLimits() error {
+	limits := c.ClusterMonitoringConfiguration.UserWorkloadConfig
+	p := c.UserWorkloadConfiguration.Prometheus
+
+	if exceedsLimit(p.EnforcedSampleLimit, limits.SampleLimit) {
+		return errors.Errorf("enforcedSampleLimit %d exceeds the limit of %d set by the cluster administrators", *p.EnforcedSampleLimit, *limits.SampleLimit)
+	}
+	if exceedsLimit(p.EnforcedTargetLimit, limits.TargetLimit) {
+		return errors.Errorf("enforcedTargetLimit %d exceeds the limit of %d set by the cluster administrators", *p.EnforcedTargetLimit, *limits.TargetLimit)
+	}
+
+	if limits.MaxRetention != "" && p.Retention != "" {
+		maxRetention, err := model.ParseDuration(limits.MaxRetention)
+		if err != nil {
+			return errors.Wrap(err, "invalid maximum retention")
+		}
+		retention, err := model.ParseDuration(p.Retention)
+		if err != nil {
+			return errors.Wrap(err, "invalid retention")
+		}
+		if retention > maxRetention {
+			return errors.Errorf("retention %s exceeds the maximum retention of %s set by the cluster administrators", p.Retention, limits.MaxRetention)
+		}
+	}
+
+	return nil
+}
+
+// lowestLimit returns the lowest of the limits, 0 meaning no limit.
+func lowestLimit(limit *uint64, other uint64) *uint64 {
+	if limit != nil && *limit != 0 && *limit <= other {
+		return limit
+	}
+	return &other
+}
+
+// exceedsLimit returns true if the value is greater than the limit. For both,
+// 0 means no limit.
+func exceedsLimit(value, limit *uint64) bool {
+	if value == nil || limit == nil || *limit == 0 {
+		return false
+	}
+	return *value == 0 || *value > *limit
+}
+
+// validateThanosObjectStorage returns an error if the object storage secret
+// reference is incomplete or if the local retention is too short to retry
+// the uploads of the blocks while the object storage is unavailable.
+func validateThanosObjectStorage(p *PrometheusK8sConfig) error {
+	sel := p.ThanosObjectStorage
+	if sel == nil {
+		return nil
+	}
+	if sel.Name == "" || sel.Key == "" {
+		return errors.New("thanosObjectStorage requires the name and the key of a secret")
+	}
+
+	retention, err := model.ParseDuration(p.Retention)
+	if err != nil {
+		return errors.Wrap(err, "invalid retention")
+	}
+	if retention < minThanosObjectStorageRetention {
+		return errors.Errorf("retention %s is lower than %s, the minimum with thanosObjectStorage", p.Retention, minThanosObjectStorageRetention)
+	}
+	return nil
+}
+
 // HTTPProxy implements the ProxyReader interface.
 func (c *Config) HTTPProxy() string {
 	return c.ClusterMonitoringConfiguration.HTTPConfig.HTTPProxy
@@ -343,14 +684,32 @@ func NewDefaultConfig() *Config {
 }
 
 type UserWorkloadConfiguration struct {
-	PrometheusOperator *PrometheusOperatorConfig   `json:"prometheusOperator"`
-	Prometheus         *PrometheusRestrictedConfig `json:"prometheus"`
-	ThanosRuler        *ThanosRulerConfig          `json:"thanosRuler"`
+	PrometheusOperator *PrometheusOperatorConfig       `json:"prometheusOperator"`
+	Prometheus         *PrometheusRestrictedConfig     `json:"prometheus"`
+	ThanosRuler        *ThanosRulerConfig              `json:"thanosRuler"`
+	Alertmanager       *AlertmanagerUserWorkloadConfig `json:"alertmanager"`
+}
+
+// AlertmanagerUserWorkloadConfig configures the optional Alertmanager
+// dedicated to user workload monitoring. When enabled, the user workload
+// Prometheus and Thanos Ruler send alerts to it instead of the platform
+// Alertmanager.
+type AlertmanagerUserWorkloadConfig struct {
+	Enabled                  bool                                 `json:"enabled"`
+	EnableAlertmanagerConfig bool                                 `json:"enableAlertmanagerConfig"`
+	LogLevel                 string                               `json:"logLevel"`
+	Replicas                 *int32                               `json:"replicas"`
+	NodeSelector             map[string]string                    `json:"nodeSelector"`
+	Tolerations              []v1.Toleration                      `json:"tolerations"`
+	Resources                *v1.ResourceRequirements             `json:"resources"`
+	VolumeClaimTemplate      *monv1.EmbeddedPersistentVolumeClaim `json:"volumeClaimTemplate"`
 }
 
 type PrometheusRestrictedConfig struct {
 	LogLevel            string                               `json:"logLevel"`
 	Retention           string                               `json:"retention"`
+	RetentionSize       string                               `json:"retentionSize"`
+	Replicas            *int32                               `json:"replicas"`
 	NodeSelector        map[string]string                    `json:"nodeSelector"`
 	Tolerations         []v1.Toleration                      `json:"tolerations"`
 	Resources           *v1.ResourceRequirements             `json:"resources"`
@@ -358,6 +717,11 @@ type PrometheusRestrictedConfig struct {
 	VolumeClaimTemplate *monv1.EmbeddedPersistentVolumeClaim `json:"volumeClaimTemplate"`
 	RemoteWrite         []monv1.RemoteWriteSpec              `json:"remoteWrite"`
 	EnforcedSampleLimit *uint64                              `json:"enforcedSampleLimit"`
+	EnforcedTargetLimit *uint64                              `json:"enforcedTargetLimit"`
+	// Shards is the number of shards splitting the scrape targets, each
+	// shard runs its own Prometheus replicas.
+	Shards              *int32                         `json:"shards"`
+	AlertmanagerConfigs []AdditionalAlertmanagerConfig `json:"additionalAlertmanagerConfigs"`
 }
 
 func (u *UserWorkloadConfiguration) applyDefaults() {
@@ -370,6 +734,9 @@ func (u *UserWorkloadConfiguration) applyDefaults() {
 	if u.ThanosRuler == nil {
 		u.ThanosRuler = &ThanosRulerConfig{}
 	}
+	if u.Alertmanager == nil {
+		u.Alertmanager = &AlertmanagerUserWorkloadConfig{}
+	}
 }
 
 func NewUserConfigFromString(content string) (*UserWorkloadConfiguration, error) {
@@ -384,6 +751,13 @@ func NewUserConfigFromString(content string) (*UserWorkloadConfiguration, error)
 
 	u.applyDefaults()
 
+	if shards := u.Prometheus.Shards; shards != nil && *shards < 1 {
+		return nil, errors.Errorf("invalid number of Prometheus shards %d, it must be at least 1", *shards)
+	}
+	if err := validateRetentionSize(u.Prometheus.RetentionSize, u.Prometheus.VolumeClaimTemplate); err != nil {
+		return nil, errors.Wrap(err, "invalid user workload Prometheus configuration")
+	}
+
 	return u, nil
 }
 
@@ -17,6 +17,8 @@ package manifests
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -170,6 +172,178 @@ func TestTelemeterClientConfig(t *testing.T) {
 	}
 }
 
+func TestNewTelemetryConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		matches []string
+		err     bool
+	}{
+		{
+			name: "valid",
+			content: `matches:
+- '{__name__="metric1"}'
+- '{__name__="ALERTS",alertstate="firing"}'
+`,
+			matches: []string{
+				`{__name__="metric1"}`,
+				`{__name__="ALERTS",alertstate="firing"}`,
+			},
+		},
+		{
+			name:    "empty",
+			content: "",
+			err:     true,
+		},
+		{
+			name:    "no match",
+			content: "matches: []",
+			err:     true,
+		},
+		{
+			name:    "invalid selector",
+			content: `matches: ['{__name__="metric1"']`,
+			err:     true,
+		},
+		{
+			name:    "selector matching empty values",
+			content: `matches: ['{job=""}']`,
+			err:     true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewTelemetryConfig(strings.NewReader(tc.content))
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.matches, c.Matches) {
+				t.Fatalf("expected matches %v, got %v", tc.matches, c.Matches)
+			}
+		})
+	}
+}
+
+func TestUserWorkloadNamespaceQuota(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  string
+		enabled bool
+		err     bool
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "valid quota",
+			config: `userWorkload:
+  namespaceQuota:
+    maxSamples: 10000
+    maxTargets: 10
+`,
+			enabled: true,
+		},
+		{
+			name: "missing maxTargets",
+			config: `userWorkload:
+  namespaceQuota:
+    maxSamples: 10000
+`,
+			err: true,
+		},
+		{
+			name: "fewer samples than targets",
+			config: `userWorkload:
+  namespaceQuota:
+    maxSamples: 5
+    maxTargets: 10
+`,
+			err: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewConfigFromString(tc.config)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if enabled := c.ClusterMonitoringConfiguration.UserWorkloadConfig.NamespaceQuota.Enabled(); enabled != tc.enabled {
+				t.Fatalf("expected enabled %v, got %v", tc.enabled, enabled)
+			}
+		})
+	}
+}
+
+func TestUserWorkloadUnsupportedLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "supported limits",
+			config: `userWorkload:
+  sampleLimit: 5000
+  targetLimit: 50
+  maxRetention: 7d
+`,
+		},
+		{
+			name: "label limit",
+			config: `userWorkload:
+  labelLimit: 30
+`,
+			err: "labelLimit",
+		},
+		{
+			name: "label name length limit",
+			config: `userWorkload:
+  labelNameLengthLimit: 50
+`,
+			err: "labelNameLengthLimit",
+		},
+		{
+			name: "label value length limit",
+			config: `userWorkload:
+  labelValueLengthLimit: 200
+`,
+			err: "labelValueLengthLimit",
+		},
+		{
+			name: "body size limit",
+			config: `userWorkload:
+  bodySizeLimit: 10MB
+`,
+			err: "bodySizeLimit",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewConfigFromString(tc.config)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
 func TestEtcdDefaultsToDisabled(t *testing.T) {
 	c, err := NewConfigFromString("")
 	if err != nil {
@@ -25,18 +25,21 @@ import (
 	"io"
 	"net"
 	"net/url"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	routev1 "github.com/openshift/api/route/v1"
 	securityv1 "github.com/openshift/api/security/v1"
-	"github.com/openshift/cluster-monitoring-operator/pkg/promqlgen"
 	"github.com/pkg/errors"
 	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
 	admissionv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -50,18 +53,19 @@ const (
 )
 
 var (
-	AlertmanagerConfig             = "alertmanager/secret.yaml"
-	AlertmanagerService            = "alertmanager/service.yaml"
-	AlertmanagerProxySecret        = "alertmanager/proxy-secret.yaml"
-	AlertmanagerMain               = "alertmanager/alertmanager.yaml"
-	AlertmanagerServiceAccount     = "alertmanager/service-account.yaml"
-	AlertmanagerClusterRoleBinding = "alertmanager/cluster-role-binding.yaml"
-	AlertmanagerClusterRole        = "alertmanager/cluster-role.yaml"
-	AlertmanagerRBACProxySecret    = "alertmanager/kube-rbac-proxy-secret.yaml"
-	AlertmanagerRoute              = "alertmanager/route.yaml"
-	AlertmanagerServiceMonitor     = "alertmanager/service-monitor.yaml"
-	AlertmanagerTrustedCABundle    = "alertmanager/trusted-ca-bundle.yaml"
-	AlertmanagerPrometheusRule     = "alertmanager/prometheus-rule.yaml"
+	AlertmanagerConfig              = "alertmanager/secret.yaml"
+	AlertmanagerService             = "alertmanager/service.yaml"
+	AlertmanagerProxySecret         = "alertmanager/proxy-secret.yaml"
+	AlertmanagerMain                = "alertmanager/alertmanager.yaml"
+	AlertmanagerServiceAccount      = "alertmanager/service-account.yaml"
+	AlertmanagerClusterRoleBinding  = "alertmanager/cluster-role-binding.yaml"
+	AlertmanagerClusterRole         = "alertmanager/cluster-role.yaml"
+	AlertmanagerRBACProxySecret     = "alertmanager/kube-rbac-proxy-secret.yaml"
+	AlertmanagerRoute               = "alertmanager/route.yaml"
+	AlertmanagerServiceMonitor      = "alertmanager/service-monitor.yaml"
+	AlertmanagerTrustedCABundle     = "alertmanager/trusted-ca-bundle.yaml"
+	AlertmanagerPrometheusRule      = "alertmanager/prometheus-rule.yaml"
+	AlertmanagerPodDisruptionBudget = "alertmanager/pod-disruption-budget.yaml"
 
 	KubeStateMetricsClusterRoleBinding = "kube-state-metrics/cluster-role-binding.yaml"
 	KubeStateMetricsClusterRole        = "kube-state-metrics/cluster-role.yaml"
@@ -108,6 +112,7 @@ var (
 	PrometheusK8sGrpcTLSSecret               = "prometheus-k8s/grpc-tls-secret.yaml"
 	PrometheusK8sTrustedCABundle             = "prometheus-k8s/trusted-ca-bundle.yaml"
 	PrometheusK8sThanosSidecarServiceMonitor = "prometheus-k8s/service-monitor-thanos-sidecar.yaml"
+	PrometheusK8sPodDisruptionBudget         = "prometheus-k8s/pod-disruption-budget.yaml"
 
 	PrometheusUserWorkloadServingCertsCABundle        = "prometheus-user-workload/serving-certs-ca-bundle.yaml"
 	PrometheusUserWorkloadServiceAccount              = "prometheus-user-workload/service-account.yaml"
@@ -123,6 +128,7 @@ var (
 	PrometheusUserWorkloadPrometheusServiceMonitor    = "prometheus-user-workload/service-monitor.yaml"
 	PrometheusUserWorkloadGrpcTLSSecret               = "prometheus-user-workload/grpc-tls-secret.yaml"
 	PrometheusUserWorkloadThanosSidecarServiceMonitor = "prometheus-user-workload/service-monitor-thanos-sidecar.yaml"
+	PrometheusUserWorkloadPodDisruptionBudget         = "prometheus-user-workload/pod-disruption-budget.yaml"
 
 	PrometheusAdapterAPIService                         = "prometheus-adapter/api-service.yaml"
 	PrometheusAdapterClusterRole                        = "prometheus-adapter/cluster-role.yaml"
@@ -138,6 +144,7 @@ var (
 	PrometheusAdapterService                            = "prometheus-adapter/service.yaml"
 	PrometheusAdapterServiceMonitor                     = "prometheus-adapter/service-monitor.yaml"
 	PrometheusAdapterServiceAccount                     = "prometheus-adapter/service-account.yaml"
+	PrometheusAdapterPodDisruptionBudget                = "prometheus-adapter/pod-disruption-budget.yaml"
 
 	PrometheusOperatorClusterRoleBinding    = "prometheus-operator/cluster-role-binding.yaml"
 	PrometheusOperatorClusterRole           = "prometheus-operator/cluster-role.yaml"
@@ -169,16 +176,18 @@ var (
 	GrafanaService              = "grafana/service.yaml"
 	GrafanaServiceMonitor       = "grafana/service-monitor.yaml"
 	GrafanaTrustedCABundle      = "grafana/trusted-ca-bundle.yaml"
-
-	ClusterMonitoringOperatorService            = "cluster-monitoring-operator/service.yaml"
-	ClusterMonitoringOperatorServiceMonitor     = "cluster-monitoring-operator/service-monitor.yaml"
-	ClusterMonitoringClusterRole                = "cluster-monitoring-operator/cluster-role.yaml"
-	ClusterMonitoringRulesEditClusterRole       = "cluster-monitoring-operator/monitoring-rules-edit-cluster-role.yaml"
-	ClusterMonitoringRulesViewClusterRole       = "cluster-monitoring-operator/monitoring-rules-view-cluster-role.yaml"
-	ClusterMonitoringEditClusterRole            = "cluster-monitoring-operator/monitoring-edit-cluster-role.yaml"
-	ClusterMonitoringEditUserWorkloadConfigRole = "cluster-monitoring-operator/user-workload-config-edit-role.yaml"
-	ClusterMonitoringGrpcTLSSecret              = "cluster-monitoring-operator/grpc-tls-secret.yaml"
-	ClusterMonitoringOperatorPrometheusRule     = "cluster-monitoring-operator/prometheus-rule.yaml"
+	GrafanaPodDisruptionBudget  = "grafana/pod-disruption-budget.yaml"
+
+	ClusterMonitoringOperatorService             = "cluster-monitoring-operator/service.yaml"
+	ClusterMonitoringOperatorServiceMonitor      = "cluster-monitoring-operator/service-monitor.yaml"
+	ClusterMonitoringClusterRole                 = "cluster-monitoring-operator/cluster-role.yaml"
+	ClusterMonitoringRulesEditClusterRole        = "cluster-monitoring-operator/monitoring-rules-edit-cluster-role.yaml"
+	ClusterMonitoringAlertRoutingEditClusterRole = "cluster-monitoring-operator/alert-routing-edit-cluster-role.yaml"
+	ClusterMonitoringRulesViewClusterRole        = "cluster-monitoring-operator/monitoring-rules-view-cluster-role.yaml"
+	ClusterMonitoringEditClusterRole             = "cluster-monitoring-operator/monitoring-edit-cluster-role.yaml"
+	ClusterMonitoringEditUserWorkloadConfigRole  = "cluster-monitoring-operator/user-workload-config-edit-role.yaml"
+	ClusterMonitoringGrpcTLSSecret               = "cluster-monitoring-operator/grpc-tls-secret.yaml"
+	ClusterMonitoringOperatorPrometheusRule      = "cluster-monitoring-operator/prometheus-rule.yaml"
 
 	TelemeterClientClusterRole            = "telemeter-client/cluster-role.yaml"
 	TelemeterClientClusterRoleBinding     = "telemeter-client/cluster-role-binding.yaml"
@@ -204,6 +213,7 @@ var (
 	ThanosQuerierClusterRoleBinding   = "thanos-querier/cluster-role-binding.yaml"
 	ThanosQuerierGrpcTLSSecret        = "thanos-querier/grpc-tls-secret.yaml"
 	ThanosQuerierTrustedCABundle      = "thanos-querier/trusted-ca-bundle.yaml"
+	ThanosQuerierPodDisruptionBudget  = "thanos-querier/pod-disruption-budget.yaml"
 
 	ThanosRulerCustomResource               = "thanos-ruler/thanos-ruler.yaml"
 	ThanosRulerService                      = "thanos-ruler/service.yaml"
@@ -220,6 +230,17 @@ var (
 	ThanosRulerTrustedCABundle              = "thanos-ruler/trusted-ca-bundle.yaml"
 	ThanosRulerServiceMonitor               = "thanos-ruler/service-monitor.yaml"
 	ThanosRulerPrometheusRule               = "thanos-ruler/thanos-ruler-prometheus-rule.yaml"
+	ThanosRulerPodDisruptionBudget          = "thanos-ruler/pod-disruption-budget.yaml"
+
+	AlertmanagerUserWorkload                               = "alertmanager-user-workload/alertmanager.yaml"
+	AlertmanagerUserWorkloadSecret                         = "alertmanager-user-workload/secret.yaml"
+	AlertmanagerUserWorkloadService                        = "alertmanager-user-workload/service.yaml"
+	AlertmanagerUserWorkloadServiceAccount                 = "alertmanager-user-workload/service-account.yaml"
+	AlertmanagerUserWorkloadClusterRole                    = "alertmanager-user-workload/cluster-role.yaml"
+	AlertmanagerUserWorkloadClusterRoleBinding             = "alertmanager-user-workload/cluster-role-binding.yaml"
+	AlertmanagerUserWorkloadAlertsSenderClusterRole        = "alertmanager-user-workload/cluster-role-alerts-sender.yaml"
+	AlertmanagerUserWorkloadAlertsSenderClusterRoleBinding = "alertmanager-user-workload/cluster-role-binding-alerts-sender.yaml"
+	AlertmanagerUserWorkloadPodDisruptionBudget            = "alertmanager-user-workload/pod-disruption-budget.yaml"
 
 	TelemeterTrustedCABundle = "telemeter-client/trusted-ca-bundle.yaml"
 
@@ -241,6 +262,20 @@ var (
 	AuthProxyRedirectURLFlag  = "-redirect-url="
 
 	TrustedCABundleKey = "ca-bundle.crt"
+
+	PrometheusK8sAdditionalAlertmanagerConfigsSecretName          = "prometheus-k8s-additional-alertmanager-configs"
+	PrometheusUserWorkloadAdditionalAlertmanagerConfigsSecretName = "prometheus-user-workload-additional-alertmanager-configs"
+
+	// prometheusOperatorDefaultRetention is the retention set by the
+	// Prometheus operator when neither retention nor retentionSize are set.
+	prometheusOperatorDefaultRetention = model.Duration(24 * time.Hour)
+
+	// ClusterMonitoringNamespaceLabel identifies the namespaces monitored by
+	// the platform stack.
+	ClusterMonitoringNamespaceLabel = "openshift.io/cluster-monitoring"
+	// UserWorkloadMonitoringNamespaceLabel opts namespaces out of user
+	// workload monitoring when set to "false".
+	UserWorkloadMonitoringNamespaceLabel = "openshift.io/user-monitoring"
 )
 
 type Factory struct {
@@ -292,7 +327,7 @@ func (f *Factory) AlertmanagerExternalURL(host string) *url.URL {
 	}
 }
 
-func (f *Factory) AlertmanagerConfig() (*v1.Secret, error) {
+func (f *Factory) AlertmanagerConfig(resolve SecretResolver) (*v1.Secret, error) {
 	s, err := f.NewSecret(f.assets.MustNewAssetReader(AlertmanagerConfig))
 	if err != nil {
 		return nil, err
@@ -300,6 +335,15 @@ func (f *Factory) AlertmanagerConfig() (*v1.Secret, error) {
 
 	s.Namespace = f.namespace
 
+	if f.config.ClusterMonitoringConfiguration.AlertmanagerMainConfig.Config != nil {
+		amConfig, err := RenderAlertmanagerConfig(f.config.ClusterMonitoringConfiguration.AlertmanagerMainConfig.Config, resolve)
+		if err != nil {
+			return nil, err
+		}
+		s.StringData = nil
+		s.Data = map[string][]byte{AlertmanagerConfigKey: []byte(amConfig)}
+	}
+
 	return s, nil
 }
 
@@ -404,6 +448,8 @@ func (f *Factory) AlertmanagerMain(host string, trustedCABundleCM *v1.ConfigMap)
 		return nil, err
 	}
 
+	setAlertmanagerReplicas(a, f.config.ClusterMonitoringConfiguration.AlertmanagerMainConfig.Replicas)
+
 	a.Spec.Image = &f.config.Images.Alertmanager
 
 	a.Spec.ExternalURL = f.AlertmanagerExternalURL(host).String()
@@ -426,6 +472,11 @@ func (f *Factory) AlertmanagerMain(host string, trustedCABundleCM *v1.ConfigMap)
 		a.Spec.Tolerations = f.config.ClusterMonitoringConfiguration.AlertmanagerMainConfig.Tolerations
 	}
 
+	if f.config.AlertmanagerMainUserConfigEnabled() {
+		a.Spec.AlertmanagerConfigSelector = &metav1.LabelSelector{}
+		a.Spec.AlertmanagerConfigNamespaceSelector = f.config.AlertmanagerConfigNamespaceSelector()
+	}
+
 	for i, c := range a.Spec.Containers {
 		switch c.Name {
 		case "alertmanager-proxy":
@@ -949,6 +1000,40 @@ func (f *Factory) PrometheusUserWorkloadGrpcTLSSecret() (*v1.Secret, error) {
 	return s, nil
 }
 
+func (f *Factory) PrometheusK8sAdditionalAlertmanagerConfigsSecret() (*v1.Secret, error) {
+	return newAdditionalAlertmanagerConfigsSecret(
+		PrometheusK8sAdditionalAlertmanagerConfigsSecretName,
+		f.namespace,
+		f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.AlertmanagerConfigs,
+	)
+}
+
+func (f *Factory) PrometheusUserWorkloadAdditionalAlertmanagerConfigsSecret() (*v1.Secret, error) {
+	return newAdditionalAlertmanagerConfigsSecret(
+		PrometheusUserWorkloadAdditionalAlertmanagerConfigsSecretName,
+		f.namespaceUserWorkload,
+		f.config.UserWorkloadConfiguration.Prometheus.AlertmanagerConfigs,
+	)
+}
+
+func newAdditionalAlertmanagerConfigsSecret(name, namespace string, cfgs []AdditionalAlertmanagerConfig) (*v1.Secret, error) {
+	content, err := renderPrometheusAlertmanagerConfigs(cfgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: v1.SecretTypeOpaque,
+		StringData: map[string]string{
+			AdditionalAlertmanagerConfigsKey: content,
+		},
+	}, nil
+}
+
 func (f *Factory) ThanosQuerierGrpcTLSSecret() (*v1.Secret, error) {
 	s, err := f.NewSecret(f.assets.MustNewAssetReader(ThanosQuerierGrpcTLSSecret))
 	if err != nil {
@@ -1032,6 +1117,27 @@ func (f *Factory) ThanosRulerAlertmanagerConfigSecret() (*v1.Secret, error) {
 		return nil, err
 	}
 
+	r := strings.NewReplacer(
+		"alertmanager-main.openshift-monitoring.svc", fmt.Sprintf("alertmanager-main.%s.svc", f.namespace),
+		"alertmanager-operated.openshift-monitoring.svc", fmt.Sprintf("alertmanager-operated.%s.svc", f.namespace),
+	)
+	if f.config.UserWorkloadAlertmanagerEnabled() {
+		r = strings.NewReplacer(
+			"alertmanager-main.openshift-monitoring.svc", fmt.Sprintf("alertmanager-user-workload.%s.svc", f.namespaceUserWorkload),
+			"alertmanager-operated.openshift-monitoring.svc", fmt.Sprintf("alertmanager-operated.%s.svc", f.namespaceUserWorkload),
+		)
+	}
+	for k, v := range s.StringData {
+		s.StringData[k] = r.Replace(v)
+	}
+
+	if cfgs := f.config.UserWorkloadConfiguration.ThanosRuler.AlertmanagerConfigs; len(cfgs) > 0 {
+		s.StringData[ThanosRulerAlertmanagersConfigKey], err = appendThanosAlertmanagerConfigs(s.StringData[ThanosRulerAlertmanagersConfigKey], cfgs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	s.Namespace = f.namespaceUserWorkload
 	return s, nil
 }
@@ -1179,6 +1285,8 @@ func (f *Factory) PrometheusK8s(host string, grpcTLS *v1.Secret, trustedCABundle
 		return nil, err
 	}
 
+	setPrometheusReplicas(p, f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.Replicas)
+
 	if f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.LogLevel != "" {
 		p.Spec.LogLevel = f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.LogLevel
 	}
@@ -1187,6 +1295,11 @@ func (f *Factory) PrometheusK8s(host string, grpcTLS *v1.Secret, trustedCABundle
 		p.Spec.Retention = f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.Retention
 	}
 
+	p.Spec.RetentionSize = retentionSize(
+		f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.RetentionSize,
+		f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.VolumeClaimTemplate,
+	)
+
 	p.Spec.Image = &f.config.Images.Prometheus
 	p.Spec.ExternalURL = f.PrometheusExternalURL(host).String()
 
@@ -1206,18 +1319,50 @@ func (f *Factory) PrometheusK8s(host string, grpcTLS *v1.Secret, trustedCABundle
 		p.Spec.ExternalLabels = f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.ExternalLabels
 	}
 
+	if len(f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.AlertmanagerConfigs) > 0 {
+		p.Spec.AdditionalAlertManagerConfigs = &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: PrometheusK8sAdditionalAlertmanagerConfigsSecretName},
+			Key:                  AdditionalAlertmanagerConfigsKey,
+		}
+		p.Spec.Secrets = appendUnique(p.Spec.Secrets, alertmanagerConfigSecrets(f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.AlertmanagerConfigs)...)
+	}
+
+	if f.config.AlertmanagerMainUserConfigEnabled() {
+		// The Prometheus operator also watches the user namespaces to
+		// discover the AlertmanagerConfig resources, the platform
+		// Prometheus must keep ignoring them.
+		platformNamespaces := &metav1.LabelSelector{
+			MatchLabels: map[string]string{ClusterMonitoringNamespaceLabel: "true"},
+		}
+		p.Spec.ServiceMonitorNamespaceSelector = platformNamespaces
+		p.Spec.PodMonitorNamespaceSelector = platformNamespaces
+		p.Spec.ProbeNamespaceSelector = platformNamespaces
+		p.Spec.RuleNamespaceSelector = platformNamespaces
+	}
+
 	if f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.VolumeClaimTemplate != nil {
 		p.Spec.Storage = &monv1.StorageSpec{
 			VolumeClaimTemplate: *f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.VolumeClaimTemplate,
 		}
 	}
 
+	if f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.ThanosObjectStorage != nil {
+		p.Spec.Thanos.ObjectStorageConfig = f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.ThanosObjectStorage
+		// The Thanos sidecar uploads the 2h blocks as they are cut, local
+		// compactions would race with the uploads and duplicate the data
+		// in the object storage.
+		p.Spec.DisableCompaction = true
+	}
+
 	telemetryEnabled := f.config.ClusterMonitoringConfiguration.TelemeterClientConfig.IsEnabled()
 	if telemetryEnabled && f.config.RemoteWrite {
 
-		selectorRelabelConfig, err := promqlgen.LabelSelectorsToRelabelConfig(f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.TelemetryMatches)
+		writeRelabelConfigs, err := TelemeterWriteRelabelConfigs(
+			f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.TelemetryMatches,
+			f.config.ClusterMonitoringConfiguration.TelemeterClientConfig.ClusterID,
+		)
 		if err != nil {
-			return nil, errors.Wrap(err, "generate label selector relabel config")
+			return nil, err
 		}
 
 		compositeToken, err := json.Marshal(map[string]string{
@@ -1248,22 +1393,7 @@ func (f *Factory) PrometheusK8s(host string, grpcTLS *v1.Secret, trustedCABundle
 				// produce (concurrency/256) number of requests per second.
 				MaxBackoff: "256s",
 			},
-			WriteRelabelConfigs: []monv1.RelabelConfig{
-				*selectorRelabelConfig,
-				monv1.RelabelConfig{
-					TargetLabel: "_id",
-					Replacement: f.config.ClusterMonitoringConfiguration.TelemeterClientConfig.ClusterID,
-				},
-				// relabeling the `ALERTS` series to `alerts` allows us to make
-				// a distinction between the series produced in-cluster and out
-				// of cluster.
-				monv1.RelabelConfig{
-					SourceLabels: []string{"__name__"},
-					TargetLabel:  "__name__",
-					Regex:        "ALERTS",
-					Replacement:  "alerts",
-				},
-			},
+			WriteRelabelConfigs: writeRelabelConfigs,
 		}
 
 		p.Spec.RemoteWrite = []monv1.RemoteWriteSpec{spec}
@@ -1359,14 +1489,31 @@ func (f *Factory) PrometheusUserWorkload(grpcTLS *v1.Secret) (*monv1.Prometheus,
 	if err != nil {
 		return nil, err
 	}
+
+	setPrometheusReplicas(p, f.config.UserWorkloadConfiguration.Prometheus.Replicas)
+
 	if f.config.UserWorkloadConfiguration.Prometheus.LogLevel != "" {
 		p.Spec.LogLevel = f.config.UserWorkloadConfiguration.Prometheus.LogLevel
 	}
 
+	limits := f.config.ClusterMonitoringConfiguration.UserWorkloadConfig
 	if f.config.UserWorkloadConfiguration.Prometheus.Retention != "" {
 		p.Spec.Retention = f.config.UserWorkloadConfiguration.Prometheus.Retention
+	} else if limits.MaxRetention != "" {
+		maxRetention, err := model.ParseDuration(limits.MaxRetention)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid maximum retention")
+		}
+		if maxRetention < prometheusOperatorDefaultRetention {
+			p.Spec.Retention = limits.MaxRetention
+		}
 	}
 
+	p.Spec.RetentionSize = retentionSize(
+		f.config.UserWorkloadConfiguration.Prometheus.RetentionSize,
+		f.config.UserWorkloadConfiguration.Prometheus.VolumeClaimTemplate,
+	)
+
 	p.Spec.Image = &f.config.Images.Prometheus
 
 	if f.config.UserWorkloadConfiguration.Prometheus.Resources != nil {
@@ -1395,10 +1542,37 @@ func (f *Factory) PrometheusUserWorkload(grpcTLS *v1.Secret) (*monv1.Prometheus,
 		p.Spec.RemoteWrite = f.config.UserWorkloadConfiguration.Prometheus.RemoteWrite
 	}
 
+	// The limits of the cluster monitoring configuration apply unless the
+	// user workload configuration sets lower limits.
+	p.Spec.EnforcedSampleLimit = limits.SampleLimit
 	if f.config.UserWorkloadConfiguration.Prometheus.EnforcedSampleLimit != nil {
 		p.Spec.EnforcedSampleLimit = f.config.UserWorkloadConfiguration.Prometheus.EnforcedSampleLimit
 	}
 
+	p.Spec.EnforcedTargetLimit = limits.TargetLimit
+	if f.config.UserWorkloadConfiguration.Prometheus.EnforcedTargetLimit != nil {
+		p.Spec.EnforcedTargetLimit = f.config.UserWorkloadConfiguration.Prometheus.EnforcedTargetLimit
+	}
+
+	// The namespace quota bounds every endpoint on top of the other limits.
+	if limits.NamespaceQuota.Enabled() {
+		targetLimit, sampleLimit := limits.NamespaceQuota.ScrapeLimits()
+		p.Spec.EnforcedTargetLimit = lowestLimit(p.Spec.EnforcedTargetLimit, targetLimit)
+		p.Spec.EnforcedSampleLimit = lowestLimit(p.Spec.EnforcedSampleLimit, sampleLimit)
+	}
+
+	if f.config.UserWorkloadConfiguration.Prometheus.Shards != nil {
+		p.Spec.Shards = f.config.UserWorkloadConfiguration.Prometheus.Shards
+	}
+
+	if len(f.config.UserWorkloadConfiguration.Prometheus.AlertmanagerConfigs) > 0 {
+		p.Spec.AdditionalAlertManagerConfigs = &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: PrometheusUserWorkloadAdditionalAlertmanagerConfigsSecretName},
+			Key:                  AdditionalAlertmanagerConfigsKey,
+		}
+		p.Spec.Secrets = appendUnique(p.Spec.Secrets, alertmanagerConfigSecrets(f.config.UserWorkloadConfiguration.Prometheus.AlertmanagerConfigs)...)
+	}
+
 	// end removal
 	if f.config.Images.Thanos != "" {
 		p.Spec.Thanos.Image = &f.config.Images.Thanos
@@ -1409,8 +1583,20 @@ func (f *Factory) PrometheusUserWorkload(grpcTLS *v1.Secret) (*monv1.Prometheus,
 			p.Spec.Containers[i].Image = f.config.Images.KubeRbacProxy
 		}
 	}
-	p.Spec.Alerting.Alertmanagers[0].Namespace = f.namespace
-	p.Spec.Alerting.Alertmanagers[0].TLSConfig.ServerName = fmt.Sprintf("alertmanager-main.%s.svc", f.namespace)
+	if f.config.UserWorkloadAlertmanagerEnabled() {
+		p.Spec.Alerting.Alertmanagers[0].Name = "alertmanager-user-workload"
+		p.Spec.Alerting.Alertmanagers[0].Namespace = f.namespaceUserWorkload
+		p.Spec.Alerting.Alertmanagers[0].TLSConfig.ServerName = fmt.Sprintf("alertmanager-user-workload.%s.svc", f.namespaceUserWorkload)
+	} else {
+		p.Spec.Alerting.Alertmanagers[0].Namespace = f.namespace
+		p.Spec.Alerting.Alertmanagers[0].TLSConfig.ServerName = fmt.Sprintf("alertmanager-main.%s.svc", f.namespace)
+	}
+	namespaceSelector := f.config.UserWorkloadNamespaceSelector()
+	p.Spec.ServiceMonitorNamespaceSelector = namespaceSelector
+	p.Spec.PodMonitorNamespaceSelector = namespaceSelector
+	p.Spec.ProbeNamespaceSelector = namespaceSelector
+	p.Spec.RuleNamespaceSelector = namespaceSelector
+
 	p.Namespace = f.namespaceUserWorkload
 
 	p.Spec.Volumes = append(p.Spec.Volumes, v1.Volume{
@@ -1544,6 +1730,8 @@ func (f *Factory) PrometheusAdapterDeployment(apiAuthSecretName string, requesth
 		return nil, err
 	}
 
+	setDeploymentReplicas(dep, f.config.ClusterMonitoringConfiguration.K8sPrometheusAdapter.Replicas)
+
 	spec := dep.Spec.Template.Spec
 
 	spec.Containers[0].Image = f.config.Images.K8sPrometheusAdapter
@@ -1911,6 +2099,94 @@ func (f *Factory) PrometheusK8sService() (*v1.Service, error) {
 	return s, nil
 }
 
+func (f *Factory) PrometheusK8sPodDisruptionBudget() (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb, err := f.NewPodDisruptionBudget(f.assets.MustNewAssetReader(PrometheusK8sPodDisruptionBudget))
+	if err != nil {
+		return nil, err
+	}
+
+	pdb.Namespace = f.namespace
+
+	return pdb, nil
+}
+
+func (f *Factory) PrometheusUserWorkloadPodDisruptionBudget() (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb, err := f.NewPodDisruptionBudget(f.assets.MustNewAssetReader(PrometheusUserWorkloadPodDisruptionBudget))
+	if err != nil {
+		return nil, err
+	}
+
+	pdb.Namespace = f.namespaceUserWorkload
+
+	return pdb, nil
+}
+
+func (f *Factory) AlertmanagerPodDisruptionBudget() (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb, err := f.NewPodDisruptionBudget(f.assets.MustNewAssetReader(AlertmanagerPodDisruptionBudget))
+	if err != nil {
+		return nil, err
+	}
+
+	pdb.Namespace = f.namespace
+
+	return pdb, nil
+}
+
+func (f *Factory) AlertmanagerUserWorkloadPodDisruptionBudget() (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb, err := f.NewPodDisruptionBudget(f.assets.MustNewAssetReader(AlertmanagerUserWorkloadPodDisruptionBudget))
+	if err != nil {
+		return nil, err
+	}
+
+	pdb.Namespace = f.namespaceUserWorkload
+
+	return pdb, nil
+}
+
+func (f *Factory) ThanosQuerierPodDisruptionBudget() (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb, err := f.NewPodDisruptionBudget(f.assets.MustNewAssetReader(ThanosQuerierPodDisruptionBudget))
+	if err != nil {
+		return nil, err
+	}
+
+	pdb.Namespace = f.namespace
+
+	return pdb, nil
+}
+
+func (f *Factory) ThanosRulerPodDisruptionBudget() (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb, err := f.NewPodDisruptionBudget(f.assets.MustNewAssetReader(ThanosRulerPodDisruptionBudget))
+	if err != nil {
+		return nil, err
+	}
+
+	pdb.Namespace = f.namespaceUserWorkload
+
+	return pdb, nil
+}
+
+func (f *Factory) PrometheusAdapterPodDisruptionBudget() (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb, err := f.NewPodDisruptionBudget(f.assets.MustNewAssetReader(PrometheusAdapterPodDisruptionBudget))
+	if err != nil {
+		return nil, err
+	}
+
+	pdb.Namespace = f.namespace
+
+	return pdb, nil
+}
+
+func (f *Factory) GrafanaPodDisruptionBudget() (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb, err := f.NewPodDisruptionBudget(f.assets.MustNewAssetReader(GrafanaPodDisruptionBudget))
+	if err != nil {
+		return nil, err
+	}
+
+	pdb.Namespace = f.namespace
+
+	return pdb, nil
+}
+
 func (f *Factory) PrometheusK8sServiceThanosSidecar() (*v1.Service, error) {
 	s, err := f.NewService(f.assets.MustNewAssetReader(PrometheusK8sServiceThanosSidecar))
 	if err != nil {
@@ -2071,6 +2347,8 @@ func (f *Factory) GrafanaDeployment(proxyCABundleCM *v1.ConfigMap) (*appsv1.Depl
 		return nil, err
 	}
 
+	setDeploymentReplicas(d, f.config.ClusterMonitoringConfiguration.GrafanaConfig.Replicas)
+
 	for i, container := range d.Spec.Template.Spec.Containers {
 		switch container.Name {
 		case "grafana":
@@ -2204,6 +2482,15 @@ func (f *Factory) ClusterMonitoringRulesEditClusterRole() (*rbacv1.ClusterRole,
 	return cr, nil
 }
 
+func (f *Factory) ClusterMonitoringAlertRoutingEditClusterRole() (*rbacv1.ClusterRole, error) {
+	cr, err := f.NewClusterRole(f.assets.MustNewAssetReader(ClusterMonitoringAlertRoutingEditClusterRole))
+	if err != nil {
+		return nil, err
+	}
+
+	return cr, nil
+}
+
 func (f *Factory) ClusterMonitoringRulesViewClusterRole() (*rbacv1.ClusterRole, error) {
 	cr, err := f.NewClusterRole(f.assets.MustNewAssetReader(ClusterMonitoringRulesViewClusterRole))
 	if err != nil {
@@ -2409,6 +2696,19 @@ func (f *Factory) NewService(manifest io.Reader) (*v1.Service, error) {
 	return s, nil
 }
 
+func (f *Factory) NewPodDisruptionBudget(manifest io.Reader) (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb, err := NewPodDisruptionBudget(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if pdb.GetNamespace() == "" {
+		pdb.SetNamespace(f.namespace)
+	}
+
+	return pdb, nil
+}
+
 func (f *Factory) NewEndpoints(manifest io.Reader) (*v1.Endpoints, error) {
 	e, err := NewEndpoints(manifest)
 	if err != nil {
@@ -2573,6 +2873,8 @@ func (f *Factory) NewPrometheusRule(manifest io.Reader) (*monv1.PrometheusRule,
 		p.SetNamespace(f.namespace)
 	}
 
+	f.applyAlertOverrides(p)
+
 	return p, nil
 }
 
@@ -2710,6 +3012,7 @@ func (f *Factory) ThanosQuerierDeployment(grpcTLS *v1.Secret, enableUserWorkload
 	}
 
 	d.Namespace = f.namespace
+	setDeploymentReplicas(d, f.config.ClusterMonitoringConfiguration.ThanosQuerierConfig.Replicas)
 
 	for i, c := range d.Spec.Template.Spec.Containers {
 		switch c.Name {
@@ -2920,10 +3223,8 @@ func (f *Factory) TelemeterClientDeployment(proxyCABundleCM *v1.ConfigMap) (*app
 			f.injectProxyVariables(&d.Spec.Template.Spec.Containers[i])
 
 			cmd := []string{}
-			// Note: matchers are read only during CMO bootstrap. This mechanism was chosen as CMO image will be reloaded during upgrades
-			// and matchers shouldn't change during runtime. It offers similar amount of protection against unwanted configuration changes
-			// while not having any performace penalty. However it should be changed to usual reconciliation mechanism after CMO performance
-			// issues are solved.
+			// Note: the matchers come from the telemetry-config ConfigMap
+			// and are refreshed on every reconciliation.
 			for _, a := range d.Spec.Template.Spec.Containers[i].Command {
 				if !strings.HasPrefix(a, "--match=") {
 					cmd = append(cmd, a)
@@ -2932,7 +3233,7 @@ func (f *Factory) TelemeterClientDeployment(proxyCABundleCM *v1.ConfigMap) (*app
 			for _, m := range f.config.ClusterMonitoringConfiguration.PrometheusK8sConfig.TelemetryMatches {
 				cmd = append(cmd, fmt.Sprintf("--match=%s", m))
 			}
-			cmd = append(cmd, "--limit-bytes=5242880")
+			cmd = append(cmd, fmt.Sprintf("--limit-bytes=%d", TelemeterClientLimitBytes))
 			d.Spec.Template.Spec.Containers[i].Command = cmd
 
 			if proxyCABundleCM != nil {
@@ -3126,6 +3427,8 @@ func (f *Factory) ThanosRulerCustomResource(queryURL string, trustedCA *v1.Confi
 		return nil, err
 	}
 
+	setThanosRulerReplicas(t, f.config.UserWorkloadConfiguration.ThanosRuler.Replicas)
+
 	t.Spec.Image = f.config.Images.Thanos
 
 	if f.config.UserWorkloadConfiguration.ThanosRuler.LogLevel != "" {
@@ -3189,15 +3492,152 @@ func (f *Factory) ThanosRulerCustomResource(queryURL string, trustedCA *v1.Confi
 	}
 	t.Spec.Volumes = append(t.Spec.Volumes, secretVolume)
 
+	for _, name := range alertmanagerConfigSecrets(f.config.UserWorkloadConfiguration.ThanosRuler.AlertmanagerConfigs) {
+		volumeName := "secret-" + name
+		t.Spec.Volumes = append(t.Spec.Volumes, v1.Volume{
+			Name: volumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: name,
+				},
+			},
+		})
+		for i := range t.Spec.Containers {
+			if t.Spec.Containers[i].Name != "thanos-ruler" {
+				continue
+			}
+			t.Spec.Containers[i].VolumeMounts = append(t.Spec.Containers[i].VolumeMounts, v1.VolumeMount{
+				Name:      volumeName,
+				MountPath: path.Join(alertmanagerSecretsMountPath, name),
+				ReadOnly:  true,
+			})
+		}
+	}
+
 	if queryURL != "" {
 		t.Spec.AlertQueryURL = queryURL
 	}
 
+	t.Spec.RuleNamespaceSelector = f.config.UserWorkloadNamespaceSelector()
+
 	t.Namespace = f.namespaceUserWorkload
 
 	return t, nil
 }
 
+func (f *Factory) AlertmanagerUserWorkload() (*monv1.Alertmanager, error) {
+	a, err := f.NewAlertmanager(f.assets.MustNewAssetReader(AlertmanagerUserWorkload))
+	if err != nil {
+		return nil, err
+	}
+
+	setAlertmanagerReplicas(a, f.config.UserWorkloadConfiguration.Alertmanager.Replicas)
+
+	a.Spec.Image = &f.config.Images.Alertmanager
+
+	if f.config.UserWorkloadConfiguration.Alertmanager.LogLevel != "" {
+		a.Spec.LogLevel = f.config.UserWorkloadConfiguration.Alertmanager.LogLevel
+	}
+
+	if f.config.UserWorkloadConfiguration.Alertmanager.Resources != nil {
+		a.Spec.Resources = *f.config.UserWorkloadConfiguration.Alertmanager.Resources
+	}
+
+	if f.config.UserWorkloadConfiguration.Alertmanager.VolumeClaimTemplate != nil {
+		a.Spec.Storage = &monv1.StorageSpec{
+			VolumeClaimTemplate: *f.config.UserWorkloadConfiguration.Alertmanager.VolumeClaimTemplate,
+		}
+	}
+
+	if f.config.UserWorkloadConfiguration.Alertmanager.NodeSelector != nil {
+		a.Spec.NodeSelector = f.config.UserWorkloadConfiguration.Alertmanager.NodeSelector
+	}
+
+	if len(f.config.UserWorkloadConfiguration.Alertmanager.Tolerations) > 0 {
+		a.Spec.Tolerations = f.config.UserWorkloadConfiguration.Alertmanager.Tolerations
+	}
+
+	if f.config.UserWorkloadAlertmanagerConfigEnabled() {
+		a.Spec.AlertmanagerConfigSelector = &metav1.LabelSelector{}
+		a.Spec.AlertmanagerConfigNamespaceSelector = f.config.AlertmanagerConfigNamespaceSelector()
+	}
+
+	for i, c := range a.Spec.Containers {
+		if c.Name == "kube-rbac-proxy" {
+			a.Spec.Containers[i].Image = f.config.Images.KubeRbacProxy
+		}
+	}
+
+	a.Namespace = f.namespaceUserWorkload
+
+	return a, nil
+}
+
+func (f *Factory) AlertmanagerUserWorkloadSecret() (*v1.Secret, error) {
+	s, err := f.NewSecret(f.assets.MustNewAssetReader(AlertmanagerUserWorkloadSecret))
+	if err != nil {
+		return nil, err
+	}
+
+	s.Namespace = f.namespaceUserWorkload
+
+	return s, nil
+}
+
+func (f *Factory) AlertmanagerUserWorkloadService() (*v1.Service, error) {
+	s, err := f.NewService(f.assets.MustNewAssetReader(AlertmanagerUserWorkloadService))
+	if err != nil {
+		return nil, err
+	}
+
+	s.Namespace = f.namespaceUserWorkload
+
+	return s, nil
+}
+
+func (f *Factory) AlertmanagerUserWorkloadServiceAccount() (*v1.ServiceAccount, error) {
+	s, err := f.NewServiceAccount(f.assets.MustNewAssetReader(AlertmanagerUserWorkloadServiceAccount))
+	if err != nil {
+		return nil, err
+	}
+
+	s.Namespace = f.namespaceUserWorkload
+
+	return s, nil
+}
+
+func (f *Factory) AlertmanagerUserWorkloadClusterRole() (*rbacv1.ClusterRole, error) {
+	return f.NewClusterRole(f.assets.MustNewAssetReader(AlertmanagerUserWorkloadClusterRole))
+}
+
+func (f *Factory) AlertmanagerUserWorkloadClusterRoleBinding() (*rbacv1.ClusterRoleBinding, error) {
+	crb, err := f.NewClusterRoleBinding(f.assets.MustNewAssetReader(AlertmanagerUserWorkloadClusterRoleBinding))
+	if err != nil {
+		return nil, err
+	}
+
+	crb.Subjects[0].Namespace = f.namespaceUserWorkload
+
+	return crb, nil
+}
+
+func (f *Factory) AlertmanagerUserWorkloadAlertsSenderClusterRole() (*rbacv1.ClusterRole, error) {
+	return f.NewClusterRole(f.assets.MustNewAssetReader(AlertmanagerUserWorkloadAlertsSenderClusterRole))
+}
+
+func (f *Factory) AlertmanagerUserWorkloadAlertsSenderClusterRoleBinding() (*rbacv1.ClusterRoleBinding, error) {
+	crb, err := f.NewClusterRoleBinding(f.assets.MustNewAssetReader(AlertmanagerUserWorkloadAlertsSenderClusterRoleBinding))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range crb.Subjects {
+		crb.Subjects[i].Namespace = f.namespaceUserWorkload
+	}
+
+	return crb, nil
+}
+
 func NewDaemonSet(manifest io.Reader) (*appsv1.DaemonSet, error) {
 	ds := appsv1.DaemonSet{}
 	err := yaml.NewYAMLOrJSONDecoder(manifest, 100).Decode(&ds)
@@ -3218,6 +3658,16 @@ func NewService(manifest io.Reader) (*v1.Service, error) {
 	return &s, nil
 }
 
+func NewPodDisruptionBudget(manifest io.Reader) (*policyv1beta1.PodDisruptionBudget, error) {
+	pdb := policyv1beta1.PodDisruptionBudget{}
+	err := yaml.NewYAMLOrJSONDecoder(manifest, 100).Decode(&pdb)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pdb, nil
+}
+
 func NewEndpoints(manifest io.Reader) (*v1.Endpoints, error) {
 	e := v1.Endpoints{}
 	err := yaml.NewYAMLOrJSONDecoder(manifest, 100).Decode(&e)
@@ -3539,3 +3989,20 @@ func trustedCABundleVolume(configMapName, volumeName string) v1.Volume {
 		},
 	}
 }
+
+// appendUnique appends the values which aren't already present in the slice.
+func appendUnique(s []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, e := range s {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s = append(s, v)
+		}
+	}
+	return s
+}
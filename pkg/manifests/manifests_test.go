@@ -24,8 +24,10 @@ import (
 	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 type fakeInfrastructureReader struct {
@@ -146,7 +148,7 @@ func TestHashSecret(t *testing.T) {
 
 func TestUnconfiguredManifests(t *testing.T) {
 	f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", NewDefaultConfig(), defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
-	_, err := f.AlertmanagerConfig()
+	_, err := f.AlertmanagerConfig(nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -161,6 +163,46 @@ func TestUnconfiguredManifests(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	_, err = f.PrometheusK8sPodDisruptionBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.PrometheusUserWorkloadPodDisruptionBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.AlertmanagerPodDisruptionBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.AlertmanagerUserWorkloadPodDisruptionBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.ThanosQuerierPodDisruptionBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.ThanosRulerPodDisruptionBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.PrometheusAdapterPodDisruptionBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.GrafanaPodDisruptionBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	_, err = f.ThanosRulerAlertmanagerConfigSecret()
 	if err != nil {
 		t.Fatal(err)
@@ -506,6 +548,46 @@ func TestUnconfiguredManifests(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	_, err = f.AlertmanagerUserWorkload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.AlertmanagerUserWorkloadSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.AlertmanagerUserWorkloadService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.AlertmanagerUserWorkloadServiceAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.AlertmanagerUserWorkloadClusterRole()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.AlertmanagerUserWorkloadClusterRoleBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.AlertmanagerUserWorkloadAlertsSenderClusterRole()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.AlertmanagerUserWorkloadAlertsSenderClusterRoleBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	tlsSecret := &v1.Secret{
 		Data: map[string][]byte{
 			"tls.crt": []byte("foo"),
@@ -650,6 +732,11 @@ func TestUnconfiguredManifests(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	_, err = f.ClusterMonitoringAlertRoutingEditClusterRole()
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	_, err = f.ClusterMonitoringRulesViewClusterRole()
 	if err != nil {
 		t.Fatal(err)
@@ -1566,129 +1653,914 @@ func TestThanosRulerConfiguration(t *testing.T) {
 	}
 }
 
-func TestNonHighlyAvailableInfrastructure(t *testing.T) {
-	type spec struct {
-		replicas int32
-		affinity *v1.Affinity
+func TestUserWorkloadNamespaceSelector(t *testing.T) {
+	optOut := metav1.LabelSelectorRequirement{
+		Key:      UserWorkloadMonitoringNamespaceLabel,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   []string{"false"},
 	}
 
-	tests := []struct {
-		name    string
-		getSpec func(f *Factory) (spec, error)
+	for _, tc := range []struct {
+		name     string
+		config   string
+		expected *metav1.LabelSelector
 	}{
 		{
-			name: "Prometheus",
-			getSpec: func(f *Factory) (spec, error) {
-				p, err := f.PrometheusK8s(
-					"prometheus-k8s.openshift-monitoring.svc",
-					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
-					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
-				)
-				if err != nil {
-					return spec{}, err
-				}
-				return spec{*p.Spec.Replicas, p.Spec.Affinity}, nil
-			},
+			name:     "default",
+			expected: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{optOut}},
 		},
 		{
-			name: "Alertmanager",
-			getSpec: func(f *Factory) (spec, error) {
-				a, err := f.AlertmanagerMain(
-					"alertmanager-main.openshift-monitoring.svc",
-					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
-				)
-				if err != nil {
-					return spec{}, err
-				}
-				return spec{*a.Spec.Replicas, a.Spec.Affinity}, nil
+			name: "namespace selector",
+			config: `userWorkload:
+  namespaceSelector:
+    matchLabels:
+      monitoring: enabled
+`,
+			expected: &metav1.LabelSelector{
+				MatchLabels:      map[string]string{"monitoring": "enabled"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{optOut},
 			},
 		},
-		{
-			name: "Thanos querier",
-			getSpec: func(f *Factory) (spec, error) {
-				q, err := f.ThanosQuerierDeployment(
-					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
-					true,
-					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
-				)
-				if err != nil {
-					return spec{}, err
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+
+			p, err := f.PrometheusUserWorkload(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, selector := range map[string]*metav1.LabelSelector{
+				"serviceMonitorNamespaceSelector": p.Spec.ServiceMonitorNamespaceSelector,
+				"podMonitorNamespaceSelector":     p.Spec.PodMonitorNamespaceSelector,
+				"probeNamespaceSelector":          p.Spec.ProbeNamespaceSelector,
+				"ruleNamespaceSelector":           p.Spec.RuleNamespaceSelector,
+			} {
+				if !reflect.DeepEqual(tc.expected, selector) {
+					t.Fatalf("expected Prometheus %s %v, got %v", name, tc.expected, selector)
 				}
-				return spec{*q.Spec.Replicas, q.Spec.Template.Spec.Affinity}, nil
-			},
+			}
+
+			tr, err := f.ThanosRulerCustomResource(
+				"",
+				&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+				&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.expected, tr.Spec.RuleNamespaceSelector) {
+				t.Fatalf("expected Thanos Ruler ruleNamespaceSelector %v, got %v", tc.expected, tr.Spec.RuleNamespaceSelector)
+			}
+		})
+	}
+}
+
+func TestUserWorkloadNamespaceSelectorInvalid(t *testing.T) {
+	_, err := NewConfigFromString(`userWorkload:
+  namespaceSelector:
+    matchExpressions:
+    - key: monitoring
+      operator: Foo
+`)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestUserWorkloadLimits(t *testing.T) {
+	uint64Ptr := func(v uint64) *uint64 { return &v }
+
+	for _, tc := range []struct {
+		name                string
+		config              string
+		uwmConfig           string
+		invalid             bool
+		expectedSampleLimit *uint64
+		expectedTargetLimit *uint64
+		expectedRetention   string
+	}{
+		{
+			name: "no limits",
 		},
 		{
-			name: "Prometheus (user-workload)",
-			getSpec: func(f *Factory) (spec, error) {
-				p, err := f.PrometheusUserWorkload(
-					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
-				)
-				if err != nil {
-					return spec{}, err
-				}
-				return spec{*p.Spec.Replicas, p.Spec.Affinity}, nil
-			},
+			name: "user limits",
+			uwmConfig: `prometheus:
+  enforcedSampleLimit: 1000
+  enforcedTargetLimit: 10
+  retention: 48h
+`,
+			expectedSampleLimit: uint64Ptr(1000),
+			expectedTargetLimit: uint64Ptr(10),
+			expectedRetention:   "48h",
 		},
 		{
-			name: "Thanos ruler",
-			getSpec: func(f *Factory) (spec, error) {
-				t, err := f.ThanosRulerCustomResource(
-					"",
-					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
-					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
-				)
-				if err != nil {
-					return spec{}, err
-				}
-				return spec{*t.Spec.Replicas, t.Spec.Affinity}, nil
-			},
+			name: "admin limits",
+			config: `userWorkload:
+  sampleLimit: 5000
+  targetLimit: 50
+  maxRetention: 12h
+`,
+			expectedSampleLimit: uint64Ptr(5000),
+			expectedTargetLimit: uint64Ptr(50),
+			expectedRetention:   "12h",
 		},
 		{
-			name: "Prometheus adapter",
-			getSpec: func(f *Factory) (spec, error) {
-				p, err := f.PrometheusAdapterDeployment(
-					"foo",
-					map[string]string{
-						"requestheader-allowed-names":        "",
-						"requestheader-extra-headers-prefix": "",
-						"requestheader-group-headers":        "",
-						"requestheader-username-headers":     "",
-					})
-				if err != nil {
-					return spec{}, err
-				}
-				return spec{*p.Spec.Replicas, p.Spec.Template.Spec.Affinity}, nil
-			},
+			name: "admin maximum retention above the default",
+			config: `userWorkload:
+  maxRetention: 7d
+`,
 		},
-	}
+		{
+			name: "user limits below admin limits",
+			config: `userWorkload:
+  sampleLimit: 5000
+  targetLimit: 50
+  maxRetention: 7d
+`,
+			uwmConfig: `prometheus:
+  enforcedSampleLimit: 1000
+  enforcedTargetLimit: 10
+  retention: 48h
+`,
+			expectedSampleLimit: uint64Ptr(1000),
+			expectedTargetLimit: uint64Ptr(10),
+			expectedRetention:   "48h",
+		},
+		{
+			name: "namespace quota",
+			config: `userWorkload:
+  namespaceQuota:
+    maxSamples: 1000
+    maxTargets: 10
+`,
+			expectedSampleLimit: uint64Ptr(100),
+			expectedTargetLimit: uint64Ptr(10),
+		},
+		{
+			name: "namespace quota with lower limits",
+			config: `userWorkload:
+  sampleLimit: 500
+  namespaceQuota:
+    maxSamples: 1000
+    maxTargets: 10
+`,
+			uwmConfig: `prometheus:
+  enforcedSampleLimit: 50
+  enforcedTargetLimit: 5
+`,
+			expectedSampleLimit: uint64Ptr(50),
+			expectedTargetLimit: uint64Ptr(5),
+		},
+		{
+			name: "namespace quota with higher limits",
+			config: `userWorkload:
+  sampleLimit: 5000
+  targetLimit: 50
+  namespaceQuota:
+    maxSamples: 1000
+    maxTargets: 10
+`,
+			expectedSampleLimit: uint64Ptr(100),
+			expectedTargetLimit: uint64Ptr(10),
+		},
+		{
+			name: "sample limit above admin limit",
+			config: `userWorkload:
+  sampleLimit: 5000
+`,
+			uwmConfig: `prometheus:
+  enforcedSampleLimit: 10000
+`,
+			invalid: true,
+		},
+		{
+			name: "no sample limit with admin limit",
+			config: `userWorkload:
+  sampleLimit: 5000
+`,
+			uwmConfig: `prometheus:
+  enforcedSampleLimit: 0
+`,
+			invalid: true,
+		},
+		{
+			name: "target limit above admin limit",
+			config: `userWorkload:
+  targetLimit: 50
+`,
+			uwmConfig: `prometheus:
+  enforcedTargetLimit: 100
+`,
+			invalid: true,
+		},
+		{
+			name: "retention above admin maximum",
+			config: `userWorkload:
+  maxRetention: 7d
+`,
+			uwmConfig: `prometheus:
+  retention: 2w
+`,
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.UserWorkloadConfiguration, err = NewUserConfigFromString(tc.uwmConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
 
-	for _, tc := range tests {
-		f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", NewDefaultConfig(), &fakeInfrastructureReader{highlyAvailableInfrastructure: false}, &fakeProxyReader{}, NewAssets(assetsPath))
-		spec, err := tc.getSpec(f)
-		if err != nil {
-			t.Error(err)
-		}
+			err = c.ValidateUserWorkloadLimits()
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
 
-		if spec.replicas != 1 {
-			t.Errorf("expecting 1 replica, got %d", spec.replicas)
-		}
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+			p, err := f.PrometheusUserWorkload(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+			if err != nil {
+				t.Fatal(err)
+			}
 
-		if spec.affinity != nil {
-			t.Errorf("expected no affinity constraints with 1 replica, got %v", spec.affinity)
-		}
+			if !reflect.DeepEqual(tc.expectedSampleLimit, p.Spec.EnforcedSampleLimit) {
+				t.Fatalf("expected sample limit %v, got %v", tc.expectedSampleLimit, p.Spec.EnforcedSampleLimit)
+			}
+			if !reflect.DeepEqual(tc.expectedTargetLimit, p.Spec.EnforcedTargetLimit) {
+				t.Fatalf("expected target limit %v, got %v", tc.expectedTargetLimit, p.Spec.EnforcedTargetLimit)
+			}
+			if p.Spec.Retention != tc.expectedRetention {
+				t.Fatalf("expected retention %q, got %q", tc.expectedRetention, p.Spec.Retention)
+			}
+		})
 	}
 }
 
-func trustedCABundleVolumeConfigured(volumes []v1.Volume, volumeName string) bool {
-	for _, volume := range volumes {
-		if volume.Name == volumeName {
-			return true
-		}
-	}
-	return false
-}
+func TestPrometheusUserWorkloadShards(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		uwmConfig string
+		invalid   bool
+		expected  *int32
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "sharded",
+			uwmConfig: `prometheus:
+  shards: 3
+`,
+			expected: func(i int32) *int32 { return &i }(3),
+		},
+		{
+			name: "no shard",
+			uwmConfig: `prometheus:
+  shards: 0
+`,
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			uwc, err := NewUserConfigFromString(tc.uwmConfig)
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
 
-func trustedCABundleVolumeMountsConfigured(volumeMounts []v1.VolumeMount, volumeName string) bool {
+			c := NewDefaultConfig()
+			c.UserWorkloadConfiguration = uwc
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+			p, err := f.PrometheusUserWorkload(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.expected, p.Spec.Shards) {
+				t.Fatalf("expected shards %v, got %v", tc.expected, p.Spec.Shards)
+			}
+		})
+	}
+}
+
+func TestRetentionSize(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   string
+		invalid  bool
+		expected string
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "explicit",
+			config: `prometheus:
+  retentionSize: 10GB
+`,
+			expected: "10GB",
+		},
+		{
+			name: "auto",
+			config: `prometheus:
+  retentionSize: auto
+  volumeClaimTemplate:
+    spec:
+      resources:
+        requests:
+          storage: 40Gi
+`,
+			expected: "34816MB",
+		},
+		{
+			name: "auto without storage",
+			config: `prometheus:
+  retentionSize: auto
+`,
+			invalid: true,
+		},
+		{
+			name: "invalid",
+			config: `prometheus:
+  retentionSize: 10G
+`,
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// The platform configuration nests the same fields
+			// under prometheusK8s.
+			c, err := NewConfigFromString(strings.Replace(tc.config, "prometheus:", "prometheusK8s:", 1))
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected an error for the platform Prometheus")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			uwc, err := NewUserConfigFromString(tc.config)
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected an error for the user workload Prometheus")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.UserWorkloadConfiguration = uwc
+
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+			p, err := f.PrometheusK8s("prometheus-k8s.openshift-monitoring.svc", &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Spec.RetentionSize != tc.expected {
+				t.Errorf("expected platform retention size %q, got %q", tc.expected, p.Spec.RetentionSize)
+			}
+
+			p, err = f.PrometheusUserWorkload(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Spec.RetentionSize != tc.expected {
+				t.Errorf("expected user workload retention size %q, got %q", tc.expected, p.Spec.RetentionSize)
+			}
+		})
+	}
+}
+
+func TestPrometheusK8sThanosObjectStorage(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   string
+		invalid  bool
+		expected *v1.SecretKeySelector
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "object storage",
+			config: `prometheusK8s:
+  thanosObjectStorage:
+    name: thanos-objstore
+    key: thanos.yaml
+`,
+			expected: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "thanos-objstore"},
+				Key:                  "thanos.yaml",
+			},
+		},
+		{
+			name: "missing key",
+			config: `prometheusK8s:
+  thanosObjectStorage:
+    name: thanos-objstore
+`,
+			invalid: true,
+		},
+		{
+			name: "short retention",
+			config: `prometheusK8s:
+  retention: 12h
+  thanosObjectStorage:
+    name: thanos-objstore
+    key: thanos.yaml
+`,
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewConfigFromString(tc.config)
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+			p, err := f.PrometheusK8s("prometheus-k8s.openshift-monitoring.svc", &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.expected, p.Spec.Thanos.ObjectStorageConfig) {
+				t.Fatalf("expected object storage config %v, got %v", tc.expected, p.Spec.Thanos.ObjectStorageConfig)
+			}
+			if p.Spec.DisableCompaction != (tc.expected != nil) {
+				t.Fatalf("expected compaction to be disabled only with the object storage, got %t", p.Spec.DisableCompaction)
+			}
+		})
+	}
+}
+
+func TestAlertmanagerUserWorkload(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		uwmConfig  string
+		serverName string
+		namespace  string
+	}{
+		{
+			name:       "disabled",
+			serverName: "alertmanager-main.openshift-monitoring.svc",
+			namespace:  "openshift-monitoring",
+		},
+		{
+			name: "enabled",
+			uwmConfig: `alertmanager:
+  enabled: true
+  logLevel: debug
+  nodeSelector:
+    type: worker
+  volumeClaimTemplate:
+    spec:
+      resources:
+        requests:
+          storage: 10Gi
+`,
+			serverName: "alertmanager-user-workload.openshift-user-workload-monitoring.svc",
+			namespace:  "openshift-user-workload-monitoring",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewConfigFromString("enableUserWorkload: true")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.UserWorkloadConfiguration, err = NewUserConfigFromString(tc.uwmConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+
+			p, err := f.PrometheusUserWorkload(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			am := p.Spec.Alerting.Alertmanagers[0]
+			if am.Namespace != tc.namespace {
+				t.Errorf("expected Alertmanager namespace %q, got %q", tc.namespace, am.Namespace)
+			}
+			if am.TLSConfig.ServerName != tc.serverName {
+				t.Errorf("expected Alertmanager server name %q, got %q", tc.serverName, am.TLSConfig.ServerName)
+			}
+
+			s, err := f.ThanosRulerAlertmanagerConfigSecret()
+			if err != nil {
+				t.Fatal(err)
+			}
+			amConfig := s.StringData["alertmanagers.yaml"]
+			if !strings.Contains(amConfig, tc.serverName) {
+				t.Errorf("expected Thanos Ruler to target %q, got:\n%s", tc.serverName, amConfig)
+			}
+			if !strings.Contains(amConfig, "alertmanager-operated."+tc.namespace+".svc") {
+				t.Errorf("expected Thanos Ruler to target the %q namespace, got:\n%s", tc.namespace, amConfig)
+			}
+
+			if tc.uwmConfig == "" {
+				return
+			}
+
+			a, err := f.AlertmanagerUserWorkload()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Namespace != "openshift-user-workload-monitoring" {
+				t.Errorf("unexpected namespace %q", a.Namespace)
+			}
+			if a.Spec.LogLevel != "debug" {
+				t.Errorf("expected log level debug, got %q", a.Spec.LogLevel)
+			}
+			if a.Spec.NodeSelector["type"] != "worker" {
+				t.Error("Alertmanager node selector not configured correctly")
+			}
+			storageRequest := a.Spec.Storage.VolumeClaimTemplate.Spec.Resources.Requests[v1.ResourceStorage]
+			if storageRequest.String() != "10Gi" {
+				t.Errorf("expected 10Gi storage request, got %s", storageRequest.String())
+			}
+		})
+	}
+}
+
+func TestReplicas(t *testing.T) {
+	c, err := NewConfigFromString(`prometheusK8s:
+  replicas: 1
+alertmanagerMain:
+  replicas: 2
+thanosQuerier:
+  replicas: 3
+grafana:
+  replicas: 2
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.UserWorkloadConfiguration, err = NewUserConfigFromString(`thanosRuler:
+  replicas: 3
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+	if err := f.ValidateReplicas(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := f.PrometheusK8s("prometheus-k8s.openshift-monitoring.svc", &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *p.Spec.Replicas != 1 || p.Spec.Affinity != nil {
+		t.Fatalf("expected 1 Prometheus replica without affinity, got %d replicas and affinity %v", *p.Spec.Replicas, p.Spec.Affinity)
+	}
+
+	a, err := f.AlertmanagerMain("alertmanager-main.openshift-monitoring.svc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *a.Spec.Replicas != 2 || a.Spec.Affinity == nil {
+		t.Fatalf("expected 2 Alertmanager replicas with affinity, got %d replicas and affinity %v", *a.Spec.Replicas, a.Spec.Affinity)
+	}
+
+	d, err := f.ThanosQuerierDeployment(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, false, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Data: map[string]string{"ca-bundle.crt": ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *d.Spec.Replicas != 3 || d.Spec.Template.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		t.Fatalf("expected 3 Thanos Querier replicas with the asset affinity, got %d replicas and affinity %v", *d.Spec.Replicas, d.Spec.Template.Spec.Affinity)
+	}
+
+	d, err = f.GrafanaDeployment(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Data: map[string]string{"ca-bundle.crt": ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *d.Spec.Replicas != 2 || d.Spec.Template.Spec.Affinity == nil {
+		t.Fatalf("expected 2 Grafana replicas with affinity, got %d replicas and affinity %v", *d.Spec.Replicas, d.Spec.Template.Spec.Affinity)
+	}
+
+	tr, err := f.ThanosRulerCustomResource("", &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *tr.Spec.Replicas != 3 || tr.Spec.Affinity == nil {
+		t.Fatalf("expected 3 Thanos Ruler replicas with affinity, got %d replicas and affinity %v", *tr.Spec.Replicas, tr.Spec.Affinity)
+	}
+
+	f = NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, &fakeInfrastructureReader{highlyAvailableInfrastructure: false}, &fakeProxyReader{}, NewAssets(assetsPath))
+	if err := f.ValidateReplicas(); err == nil {
+		t.Fatal("expected an error for several replicas on a single replica topology")
+	}
+
+	c, err = NewConfigFromString(`k8sPrometheusAdapter:
+  replicas: 0
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f = NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+	if err := f.ValidateReplicas(); err == nil {
+		t.Fatal("expected an error for 0 replicas")
+	}
+}
+
+func TestPodDisruptionBudgets(t *testing.T) {
+	f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", NewDefaultConfig(), defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+
+	// podLabels returns the labels set by the Prometheus operator on the pods
+	// of a custom resource.
+	podLabels := func(meta *monv1.EmbeddedObjectMetadata, selector map[string]string) labels.Set {
+		lset := labels.Set{}
+		if meta != nil {
+			for k, v := range meta.Labels {
+				lset[k] = v
+			}
+		}
+		for k, v := range selector {
+			lset[k] = v
+		}
+		return lset
+	}
+
+	for _, tc := range []struct {
+		name string
+		pdb  func() (*policyv1beta1.PodDisruptionBudget, error)
+		pods func() (labels.Set, error)
+	}{
+		{
+			name: "Prometheus",
+			pdb:  f.PrometheusK8sPodDisruptionBudget,
+			pods: func() (labels.Set, error) {
+				p, err := f.PrometheusK8s("prometheus-k8s.openshift-monitoring.svc", secret, nil)
+				if err != nil {
+					return nil, err
+				}
+				return podLabels(p.Spec.PodMetadata, map[string]string{"app": "prometheus", "prometheus": p.Name}), nil
+			},
+		},
+		{
+			name: "Alertmanager",
+			pdb:  f.AlertmanagerPodDisruptionBudget,
+			pods: func() (labels.Set, error) {
+				a, err := f.AlertmanagerMain("alertmanager-main.openshift-monitoring.svc", nil)
+				if err != nil {
+					return nil, err
+				}
+				return podLabels(a.Spec.PodMetadata, map[string]string{"app": "alertmanager", "alertmanager": a.Name}), nil
+			},
+		},
+		{
+			name: "Thanos Querier",
+			pdb:  f.ThanosQuerierPodDisruptionBudget,
+			pods: func() (labels.Set, error) {
+				d, err := f.ThanosQuerierDeployment(secret, true, nil)
+				if err != nil {
+					return nil, err
+				}
+				return d.Spec.Template.Labels, nil
+			},
+		},
+		{
+			name: "prometheus-adapter",
+			pdb:  f.PrometheusAdapterPodDisruptionBudget,
+			pods: func() (labels.Set, error) {
+				d, err := f.PrometheusAdapterDeployment("foo", map[string]string{
+					"requestheader-allowed-names":        "",
+					"requestheader-extra-headers-prefix": "",
+					"requestheader-group-headers":        "",
+					"requestheader-username-headers":     "",
+				})
+				if err != nil {
+					return nil, err
+				}
+				return d.Spec.Template.Labels, nil
+			},
+		},
+		{
+			name: "Grafana",
+			pdb:  f.GrafanaPodDisruptionBudget,
+			pods: func() (labels.Set, error) {
+				d, err := f.GrafanaDeployment(nil)
+				if err != nil {
+					return nil, err
+				}
+				return d.Spec.Template.Labels, nil
+			},
+		},
+		{
+			name: "UserWorkload Prometheus",
+			pdb:  f.PrometheusUserWorkloadPodDisruptionBudget,
+			pods: func() (labels.Set, error) {
+				p, err := f.PrometheusUserWorkload(secret)
+				if err != nil {
+					return nil, err
+				}
+				return podLabels(p.Spec.PodMetadata, map[string]string{"app": "prometheus", "prometheus": p.Name}), nil
+			},
+		},
+		{
+			name: "UserWorkload Alertmanager",
+			pdb:  f.AlertmanagerUserWorkloadPodDisruptionBudget,
+			pods: func() (labels.Set, error) {
+				a, err := f.AlertmanagerUserWorkload()
+				if err != nil {
+					return nil, err
+				}
+				return podLabels(a.Spec.PodMetadata, map[string]string{"app": "alertmanager", "alertmanager": a.Name}), nil
+			},
+		},
+		{
+			name: "Thanos Ruler",
+			pdb:  f.ThanosRulerPodDisruptionBudget,
+			pods: func() (labels.Set, error) {
+				tr, err := f.ThanosRulerCustomResource("", nil, secret)
+				if err != nil {
+					return nil, err
+				}
+				return podLabels(tr.Spec.PodMetadata, map[string]string{"app": "thanos-ruler", "thanos-ruler": tr.Name}), nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pdb, err := tc.pdb()
+			if err != nil {
+				t.Fatal(err)
+			}
+			pods, err := tc.pods()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !selector.Matches(pods) {
+				t.Fatalf("expected the selector %q to match the pod labels %v", selector, pods)
+			}
+		})
+	}
+}
+
+func TestNonHighlyAvailableInfrastructure(t *testing.T) {
+	type spec struct {
+		replicas int32
+		affinity *v1.Affinity
+	}
+
+	tests := []struct {
+		name    string
+		getSpec func(f *Factory) (spec, error)
+	}{
+		{
+			name: "Prometheus",
+			getSpec: func(f *Factory) (spec, error) {
+				p, err := f.PrometheusK8s(
+					"prometheus-k8s.openshift-monitoring.svc",
+					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+				)
+				if err != nil {
+					return spec{}, err
+				}
+				return spec{*p.Spec.Replicas, p.Spec.Affinity}, nil
+			},
+		},
+		{
+			name: "Alertmanager",
+			getSpec: func(f *Factory) (spec, error) {
+				a, err := f.AlertmanagerMain(
+					"alertmanager-main.openshift-monitoring.svc",
+					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+				)
+				if err != nil {
+					return spec{}, err
+				}
+				return spec{*a.Spec.Replicas, a.Spec.Affinity}, nil
+			},
+		},
+		{
+			name: "Thanos querier",
+			getSpec: func(f *Factory) (spec, error) {
+				q, err := f.ThanosQuerierDeployment(
+					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+					true,
+					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+				)
+				if err != nil {
+					return spec{}, err
+				}
+				return spec{*q.Spec.Replicas, q.Spec.Template.Spec.Affinity}, nil
+			},
+		},
+		{
+			name: "Prometheus (user-workload)",
+			getSpec: func(f *Factory) (spec, error) {
+				p, err := f.PrometheusUserWorkload(
+					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+				)
+				if err != nil {
+					return spec{}, err
+				}
+				return spec{*p.Spec.Replicas, p.Spec.Affinity}, nil
+			},
+		},
+		{
+			name: "Alertmanager (user-workload)",
+			getSpec: func(f *Factory) (spec, error) {
+				a, err := f.AlertmanagerUserWorkload()
+				if err != nil {
+					return spec{}, err
+				}
+				return spec{*a.Spec.Replicas, a.Spec.Affinity}, nil
+			},
+		},
+		{
+			name: "Thanos ruler",
+			getSpec: func(f *Factory) (spec, error) {
+				t, err := f.ThanosRulerCustomResource(
+					"",
+					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+				)
+				if err != nil {
+					return spec{}, err
+				}
+				return spec{*t.Spec.Replicas, t.Spec.Affinity}, nil
+			},
+		},
+		{
+			name: "Prometheus adapter",
+			getSpec: func(f *Factory) (spec, error) {
+				p, err := f.PrometheusAdapterDeployment(
+					"foo",
+					map[string]string{
+						"requestheader-allowed-names":        "",
+						"requestheader-extra-headers-prefix": "",
+						"requestheader-group-headers":        "",
+						"requestheader-username-headers":     "",
+					})
+				if err != nil {
+					return spec{}, err
+				}
+				return spec{*p.Spec.Replicas, p.Spec.Template.Spec.Affinity}, nil
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", NewDefaultConfig(), &fakeInfrastructureReader{highlyAvailableInfrastructure: false}, &fakeProxyReader{}, NewAssets(assetsPath))
+		spec, err := tc.getSpec(f)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if spec.replicas != 1 {
+			t.Errorf("expecting 1 replica, got %d", spec.replicas)
+		}
+
+		if spec.affinity != nil {
+			t.Errorf("expected no affinity constraints with 1 replica, got %v", spec.affinity)
+		}
+	}
+}
+
+func trustedCABundleVolumeConfigured(volumes []v1.Volume, volumeName string) bool {
+	for _, volume := range volumes {
+		if volume.Name == volumeName {
+			return true
+		}
+	}
+	return false
+}
+
+func trustedCABundleVolumeMountsConfigured(volumeMounts []v1.VolumeMount, volumeName string) bool {
 	for _, volumeMount := range volumeMounts {
 		if volumeMount.Name == volumeName {
 			return true
@@ -1696,3 +2568,471 @@ func trustedCABundleVolumeMountsConfigured(volumeMounts []v1.VolumeMount, volume
 	}
 	return false
 }
+
+func TestAlertmanagerConfigSelector(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		config    string
+		uwmConfig string
+		main      bool
+		uwm       bool
+	}{
+		{
+			name: "disabled",
+			config: `enableUserWorkload: true
+`,
+		},
+		{
+			name: "user workload disabled",
+			config: `alertmanagerMain:
+  enableUserAlertmanagerConfig: true
+`,
+		},
+		{
+			name: "platform Alertmanager",
+			config: `enableUserWorkload: true
+alertmanagerMain:
+  enableUserAlertmanagerConfig: true
+`,
+			main: true,
+		},
+		{
+			name: "platform Alertmanager with namespace selector",
+			config: `enableUserWorkload: true
+userWorkload:
+  namespaceSelector:
+    matchLabels:
+      team: a
+alertmanagerMain:
+  enableUserAlertmanagerConfig: true
+`,
+			main: true,
+		},
+		{
+			name: "user workload Alertmanager deployed",
+			config: `enableUserWorkload: true
+alertmanagerMain:
+  enableUserAlertmanagerConfig: true
+`,
+			uwmConfig: `alertmanager:
+  enabled: true
+`,
+		},
+		{
+			name: "user workload Alertmanager",
+			config: `enableUserWorkload: true
+`,
+			uwmConfig: `alertmanager:
+  enabled: true
+  enableAlertmanagerConfig: true
+`,
+			uwm: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.UserWorkloadConfiguration, err = NewUserConfigFromString(tc.uwmConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+
+			a, err := f.AlertmanagerMain("alertmanager-main.openshift-monitoring.svc", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkAlertmanagerConfigSelector(t, c, a, tc.main)
+
+			a, err = f.AlertmanagerUserWorkload()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkAlertmanagerConfigSelector(t, c, a, tc.uwm)
+
+			// The platform operator is always scoped to the given
+			// namespaces.
+			d, err := f.PrometheusOperatorDeployment([]string{"openshift-monitoring", "user"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			namespacesFound := false
+			for _, arg := range d.Spec.Template.Spec.Containers[0].Args {
+				if arg == PrometheusOperatorNamespaceFlag+"openshift-monitoring,user" {
+					namespacesFound = true
+				}
+			}
+			if !namespacesFound {
+				t.Fatalf("expected %s flag to be present: %v", PrometheusOperatorNamespaceFlag, d.Spec.Template.Spec.Containers[0].Args)
+			}
+
+			p, err := f.PrometheusK8s("prometheus-k8s.openshift-monitoring.svc", &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			platformOnly := len(p.Spec.ServiceMonitorNamespaceSelector.MatchLabels) > 0
+			if platformOnly != tc.main {
+				t.Fatalf("expected the Prometheus namespace selectors to be restricted: %v, got %v", tc.main, p.Spec.ServiceMonitorNamespaceSelector)
+			}
+		})
+	}
+}
+
+func checkAlertmanagerConfigSelector(t *testing.T, c *Config, a *monv1.Alertmanager, enabled bool) {
+	t.Helper()
+
+	if !enabled {
+		if a.Spec.AlertmanagerConfigSelector != nil || a.Spec.AlertmanagerConfigNamespaceSelector != nil {
+			t.Fatalf("expected no AlertmanagerConfig selectors for %s", a.Name)
+		}
+		return
+	}
+
+	if a.Spec.AlertmanagerConfigSelector == nil {
+		t.Fatalf("expected an AlertmanagerConfig selector for %s", a.Name)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(a.Spec.AlertmanagerConfigNamespaceSelector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		labels   map[string]string
+		expected bool
+	}{
+		{
+			labels:   map[string]string{ClusterMonitoringNamespaceLabel: "true"},
+			expected: false,
+		},
+		{
+			labels:   map[string]string{UserWorkloadMonitoringNamespaceLabel: "false"},
+			expected: false,
+		},
+		{
+			labels:   map[string]string{"team": "b"},
+			expected: c.ClusterMonitoringConfiguration.UserWorkloadConfig.NamespaceSelector == nil,
+		},
+		{
+			labels:   map[string]string{"team": "a"},
+			expected: true,
+		},
+	} {
+		if got := selector.Matches(labels.Set(tc.labels)); got != tc.expected {
+			t.Fatalf("expected namespace with labels %v selected: %v for %s, got %v", tc.labels, tc.expected, a.Name, got)
+		}
+	}
+}
+
+func TestAdditionalAlertmanagerConfigs(t *testing.T) {
+	c, err := NewConfigFromString(`prometheusK8s:
+  additionalAlertmanagerConfigs:
+  - scheme: https
+    pathPrefix: /central
+    apiVersion: v2
+    bearerToken:
+      name: central-alertmanager
+      key: token
+    tlsConfig:
+      ca:
+        name: central-alertmanager-tls
+        key: ca.crt
+      serverName: alertmanager.example.com
+    staticConfigs:
+    - alertmanager.example.com:443
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.UserWorkloadConfiguration, err = NewUserConfigFromString(`thanosRuler:
+  additionalAlertmanagerConfigs:
+  - scheme: https
+    basicAuth:
+      username: ruler
+      password:
+        name: team-alertmanager
+        key: password
+    staticConfigs:
+    - alertmanager.team.example.com:9093
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+
+	s, err := f.PrometheusK8sAdditionalAlertmanagerConfigsSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `- scheme: https
+  path_prefix: /central
+  api_version: v2
+  bearer_token_file: /etc/prometheus/secrets/central-alertmanager/token
+  tls_config:
+    ca_file: /etc/prometheus/secrets/central-alertmanager-tls/ca.crt
+    server_name: alertmanager.example.com
+  static_configs:
+  - targets:
+    - alertmanager.example.com:443
+`
+	if got := s.StringData[AdditionalAlertmanagerConfigsKey]; got != expected {
+		t.Fatalf("unexpected additional Alertmanager configs, expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if s.Namespace != "openshift-monitoring" {
+		t.Fatalf("expected namespace openshift-monitoring, got %q", s.Namespace)
+	}
+
+	p, err := f.PrometheusK8s("prometheus-k8s.openshift-monitoring.svc", &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Spec.AdditionalAlertManagerConfigs == nil || p.Spec.AdditionalAlertManagerConfigs.Name != s.Name {
+		t.Fatalf("expected Prometheus to reference the %q secret, got %v", s.Name, p.Spec.AdditionalAlertManagerConfigs)
+	}
+	if got := p.Spec.Secrets[len(p.Spec.Secrets)-2:]; !reflect.DeepEqual(got, []string{"central-alertmanager", "central-alertmanager-tls"}) {
+		t.Errorf("expected the Alertmanager secrets to be mounted, got %v", p.Spec.Secrets)
+	}
+
+	p, err = f.PrometheusUserWorkload(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Spec.AdditionalAlertManagerConfigs != nil {
+		t.Fatalf("expected no additional Alertmanager configs for the UserWorkload Prometheus, got %v", p.Spec.AdditionalAlertManagerConfigs)
+	}
+
+	s, err = f.ThanosRulerAlertmanagerConfigSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	amConfig := s.StringData[ThanosRulerAlertmanagersConfigKey]
+	for _, expected := range []string{
+		"dnssrv+_web._tcp.alertmanager-operated.openshift-monitoring.svc",
+		"- alertmanager.team.example.com:9093",
+		"password_file: /etc/prometheus/secrets/team-alertmanager/password",
+	} {
+		if !strings.Contains(amConfig, expected) {
+			t.Errorf("expected Thanos Ruler alertmanagers config to contain %q, got:\n%s", expected, amConfig)
+		}
+	}
+
+	tr, err := f.ThanosRulerCustomResource(
+		"",
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mounted := false
+	for _, c := range tr.Spec.Containers {
+		if c.Name != "thanos-ruler" {
+			continue
+		}
+		for _, vm := range c.VolumeMounts {
+			if vm.Name == "secret-team-alertmanager" && vm.MountPath == "/etc/prometheus/secrets/team-alertmanager" {
+				mounted = true
+			}
+		}
+	}
+	if !mounted {
+		t.Fatal("expected the team-alertmanager secret to be mounted in the thanos-ruler container")
+	}
+}
+
+func TestAdditionalAlertmanagerConfigsInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "no address",
+			config: `prometheusK8s:
+  additionalAlertmanagerConfigs:
+  - scheme: https
+`,
+			err: "at least one static address is required",
+		},
+		{
+			name: "bearer token and basic auth",
+			config: `prometheusK8s:
+  additionalAlertmanagerConfigs:
+  - bearerToken:
+      name: foo
+      key: token
+    basicAuth:
+      username: foo
+      password:
+        name: foo
+        key: password
+    staticConfigs:
+    - alertmanager:9093
+`,
+			err: "mutually exclusive",
+		},
+		{
+			name: "incomplete secret reference",
+			config: `prometheusK8s:
+  additionalAlertmanagerConfigs:
+  - bearerToken:
+      name: foo
+    staticConfigs:
+    - alertmanager:9093
+`,
+			err: "secret references require both name and key",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+			_, err = f.PrometheusK8sAdditionalAlertmanagerConfigsSecret()
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestAlertOverrides(t *testing.T) {
+	c, err := NewConfigFromString(`alertOverrides:
+- alert: KubeCPUOvercommit
+  disabled: true
+- alert: KubeMemoryOvercommit
+  for: 1h
+  severity: info
+  labels:
+    team: capacity
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+	if err := f.ValidateAlertOverrides(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := f.ControlPlanePrometheusRule()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var memOvercommit *monv1.Rule
+	for _, g := range r.Spec.Groups {
+		if len(g.Rules) == 0 {
+			t.Errorf("expected group %q to have rules", g.Name)
+		}
+		for i, rule := range g.Rules {
+			switch rule.Alert {
+			case "KubeCPUOvercommit":
+				t.Fatal("expected KubeCPUOvercommit to be disabled")
+			case "KubeMemoryOvercommit":
+				memOvercommit = &g.Rules[i]
+			}
+		}
+	}
+
+	if memOvercommit == nil {
+		t.Fatal("expected KubeMemoryOvercommit to be present")
+	}
+	if memOvercommit.For != "1h" {
+		t.Errorf("expected for duration 1h, got %q", memOvercommit.For)
+	}
+	expected := map[string]string{"severity": "info", "team": "capacity"}
+	if !reflect.DeepEqual(memOvercommit.Labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, memOvercommit.Labels)
+	}
+
+	// Other rules are left untouched.
+	r, err = f.AlertmanagerPrometheusRule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unmodified, err := NewPrometheusRule(f.assets.MustNewAssetReader(AlertmanagerPrometheusRule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Spec, unmodified.Spec) {
+		t.Error("expected the Alertmanager rules to be unmodified")
+	}
+}
+
+func TestAlertOverridesInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "unknown alert",
+			config: `alertOverrides:
+- alert: NotAnAlert
+  disabled: true
+`,
+			err: `alert "NotAnAlert" isn't a platform alert`,
+		},
+		{
+			name: "missing name",
+			config: `alertOverrides:
+- disabled: true
+`,
+			err: "alert override without alert name",
+		},
+		{
+			name: "duplicate",
+			config: `alertOverrides:
+- alert: KubeCPUOvercommit
+  disabled: true
+- alert: KubeCPUOvercommit
+  for: 1h
+`,
+			err: "overridden more than once",
+		},
+		{
+			name: "invalid duration",
+			config: `alertOverrides:
+- alert: KubeCPUOvercommit
+  for: 5 minutes
+`,
+			err: "invalid 'for' duration",
+		},
+		{
+			name: "severity label",
+			config: `alertOverrides:
+- alert: KubeCPUOvercommit
+  labels:
+    severity: info
+`,
+			err: "must be overridden with the severity field",
+		},
+		{
+			name: "invalid label",
+			config: `alertOverrides:
+- alert: KubeCPUOvercommit
+  labels:
+    routing-key: foo
+`,
+			err: `invalid label name "routing-key"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", c, defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
+			err = f.ValidateAlertOverrides()
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"bytes"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const versionLabel = "app.kubernetes.io/version"
+
+// operands lists the components reported as operands in the ClusterOperator
+// status. Each entry references the key of the component in the images map
+// and the asset carrying the app.kubernetes.io/version label.
+var operands = []struct {
+	name  string
+	image string
+	asset string
+}{
+	{name: "prometheus", image: "prometheus", asset: PrometheusK8s},
+	{name: "alertmanager", image: "alertmanager", asset: AlertmanagerMain},
+	{name: "thanos", image: "thanos", asset: ThanosQuerierDeployment},
+	{name: "prometheus-operator", image: "prometheus-operator", asset: PrometheusOperatorDeployment},
+	{name: "kube-state-metrics", image: "kube-state-metrics", asset: KubeStateMetricsDeployment},
+	{name: "node-exporter", image: "node-exporter", asset: NodeExporterDaemonSet},
+	{name: "prometheus-adapter", image: "k8s-prometheus-adapter", asset: PrometheusAdapterDeployment},
+	{name: "grafana", image: "grafana", asset: GrafanaDeployment},
+	{name: "openshift-state-metrics", image: "openshift-state-metrics", asset: OpenShiftStateMetricsDeployment},
+	{name: "telemeter-client", image: "telemeter-client", asset: TelemeterClientDeployment},
+}
+
+// OperandVersions returns the versions of the operands deployed by the
+// operator. An operand is only reported when an image is configured for it
+// and its asset defines a version label.
+func OperandVersions(a *Assets, images map[string]string) ([]configv1.OperandVersion, error) {
+	var versions []configv1.OperandVersion
+	for _, o := range operands {
+		if images[o.image] == "" {
+			continue
+		}
+
+		b, err := a.GetAsset(o.asset)
+		if err != nil {
+			return nil, err
+		}
+
+		var m metav1.PartialObjectMetadata
+		if err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 100).Decode(&m); err != nil {
+			return nil, errors.Wrapf(err, "parsing asset %s failed", o.asset)
+		}
+
+		v := m.GetLabels()[versionLabel]
+		if v == "" {
+			continue
+		}
+
+		versions = append(versions, configv1.OperandVersion{
+			Name:    o.name,
+			Version: v,
+		})
+	}
+
+	return versions, nil
+}
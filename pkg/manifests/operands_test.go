@@ -0,0 +1,71 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"testing"
+)
+
+func TestOperandVersions(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		images   map[string]string
+		expected map[string]bool
+	}{
+		{
+			name:     "no images",
+			images:   map[string]string{},
+			expected: map[string]bool{},
+		},
+		{
+			name: "some images",
+			images: map[string]string{
+				"prometheus":   "quay.io/prometheus/prometheus:latest",
+				"alertmanager": "quay.io/prometheus/alertmanager:latest",
+			},
+			expected: map[string]bool{
+				"prometheus":   true,
+				"alertmanager": true,
+			},
+		},
+		{
+			name: "image without version label",
+			images: map[string]string{
+				"telemeter-client": "quay.io/openshift/telemeter:latest",
+			},
+			expected: map[string]bool{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			versions, err := OperandVersions(NewAssets(assetsPath), tc.images)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(versions) != len(tc.expected) {
+				t.Fatalf("expected %d operand versions, got %v", len(tc.expected), versions)
+			}
+
+			for _, v := range versions {
+				if !tc.expected[v.Name] {
+					t.Errorf("unexpected operand %q", v.Name)
+				}
+				if v.Version == "" {
+					t.Errorf("expected version for operand %q, got none", v.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValidateReplicas returns an error if a configured number of replicas is
+// lower than 1 or if it is greater than 1 on a single replica infrastructure
+// topology.
+func (f *Factory) ValidateReplicas() error {
+	cmc := f.config.ClusterMonitoringConfiguration
+	uwc := f.config.UserWorkloadConfiguration
+
+	for _, c := range []struct {
+		component string
+		replicas  *int32
+	}{
+		{component: "prometheusK8s", replicas: cmc.PrometheusK8sConfig.Replicas},
+		{component: "alertmanagerMain", replicas: cmc.AlertmanagerMainConfig.Replicas},
+		{component: "thanosQuerier", replicas: cmc.ThanosQuerierConfig.Replicas},
+		{component: "k8sPrometheusAdapter", replicas: cmc.K8sPrometheusAdapter.Replicas},
+		{component: "grafana", replicas: cmc.GrafanaConfig.Replicas},
+		{component: "user workload prometheus", replicas: uwc.Prometheus.Replicas},
+		{component: "user workload alertmanager", replicas: uwc.Alertmanager.Replicas},
+		{component: "user workload thanosRuler", replicas: uwc.ThanosRuler.Replicas},
+	} {
+		if c.replicas == nil {
+			continue
+		}
+		if *c.replicas < 1 {
+			return errors.Errorf("%s: invalid number of replicas %d, it must be at least 1", c.component, *c.replicas)
+		}
+		if *c.replicas > 1 && !f.infrastructure.HighlyAvailableInfrastructure() {
+			return errors.Errorf("%s: %d replicas aren't supported by the single replica infrastructure topology", c.component, *c.replicas)
+		}
+	}
+
+	return nil
+}
+
+// setPrometheusReplicas overrides the replicas of the asset. The
+// anti-affinity is removed for a single replica.
+func setPrometheusReplicas(p *monv1.Prometheus, replicas *int32) {
+	if replicas == nil {
+		return
+	}
+	p.Spec.Replicas = replicas
+	if *replicas == 1 {
+		p.Spec.Affinity = nil
+	}
+}
+
+// setAlertmanagerReplicas overrides the replicas of the asset. The
+// anti-affinity is removed for a single replica.
+func setAlertmanagerReplicas(a *monv1.Alertmanager, replicas *int32) {
+	if replicas == nil {
+		return
+	}
+	a.Spec.Replicas = replicas
+	if *replicas == 1 {
+		a.Spec.Affinity = nil
+	}
+}
+
+// setThanosRulerReplicas overrides the replicas of the asset. The
+// anti-affinity is removed for a single replica and added for more replicas
+// since the asset doesn't define any.
+func setThanosRulerReplicas(t *monv1.ThanosRuler, replicas *int32) {
+	if replicas == nil {
+		return
+	}
+	t.Spec.Replicas = replicas
+	switch {
+	case *replicas == 1:
+		t.Spec.Affinity = nil
+	case t.Spec.Affinity == nil:
+		t.Spec.Affinity = preferredPodAntiAffinity(t.Namespace, map[string]string{"thanos-ruler": t.Name})
+	}
+}
+
+// setDeploymentReplicas overrides the replicas of the asset. The
+// anti-affinity is removed for a single replica and added for more replicas
+// when the asset doesn't define any.
+func setDeploymentReplicas(d *appsv1.Deployment, replicas *int32) {
+	if replicas == nil {
+		return
+	}
+	d.Spec.Replicas = replicas
+	switch {
+	case *replicas == 1:
+		d.Spec.Template.Spec.Affinity = nil
+	case d.Spec.Template.Spec.Affinity == nil:
+		d.Spec.Template.Spec.Affinity = preferredPodAntiAffinity(d.Namespace, d.Spec.Selector.MatchLabels)
+	}
+}
+
+// preferredPodAntiAffinity spreads the pods matching the labels across nodes
+// when possible.
+func preferredPodAntiAffinity(namespace string, labels map[string]string) *v1.Affinity {
+	return &v1.Affinity{
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: v1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
+						Namespaces:    []string{namespace},
+						TopologyKey:   "kubernetes.io/hostname",
+					},
+				},
+			},
+		},
+	}
+}
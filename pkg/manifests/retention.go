@@ -0,0 +1,86 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// RetentionSizeAuto derives the retention size from the storage
+	// requested by the volume claim template.
+	RetentionSizeAuto = "auto"
+
+	// autoRetentionSizeRatio is the share of the volume used by the TSDB
+	// blocks in the automatic mode. The remainder leaves room for the WAL
+	// and for the compactions, which Prometheus doesn't account for.
+	autoRetentionSizeRatio = 85
+)
+
+// retentionSizeRegexp matches the byte sizes understood by Prometheus.
+var retentionSizeRegexp = regexp.MustCompile(`^(0|([0-9]*[.])?[0-9]+((K|M|G|T|E|P)i?)?B)$`)
+
+// validateRetentionSize returns an error if the retention size can't be
+// parsed by Prometheus or if the automatic mode has no storage request to
+// derive it from.
+func validateRetentionSize(size string, vct *monv1.EmbeddedPersistentVolumeClaim) error {
+	switch {
+	case size == "":
+		return nil
+	case size == RetentionSizeAuto:
+		if _, found := storageRequest(vct); !found {
+			return errors.Errorf("retentionSize %q requires a volumeClaimTemplate with a storage request", RetentionSizeAuto)
+		}
+		return nil
+	case !retentionSizeRegexp.MatchString(size):
+		return errors.Errorf("invalid retentionSize %q, it must be %q or a size in bytes such as 10GB", size, RetentionSizeAuto)
+	}
+	return nil
+}
+
+// retentionSize returns the retention size passed to Prometheus. The
+// automatic mode leaves 15% of the requested storage free.
+func retentionSize(size string, vct *monv1.EmbeddedPersistentVolumeClaim) string {
+	if size != RetentionSizeAuto {
+		return size
+	}
+
+	request, found := storageRequest(vct)
+	if !found {
+		return ""
+	}
+	b := request.Value() * autoRetentionSizeRatio / 100
+	if b < 1<<20 {
+		return fmt.Sprintf("%dB", b)
+	}
+	return fmt.Sprintf("%dMB", b>>20)
+}
+
+func storageRequest(vct *monv1.EmbeddedPersistentVolumeClaim) (*resource.Quantity, bool) {
+	if vct == nil {
+		return nil, false
+	}
+	request, found := vct.Spec.Resources.Requests[v1.ResourceStorage]
+	if !found || request.IsZero() {
+		return nil, false
+	}
+	return &request, true
+}
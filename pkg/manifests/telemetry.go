@@ -0,0 +1,50 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"github.com/openshift/cluster-monitoring-operator/pkg/promqlgen"
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+)
+
+// TelemeterClientLimitBytes is the maximum size of the payload which
+// telemeter-client federates from Prometheus.
+const TelemeterClientLimitBytes = 5242880
+
+// TelemeterWriteRelabelConfigs returns the relabel configs applied to the
+// series sent to the Telemeter server with remote write.
+func TelemeterWriteRelabelConfigs(matches []string, clusterID string) ([]monv1.RelabelConfig, error) {
+	selectorRelabelConfigs, err := promqlgen.LabelSelectorsToRelabelConfig(matches)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate label selector relabel config")
+	}
+
+	return append(selectorRelabelConfigs,
+		monv1.RelabelConfig{
+			TargetLabel: "_id",
+			Replacement: clusterID,
+		},
+		// relabeling the `ALERTS` series to `alerts` allows us to make
+		// a distinction between the series produced in-cluster and out
+		// of cluster.
+		monv1.RelabelConfig{
+			SourceLabels: []string{"__name__"},
+			TargetLabel:  "__name__",
+			Regex:        "ALERTS",
+			Replacement:  "alerts",
+		},
+	), nil
+}
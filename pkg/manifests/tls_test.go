@@ -205,7 +205,7 @@ func TestRotateGrpcTLSSecret(t *testing.T) {
 
 func TestUnconfiguredGRPCManifests(t *testing.T) {
 	f := NewFactory("openshift-monitoring", "openshift-user-workload-monitoring", NewDefaultConfig(), defaultInfrastructureReader(), &fakeProxyReader{}, NewAssets(assetsPath))
-	_, err := f.AlertmanagerConfig()
+	_, err := f.AlertmanagerConfig(nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,107 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+// namespaceQuotaConditionType is the type of the ClusterMonitoring condition
+// listing the namespaces over quota.
+const namespaceQuotaConditionType = "UserWorkloadNamespaceQuotaExceeded"
+
+// namespaceQuotaChecker finds the user namespaces over quota. The quota
+// itself is enforced by the user workload Prometheus which bounds every
+// ServiceMonitor and PodMonitor endpoint to the quota of a namespace, the
+// resources of the namespaces are never modified. A namespace is over quota
+// when its monitors have more endpoints than the quota allows targets.
+type namespaceQuotaChecker struct {
+	namespaces      cache.Store
+	serviceMonitors lister
+	podMonitors     lister
+	// platformNamespaces selects the namespaces which aren't considered for
+	// user workload monitoring.
+	platformNamespaces labels.Selector
+
+	// quota and selector are nil when the quota doesn't apply.
+	quota    *manifests.UserWorkloadNamespaceQuota
+	selector labels.Selector
+}
+
+// setConfig sets the quota and the user namespaces from the configuration.
+func (c *namespaceQuotaChecker) setConfig(config *manifests.Config) error {
+	c.quota, c.selector = nil, nil
+
+	quota := config.ClusterMonitoringConfiguration.UserWorkloadConfig.NamespaceQuota
+	if !*config.ClusterMonitoringConfiguration.UserWorkloadEnabled || !quota.Enabled() {
+		return nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(config.UserWorkloadNamespaceSelector())
+	if err != nil {
+		return err
+	}
+	c.quota, c.selector = quota, selector
+	return nil
+}
+
+// check returns a description of the namespaces over quota indexed by name.
+func (c *namespaceQuotaChecker) check() map[string]string {
+	overQuota := make(map[string]string)
+	if c.quota == nil {
+		return overQuota
+	}
+
+	endpoints := make(map[string]int)
+	for _, obj := range c.serviceMonitors.List() {
+		sm := obj.(*monv1.ServiceMonitor)
+		endpoints[sm.Namespace] += len(sm.Spec.Endpoints)
+	}
+	for _, obj := range c.podMonitors.List() {
+		pm := obj.(*monv1.PodMonitor)
+		endpoints[pm.Namespace] += len(pm.Spec.PodMetricsEndpoints)
+	}
+
+	for ns, n := range endpoints {
+		if uint64(n) <= c.quota.MaxTargets || !c.quotaApplies(ns) {
+			continue
+		}
+		overQuota[ns] = fmt.Sprintf(
+			"The ServiceMonitors and PodMonitors of the namespace have %d endpoints but its user workload monitoring quota allows %d targets.",
+			n, c.quota.MaxTargets,
+		)
+	}
+
+	return overQuota
+}
+
+// quotaApplies returns true if the namespace is monitored by the user
+// workload stack.
+func (c *namespaceQuotaChecker) quotaApplies(namespace string) bool {
+	obj, exists, err := c.namespaces.GetByKey(namespace)
+	if err != nil || !exists {
+		return false
+	}
+	lset := labels.Set(obj.(*v1.Namespace).Labels)
+	return !c.platformNamespaces.Matches(lset) && c.selector.Matches(lset)
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+func TestNamespaceQuotaChecker(t *testing.T) {
+	serviceMonitor := func(namespace, name string, endpoints int) *monv1.ServiceMonitor {
+		return &monv1.ServiceMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       monv1.ServiceMonitorSpec{Endpoints: make([]monv1.Endpoint, endpoints)},
+		}
+	}
+
+	namespaces := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for _, ns := range []*v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "openshift-monitoring", Labels: map[string]string{"openshift.io/cluster-monitoring": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "user"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "crowded"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "opted-out", Labels: map[string]string{manifests.UserWorkloadMonitoringNamespaceLabel: "false"}}},
+	} {
+		if err := namespaces.Add(ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serviceMonitors := []runtime.Object{
+		serviceMonitor("openshift-monitoring", "platform", 3),
+		serviceMonitor("user", "app", 1),
+		serviceMonitor("crowded", "old", 2),
+		serviceMonitor("crowded", "new", 1),
+		serviceMonitor("opted-out", "app", 3),
+	}
+	podMonitors := []runtime.Object{
+		&monv1.PodMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "pods", Namespace: "user"},
+			Spec:       monv1.PodMonitorSpec{PodMetricsEndpoints: make([]monv1.PodMetricsEndpoint, 1)},
+		},
+	}
+
+	c := &namespaceQuotaChecker{
+		namespaces:         namespaces,
+		serviceMonitors:    newStore(t, serviceMonitors, &monv1.ServiceMonitor{}),
+		podMonitors:        newStore(t, podMonitors, &monv1.PodMonitor{}),
+		platformNamespaces: labels.SelectorFromSet(labels.Set{"openshift.io/cluster-monitoring": "true"}),
+	}
+
+	for _, tc := range []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name: "quota disabled",
+			config: `enableUserWorkload: true
+`,
+		},
+		{
+			name: "user workload disabled",
+			config: `userWorkload:
+  namespaceQuota:
+    maxSamples: 1000
+    maxTargets: 2
+`,
+		},
+		{
+			name: "quota enabled",
+			config: `enableUserWorkload: true
+userWorkload:
+  namespaceQuota:
+    maxSamples: 1000
+    maxTargets: 2
+`,
+			expected: []string{"crowded"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := manifests.NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.setConfig(config); err != nil {
+				t.Fatal(err)
+			}
+
+			overQuota := c.check()
+			if len(overQuota) != len(tc.expected) {
+				t.Fatalf("expected %v to be over quota, got %v", tc.expected, overQuota)
+			}
+			for _, ns := range tc.expected {
+				if _, found := overQuota[ns]; !found {
+					t.Fatalf("expected %v to be over quota, got %v", tc.expected, overQuota)
+				}
+			}
+		})
+	}
+}
@@ -17,7 +17,10 @@ package operator
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
@@ -26,6 +29,8 @@ import (
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -34,7 +39,10 @@ import (
 
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/openshift/cluster-monitoring-operator/pkg/rules"
+	cmostrings "github.com/openshift/cluster-monitoring-operator/pkg/strings"
 	"github.com/openshift/cluster-monitoring-operator/pkg/tasks"
+	"github.com/openshift/cluster-monitoring-operator/pkg/telemetry"
 )
 
 // InfrastructureConfig stores information about the cluster infrastructure
@@ -110,6 +118,13 @@ func (pc *ProxyConfig) NoProxy() string {
 const (
 	resyncPeriod = 15 * time.Minute
 
+	// volumeResizeCheckPeriod is the interval at which the operator checks
+	// the persistent volume claims being expanded.
+	volumeResizeCheckPeriod = time.Minute
+	// volumeResizeTimeout is the time after which a volume expansion which
+	// hasn't completed is reported as failed.
+	volumeResizeTimeout = 30 * time.Minute
+
 	// see https://github.com/kubernetes/apiserver/blob/b571c70e6e823fd78910c3f5b9be895a756f4cbb/pkg/server/options/authentication.go#L239
 	apiAuthenticationConfigMap    = "kube-system/extension-apiserver-authentication"
 	kubeletServingCAConfigMap     = "openshift-config-managed/kubelet-serving-ca"
@@ -118,6 +133,27 @@ const (
 	telemeterCABundleConfigMap    = "openshift-monitoring/telemeter-trusted-ca-bundle"
 	alertmanagerCABundleConfigMap = "openshift-monitoring/alertmanager-trusted-ca-bundle"
 	grpcTLS                       = "openshift-monitoring/grpc-tls"
+	telemetryConfigMap            = "openshift-monitoring/telemetry-config"
+
+	// Key of the telemetry configuration in the telemetry ConfigMap.
+	telemetryConfigKey = "metrics.yaml"
+
+	// Queue key of the reconciliations triggered by namespace changes, only
+	// the Prometheus operators are updated.
+	namespacesKey = "namespaces"
+
+	// Queue key of the checks of the user workload namespace quota and of
+	// the user-defined resources, which run outside of the reconciliation of
+	// the stack.
+	userWorkloadResourcesKey = "user-workload-resources"
+
+	// Queue key of the check of the persistent volume expansions, which
+	// runs after the reconciliation of the stack.
+	volumesKey = "volumes"
+
+	// Reason of the Degraded condition when persistent volumes can't be
+	// expanded.
+	volumeExpansionFailedReason = "VolumeExpansionFailed"
 
 	// Canonical name of the cluster-wide infrastrucure resource.
 	clusterResourceName = "cluster"
@@ -137,8 +173,21 @@ type Operator struct {
 
 	client *client.Client
 
-	cmapInf   cache.SharedIndexInformer
-	informers []cache.SharedIndexInformer
+	cmapInf              cache.SharedIndexInformer
+	clusterMonitoringInf cache.SharedIndexInformer
+	informers            []cache.SharedIndexInformer
+	// namespaceStore is the cache of the namespace informer.
+	namespaceStore cache.Store
+
+	// convertedConfigMapVersion is the resource version of the Cluster
+	// Monitoring ConfigMap last mirrored into the ClusterMonitoring
+	// resource, nil if no conversion happened yet. The version is empty
+	// when the ConfigMap doesn't exist.
+	convertedConfigMapVersion *string
+
+	// observedGeneration is the generation of the ClusterMonitoring resource
+	// used by the current reconciliation, 0 if there is none.
+	observedGeneration int64
 
 	queue workqueue.RateLimitingInterface
 
@@ -146,6 +195,60 @@ type Operator struct {
 	reconcileStatus   prometheus.Gauge
 
 	assets *manifests.Assets
+
+	upgradeableChecks []upgradeableCheck
+
+	// The following fields are only used by the worker, hence they aren't
+	// protected by a mutex. userWorkloadConfig is the configuration of the
+	// last reconciliation, nil until the first one. namespacesOverQuota and
+	// rejectedResources hold the problems already reported.
+	userWorkloadConfig    *manifests.Config
+	userWorkloadInfs      *userWorkloadInformers
+	userWorkloadResources *userWorkloadResourcesValidator
+	namespaceQuota        *namespaceQuotaChecker
+	namespacesOverQuota   map[string]string
+	rejectedResources     map[string]string
+
+	// userWorkloadUpgradeable is the upgradeable check reading the caches
+	// of userWorkloadInfs. upgradeablePending is true when the last
+	// evaluation of the Upgradeable condition ran before they were synced.
+	userWorkloadUpgradeable *userWorkloadResourcesCheck
+	upgradeablePending      bool
+
+	// rulesLintErr holds the problems found in the PrometheusRule assets
+	// when linting is enabled.
+	rulesLintErr error
+
+	// resizingVolumes holds when the worker first saw each persistent
+	// volume claim being expanded. volumesErr holds the volume expansions
+	// reported as failed.
+	resizingVolumes map[string]time.Time
+	volumesErr      error
+
+	// telemetryConfig is the telemetry configuration of the last
+	// reconciliation, used by the telemetry preview.
+	telemetryMtx    sync.RWMutex
+	telemetryConfig *telemetry.Config
+
+	// platformNamespaces selects the namespaces monitored by the platform
+	// stack.
+	platformNamespaces labels.Selector
+	// namespacesSynced is false until the first reconciliation.
+	namespacesMtx    sync.RWMutex
+	namespacesSynced bool
+	// userWorkloadEnabled is true when user workload monitoring is enabled,
+	// the labels of the namespaces then select the resources checked by
+	// userWorkloadResourcesKey.
+	userWorkloadEnabled bool
+	// alertmanagerConfigNamespaces selects the user namespaces watched by
+	// the platform Prometheus operator for AlertmanagerConfig resources. It
+	// is nil when the platform Alertmanager doesn't process them.
+	alertmanagerConfigNamespaces labels.Selector
+
+	// alertmanagerSecrets holds the keys of the Secrets referenced by the
+	// structured Alertmanager configuration.
+	alertmanagerSecretsMtx sync.RWMutex
+	alertmanagerSecrets    map[string]struct{}
 }
 
 func New(
@@ -161,6 +264,11 @@ func New(
 		return nil, err
 	}
 
+	platformNamespaces, err := labels.Parse(namespaceSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid namespace selector")
+	}
+
 	o := &Operator{
 		images:                    images,
 		telemetryMatches:          telemetryMatches,
@@ -173,6 +281,7 @@ func New(
 		queue:                     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "cluster-monitoring"),
 		informers:                 make([]cache.SharedIndexInformer, 0),
 		assets:                    a,
+		platformNamespaces:        platformNamespaces,
 	}
 
 	informer := cache.NewSharedIndexInformer(
@@ -194,6 +303,27 @@ func New(
 		DeleteFunc: o.handleEvent,
 	})
 
+	o.clusterMonitoringInf = cache.NewSharedIndexInformer(
+		o.client.ClusterMonitoringListWatch(), &unstructured.Unstructured{}, resyncPeriod, cache.Indexers{},
+	)
+	o.clusterMonitoringInf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    o.handleEvent,
+		UpdateFunc: o.handleClusterMonitoringUpdate,
+		DeleteFunc: o.handleEvent,
+	})
+
+	o.upgradeableChecks = []upgradeableCheck{
+		&deprecatedConfigCheck{
+			configMaps:         o.cmapInf.GetStore(),
+			clusterMonitorings: o.clusterMonitoringInf.GetStore(),
+			configMapKey:       namespace + "/" + configMapName,
+		},
+		&persistentStorageCheck{
+			kclient:   c.KubernetesInterface(),
+			namespace: namespace,
+		},
+	}
+
 	informer = cache.NewSharedIndexInformer(
 		o.client.ConfigMapListWatchForNamespace(namespaceUserWorkload), &v1.ConfigMap{}, resyncPeriod, cache.Indexers{},
 	)
@@ -231,6 +361,37 @@ func New(
 	})
 	o.informers = append(o.informers, informer)
 
+	informer = cache.NewSharedIndexInformer(
+		o.client.NamespaceListWatch(), &v1.Namespace{}, resyncPeriod, cache.Indexers{},
+	)
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { o.handleNamespaceEvent(nil, obj) },
+		UpdateFunc: o.handleNamespaceEvent,
+		DeleteFunc: func(obj interface{}) { o.handleNamespaceEvent(obj, nil) },
+	})
+	o.informers = append(o.informers, informer)
+
+	// The user-defined resources are watched by userWorkloadInfs, which
+	// only run while user workload monitoring is enabled, to report the
+	// namespaces over quota and the rejected resources.
+	o.namespaceStore = informer.GetStore()
+	o.userWorkloadInfs = &userWorkloadInformers{}
+	o.namespaceQuota = &namespaceQuotaChecker{
+		namespaces:         o.namespaceStore,
+		platformNamespaces: platformNamespaces,
+	}
+	o.userWorkloadResources = &userWorkloadResourcesValidator{
+		namespaces:         o.namespaceStore,
+		getSecret:          c.GetSecret,
+		platformNamespaces: platformNamespaces,
+	}
+	o.userWorkloadUpgradeable = &userWorkloadResourcesCheck{
+		namespaces:         o.namespaceStore,
+		platformNamespaces: platformNamespaces,
+	}
+	o.upgradeableChecks = append(o.upgradeableChecks, o.userWorkloadUpgradeable)
+	o.setUserWorkloadStores()
+
 	informer = cache.NewSharedIndexInformer(
 		o.client.InfrastructureListWatchForResource(context.TODO(), clusterResourceName),
 		&configv1.Infrastructure{}, resyncPeriod, cache.Indexers{},
@@ -286,6 +447,11 @@ func (o *Operator) Run(stopc <-chan struct{}) error {
 		return nil
 	}
 
+	// The ClusterMonitoring informer isn't waited for: the operator must
+	// start even if the CRD isn't installed yet, in which case the
+	// configuration is read from the ConfigMap.
+	go o.clusterMonitoringInf.Run(stopc)
+
 	go o.cmapInf.Run(stopc)
 	synced := []cache.InformerSynced{o.cmapInf.HasSynced}
 	for _, inf := range o.informers {
@@ -344,6 +510,12 @@ func (o *Operator) handleEvent(obj interface{}) {
 		return
 	}
 
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		klog.Infof("Triggering update due to a ClusterMonitoring update")
+		o.enqueue(cmoConfigMap)
+		return
+	}
+
 	key, ok := o.keyFunc(obj)
 	if !ok {
 		return
@@ -362,8 +534,12 @@ func (o *Operator) handleEvent(obj interface{}) {
 	case telemeterCABundleConfigMap:
 	case alertmanagerCABundleConfigMap:
 	case grpcTLS:
+	case telemetryConfigMap:
 	case uwmConfigMap:
 	default:
+		if o.isAlertmanagerSecret(key) {
+			break
+		}
 		klog.V(5).Infof("ConfigMap or Secret (%s) not triggering an update.", key)
 		return
 	}
@@ -375,6 +551,118 @@ func (o *Operator) handleEvent(obj interface{}) {
 	o.enqueue(cmoConfigMap)
 }
 
+// handleClusterMonitoringUpdate triggers a reconciliation when the spec or
+// the annotations of the ClusterMonitoring resource change. Updates of the
+// status, which are made by the operator itself, are ignored.
+func (o *Operator) handleClusterMonitoringUpdate(oldObj, newObj interface{}) {
+	oldCM, ok := oldObj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	newCM, ok := newObj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	if oldCM.GetGeneration() == newCM.GetGeneration() &&
+		reflect.DeepEqual(oldCM.GetAnnotations(), newCM.GetAnnotations()) {
+		return
+	}
+	o.handleEvent(newObj)
+}
+
+// handleNamespaceEvent triggers the reconciliation of the Prometheus operators
+// when a namespace starts or stops being watched by the platform operator.
+// The user workload operator only denies the platform namespaces, the other
+// namespaces are filtered by the selectors of the user workload resources.
+func (o *Operator) handleNamespaceEvent(oldObj, newObj interface{}) {
+	if tombstone, ok := oldObj.(cache.DeletedFinalStateUnknown); ok {
+		oldObj = tombstone.Obj
+	}
+	oldNs, _ := oldObj.(*v1.Namespace)
+	newNs, _ := newObj.(*v1.Namespace)
+
+	o.namespacesMtx.RLock()
+	synced := o.namespacesSynced
+	userWorkloadEnabled := o.userWorkloadEnabled
+	alertmanagerConfigNamespaces := o.alertmanagerConfigNamespaces
+	o.namespacesMtx.RUnlock()
+
+	// The first reconciliation takes all the namespaces into account.
+	if !synced {
+		return
+	}
+
+	// The watched user-defined resources depend on the namespaces and their
+	// labels.
+	if userWorkloadEnabled && (oldNs == nil || newNs == nil || !reflect.DeepEqual(oldNs.Labels, newNs.Labels)) {
+		o.enqueue(userWorkloadResourcesKey)
+	}
+
+	changed := namespaceSelected(o.platformNamespaces, oldNs) != namespaceSelected(o.platformNamespaces, newNs)
+	if alertmanagerConfigNamespaces != nil {
+		changed = changed ||
+			namespaceSelected(alertmanagerConfigNamespaces, oldNs) != namespaceSelected(alertmanagerConfigNamespaces, newNs)
+	}
+	if !changed {
+		return
+	}
+
+	ns := newNs
+	if ns == nil {
+		ns = oldNs
+	}
+	klog.Infof("Triggering an update of the Prometheus operators due to namespace %s", ns.Name)
+	o.enqueue(namespacesKey)
+}
+
+// namespaceSelected returns true if the namespace exists and matches the
+// selector.
+func namespaceSelected(selector labels.Selector, ns *v1.Namespace) bool {
+	return ns != nil && selector.Matches(labels.Set(ns.Labels))
+}
+
+// setWatchedNamespaces records the selector of the user namespaces watched by
+// the platform operator.
+func (o *Operator) setWatchedNamespaces(config *manifests.Config) {
+	var alertmanagerConfigSelector labels.Selector
+	if config.AlertmanagerMainUserConfigEnabled() {
+		var err error
+		alertmanagerConfigSelector, err = metav1.LabelSelectorAsSelector(config.AlertmanagerConfigNamespaceSelector())
+		if err != nil {
+			// The selector is validated when loading the configuration.
+			klog.Warningf("invalid AlertmanagerConfig namespace selector: %v", err)
+			return
+		}
+	}
+
+	o.namespacesMtx.Lock()
+	defer o.namespacesMtx.Unlock()
+	o.namespacesSynced = true
+	o.alertmanagerConfigNamespaces = alertmanagerConfigSelector
+}
+
+// setAlertmanagerSecrets records the Secrets referenced by the structured
+// Alertmanager configuration so that their changes trigger a reconciliation.
+func (o *Operator) setAlertmanagerSecrets(config *manifests.Config) {
+	secrets := map[string]struct{}{}
+	for _, name := range manifests.AlertmanagerConfigSecrets(config.ClusterMonitoringConfiguration.AlertmanagerMainConfig.Config) {
+		secrets[o.namespace+"/"+name] = struct{}{}
+	}
+
+	o.alertmanagerSecretsMtx.Lock()
+	defer o.alertmanagerSecretsMtx.Unlock()
+	o.alertmanagerSecrets = secrets
+}
+
+// isAlertmanagerSecret returns true if the key is a Secret referenced by the
+// structured Alertmanager configuration.
+func (o *Operator) isAlertmanagerSecret(key string) bool {
+	o.alertmanagerSecretsMtx.RLock()
+	defer o.alertmanagerSecretsMtx.RUnlock()
+	_, found := o.alertmanagerSecrets[key]
+	return found
+}
+
 func (o *Operator) worker() {
 	for o.processNextWorkItem() {
 	}
@@ -387,6 +675,26 @@ func (o *Operator) processNextWorkItem() bool {
 	}
 	defer o.queue.Done(key)
 
+	var syncFn func() error
+	switch key {
+	case namespacesKey:
+		syncFn = o.syncNamespaces
+	case userWorkloadResourcesKey:
+		syncFn = o.syncUserWorkloadResources
+	case volumesKey:
+		syncFn = o.syncVolumes
+	}
+	if syncFn != nil {
+		if err := syncFn(); err != nil {
+			klog.Errorf("Syncing %q failed", key)
+			utilruntime.HandleError(errors.Wrapf(err, "sync %q failed", key))
+			o.queue.AddRateLimited(key)
+			return true
+		}
+		o.queue.Forget(key)
+		return true
+	}
+
 	o.reconcileAttempts.Inc()
 	err := o.sync(key.(string))
 	if err == nil {
@@ -420,73 +728,503 @@ func (o *Operator) enqueue(obj interface{}) {
 }
 
 func (o *Operator) sync(key string) error {
-	config, err := o.Config(key)
+	config, factory, err := o.validConfig(key)
 	if err != nil {
 		klog.Infof("Updating ClusterOperator status to failed: %v", err)
-		reportErr := o.client.StatusReporter().SetFailed(err, "InvalidConfiguration")
+		o.client.EventRecorder().ReconcileFailed("InvalidConfiguration", err)
+		reportErr := o.client.StatusReporter().SetFailed(err, "InvalidConfiguration", nil)
+		if reportErr != nil {
+			klog.Errorf("error occurred while setting status to failed: %v", reportErr)
+		}
+		return err
+	}
+	o.setWatchedNamespaces(config)
+	o.setUserWorkloadConfig(config)
+	// The upgradeable checks read the caches of the user workload informers
+	// which must match the configuration.
+	if err := o.updateUserWorkloadInformers(); err != nil {
+		klog.Warningf("updating the user workload informers failed: %v", err)
+	}
+
+	tl := tasks.NewTaskRunner(o.client, o.taskSpecs(factory, config))
+
+	o.updateUpgradeable(config)
+
+	klog.Info("Updating ClusterOperator status to in progress.")
+	err = o.client.StatusReporter().SetInProgress()
+	if err != nil {
+		klog.Errorf("error occurred while setting status to in progress: %v", err)
+	}
+	o.client.EventRecorder().ReconcileStarted()
+
+	taskName, err := tl.RunAll()
+	components := componentStatuses(tl.Results())
+	o.updateClusterMonitoringStatus(components)
+	if err != nil {
+		klog.Infof("Updating ClusterOperator status to failed. Err: %v", err)
+		failedTaskReason := strings.Join(strings.Fields(taskName+"Failed"), "")
+		o.client.EventRecorder().ReconcileFailed(failedTaskReason, err)
+		reportErr := o.client.StatusReporter().SetFailed(err, failedTaskReason, components)
 		if reportErr != nil {
 			klog.Errorf("error occurred while setting status to failed: %v", reportErr)
 		}
 		return err
 	}
+
+	operands, err := manifests.OperandVersions(o.assets, o.images)
+	if err != nil {
+		klog.Warningf("error occurred while reading operand versions: %v", err)
+	}
+
+	// The stack has been rolled out even if the rules have problems, hence
+	// the versions are reported in both cases. Retrying wouldn't fix the
+	// rules, the assets only change with a new release. The failed volume
+	// expansions of the last check are kept until the next one.
+	degradedErr, degradedReason := o.rulesLintErr, "PrometheusRulesLintFailed"
+	if degradedErr == nil && o.volumesErr != nil {
+		degradedErr, degradedReason = o.volumesErr, volumeExpansionFailedReason
+	}
+	if degradedErr != nil {
+		klog.Infof("Updating ClusterOperator status to degraded: %v", degradedErr)
+		o.client.EventRecorder().ReconcileFailed(degradedReason, degradedErr)
+		err = o.client.StatusReporter().SetDegraded(degradedErr, degradedReason, operands, components)
+		if err != nil {
+			klog.Errorf("error occurred while setting status to degraded: %v", err)
+		}
+	} else {
+		o.client.EventRecorder().ReconcileSucceeded()
+
+		klog.Info("Updating ClusterOperator status to done.")
+		err = o.client.StatusReporter().SetDone(operands, components)
+		if err != nil {
+			klog.Errorf("error occurred while setting status to done: %v", err)
+		}
+	}
+
+	o.enqueue(volumesKey)
+
+	return nil
+}
+
+// validConfig loads the configuration and returns it with its factory. It
+// returns the first error found by the validation of the configuration.
+func (o *Operator) validConfig(key string) (*manifests.Config, *manifests.Factory, error) {
+	config, err := o.Config(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The referenced Secrets are recorded before the validation so that
+	// creating or rotating them retries a failed reconciliation too.
+	o.setAlertmanagerSecrets(config)
+
+	factory := o.newFactory(config)
+	if err := factory.ValidateAlertOverrides(); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid alert overrides")
+	}
+	if err := factory.ValidateReplicas(); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid replicas")
+	}
+
+	return config, factory, nil
+}
+
+// taskSpecs returns the tasks reconciling the monitoring stack. Only the
+// failures of the core paths, which are the platform Prometheus with its
+// operator, Alertmanager, the metrics API and Thanos Querier, make the stack
+// unavailable. The failures of the other tasks, including all the user
+// workload monitoring ones, only degrade it.
+func (o *Operator) taskSpecs(factory *manifests.Factory, config *manifests.Config) []*tasks.TaskSpec {
+	return []*tasks.TaskSpec{
+		tasks.NewTaskSpec("Updating Prometheus Operator", tasks.NewPrometheusOperatorTask(o.client, factory, config)),
+		tasks.NewOptionalTaskSpec("Updating user workload Prometheus Operator", tasks.NewPrometheusOperatorUserWorkloadTask(o.client, factory, config)),
+		tasks.NewOptionalTaskSpec("Updating Cluster Monitoring Operator", tasks.NewClusterMonitoringOperatorTask(o.client, factory)),
+		tasks.NewOptionalTaskSpec("Updating Grafana", tasks.NewGrafanaTask(o.client, factory)),
+		tasks.NewTaskSpec("Updating Prometheus-k8s", tasks.NewPrometheusTask(o.client, factory)),
+		tasks.NewOptionalTaskSpec("Updating User Workload Alertmanager", tasks.NewAlertmanagerUserWorkloadTask(o.client, factory, config)),
+		tasks.NewOptionalTaskSpec("Updating Prometheus-user-workload", tasks.NewPrometheusUserWorkloadTask(o.client, factory, config)),
+		tasks.NewTaskSpec("Updating Alertmanager", tasks.NewAlertmanagerTask(o.client, factory, config)),
+		tasks.NewOptionalTaskSpec("Updating node-exporter", tasks.NewNodeExporterTask(o.client, factory)),
+		tasks.NewOptionalTaskSpec("Updating kube-state-metrics", tasks.NewKubeStateMetricsTask(o.client, factory)),
+		tasks.NewOptionalTaskSpec("Updating openshift-state-metrics", tasks.NewOpenShiftStateMetricsTask(o.client, factory)),
+		tasks.NewTaskSpec("Updating prometheus-adapter", tasks.NewPrometheusAdapterTaks(o.namespace, o.client, factory)),
+		tasks.NewOptionalTaskSpec("Updating Telemeter client", tasks.NewTelemeterClientTask(o.client, factory, config)),
+		tasks.NewOptionalTaskSpec("Updating configuration sharing", tasks.NewConfigSharingTask(o.client, factory)),
+		tasks.NewTaskSpec("Updating Thanos Querier", tasks.NewThanosQuerierTask(o.client, factory, config)),
+		tasks.NewOptionalTaskSpec("Updating User Workload Thanos Ruler", tasks.NewThanosRulerUserWorkloadTask(o.client, factory, config)),
+		tasks.NewOptionalTaskSpec("Updating Control Plane components", tasks.NewControlPlaneTask(o.client, factory, config)),
+	}
+}
+
+// syncVolumes checks the expansion of the persistent volumes of the
+// monitoring components. The volumes which can't be expanded or whose
+// expansion doesn't complete within volumeResizeTimeout are reported as
+// degraded. The check is repeated while volumes are being expanded.
+func (o *Operator) syncVolumes() error {
+	expansions, err := o.client.PendingVolumeExpansions(o.namespace, o.namespaceUserWorkload)
+	if err != nil {
+		return errors.Wrap(err, "checking the persistent volume expansions failed")
+	}
+
+	if o.resizingVolumes == nil {
+		o.resizingVolumes = make(map[string]time.Time)
+	}
+	resizing, volumesErr := volumeExpansionStatus(expansions, o.resizingVolumes, time.Now())
+	if len(resizing) > 0 {
+		klog.Infof("Expanding persistent volume claims: %s", strings.Join(resizing, ", "))
+		o.queue.AddAfter(volumesKey, volumeResizeCheckPeriod)
+	}
+
+	if fmt.Sprint(volumesErr) == fmt.Sprint(o.volumesErr) {
+		return nil
+	}
+
+	if volumesErr != nil {
+		klog.Infof("Updating ClusterOperator status to degraded: %v", volumesErr)
+		err = o.client.StatusReporter().SetDegradedCondition(volumesErr, volumeExpansionFailedReason)
+	} else {
+		err = o.client.StatusReporter().ClearDegradedCondition(volumeExpansionFailedReason)
+	}
+	if err != nil {
+		return errors.Wrap(err, "updating ClusterOperator status failed")
+	}
+
+	o.volumesErr = volumesErr
+	return nil
+}
+
+// volumeExpansionStatus returns the claims being expanded and an error
+// listing the failed expansions. The expansions which can't happen fail
+// immediately, the others after volumeResizeTimeout. since holds when each
+// expansion was first seen, it is updated with the current expansions.
+func volumeExpansionStatus(expansions []client.VolumeExpansion, since map[string]time.Time, now time.Time) ([]string, error) {
+	var (
+		resizing, failed []string
+		current          = make(map[string]struct{})
+	)
+	for _, e := range expansions {
+		current[e.Claim] = struct{}{}
+		if e.Blocked != "" {
+			failed = append(failed, fmt.Sprintf("%s: %s", e.Claim, e.Blocked))
+			continue
+		}
+
+		start, found := since[e.Claim]
+		if !found {
+			start = now
+			since[e.Claim] = now
+		}
+		if now.Sub(start) >= volumeResizeTimeout {
+			failed = append(failed, fmt.Sprintf("%s: not expanded after %s, check the events of the claim", e.Claim, volumeResizeTimeout))
+			continue
+		}
+		resizing = append(resizing, e.Claim)
+	}
+
+	for claim := range since {
+		if _, found := current[claim]; !found {
+			delete(since, claim)
+		}
+	}
+
+	if len(failed) > 0 {
+		return resizing, errors.Errorf("expanding persistent volume claims failed: %s", strings.Join(failed, ", "))
+	}
+	return resizing, nil
+}
+
+// loadTelemetryMatches updates the telemetry matches from the telemetry
+// ConfigMap. The current matches are kept when the ConfigMap doesn't exist or
+// isn't valid.
+func (o *Operator) loadTelemetryMatches() {
+	obj, exists, err := o.cmapInf.GetStore().GetByKey(telemetryConfigMap)
+	if err != nil {
+		klog.Warningf("reading telemetry ConfigMap failed, using the current matches: %v", err)
+		return
+	}
+	if !exists {
+		return
+	}
+
+	cm, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		return
+	}
+
+	tc, err := manifests.NewTelemetryConfig(strings.NewReader(cm.Data[telemetryConfigKey]))
+	if err != nil {
+		klog.Warningf("invalid telemetry configuration in %s, using the current matches: %v", telemetryConfigMap, err)
+		return
+	}
+
+	if !reflect.DeepEqual(o.telemetryMatches, tc.Matches) {
+		klog.Infof("Telemetry matches updated from %s", telemetryConfigMap)
+		for _, m := range tc.Matches {
+			klog.V(4).Info(m)
+		}
+	}
+	o.telemetryMatches = tc.Matches
+}
+
+// newFactory completes the configuration with the operator settings and
+// returns the manifests factory.
+func (o *Operator) newFactory(config *manifests.Config) *manifests.Factory {
 	config.SetImages(o.images)
+	o.loadTelemetryMatches()
 	config.SetTelemetryMatches(o.telemetryMatches)
 	config.SetRemoteWrite(o.remoteWrite)
+	o.setTelemetryConfig(config)
 
 	var proxyConfig manifests.ProxyReader
-	proxyConfig, err = o.loadProxyConfig()
+	proxyConfig, err := o.loadProxyConfig()
 	if err != nil {
 		klog.Warningf("using proxy config from CMO configmap: %v", err)
 		proxyConfig = config
 	}
-	factory := manifests.NewFactory(o.namespace, o.namespaceUserWorkload, config, o.loadInfrastructureConfig(), proxyConfig, o.assets)
+	return manifests.NewFactory(o.namespace, o.namespaceUserWorkload, config, o.loadInfrastructureConfig(), proxyConfig, o.assets)
+}
+
+// syncNamespaces updates the Prometheus operators after namespace changes.
+// The other components and the ClusterOperator status are left to the full
+// reconciliation.
+func (o *Operator) syncNamespaces() error {
+	config, err := o.Config(o.namespace + "/" + o.configMapName)
+	if err != nil {
+		return err
+	}
+	factory := o.newFactory(config)
+	o.setWatchedNamespaces(config)
 
 	tl := tasks.NewTaskRunner(
 		o.client,
 		[]*tasks.TaskSpec{
-			tasks.NewTaskSpec("Updating Prometheus Operator", tasks.NewPrometheusOperatorTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating user workload Prometheus Operator", tasks.NewPrometheusOperatorUserWorkloadTask(o.client, factory, config)),
-			tasks.NewTaskSpec("Updating Cluster Monitoring Operator", tasks.NewClusterMonitoringOperatorTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating Grafana", tasks.NewGrafanaTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating Prometheus-k8s", tasks.NewPrometheusTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating Prometheus-user-workload", tasks.NewPrometheusUserWorkloadTask(o.client, factory, config)),
-			tasks.NewTaskSpec("Updating Alertmanager", tasks.NewAlertmanagerTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating node-exporter", tasks.NewNodeExporterTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating kube-state-metrics", tasks.NewKubeStateMetricsTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating openshift-state-metrics", tasks.NewOpenShiftStateMetricsTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating prometheus-adapter", tasks.NewPrometheusAdapterTaks(o.namespace, o.client, factory)),
-			tasks.NewTaskSpec("Updating Telemeter client", tasks.NewTelemeterClientTask(o.client, factory, config)),
-			tasks.NewTaskSpec("Updating configuration sharing", tasks.NewConfigSharingTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating Thanos Querier", tasks.NewThanosQuerierTask(o.client, factory, config)),
-			tasks.NewTaskSpec("Updating User Workload Thanos Ruler", tasks.NewThanosRulerUserWorkloadTask(o.client, factory, config)),
-			tasks.NewTaskSpec("Updating Control Plane components", tasks.NewControlPlaneTask(o.client, factory, config)),
+			tasks.NewTaskSpec("Updating Prometheus Operator", tasks.NewPrometheusOperatorTask(o.client, factory, config)),
+			tasks.NewOptionalTaskSpec("Updating user workload Prometheus Operator", tasks.NewPrometheusOperatorUserWorkloadTask(o.client, factory, config)),
 		},
 	)
+	_, err = tl.RunAll()
+	return err
+}
 
-	klog.Info("Updating ClusterOperator status to in progress.")
-	err = o.client.StatusReporter().SetInProgress()
+// setUserWorkloadConfig records the configuration used to check the
+// user-defined resources and triggers the checks.
+func (o *Operator) setUserWorkloadConfig(config *manifests.Config) {
+	if err := o.namespaceQuota.setConfig(config); err != nil {
+		// The selector is validated when loading the configuration.
+		klog.Warningf("invalid user workload namespace selector: %v", err)
+		return
+	}
+	o.userWorkloadConfig = config
+
+	o.namespacesMtx.Lock()
+	o.userWorkloadEnabled = *config.ClusterMonitoringConfiguration.UserWorkloadEnabled
+	o.namespacesMtx.Unlock()
+
+	o.enqueue(userWorkloadResourcesKey)
+}
+
+// syncUserWorkloadResources reports the namespaces over quota and the
+// user-defined resources rejected by user workload monitoring. Both only use
+// informer caches and run outside of the reconciliation of the stack.
+func (o *Operator) syncUserWorkloadResources() error {
+	// The reconciliation triggers the checks once the configuration is
+	// known.
+	if o.userWorkloadConfig == nil {
+		return nil
+	}
+	if err := o.updateUserWorkloadInformers(); err != nil {
+		return err
+	}
+	if !o.userWorkloadInfs.hasSynced() {
+		return errors.New("user workload resources informers not synced yet")
+	}
+	if o.upgradeablePending {
+		o.updateUpgradeable(o.userWorkloadConfig)
+	}
+
+	overQuota := o.namespaceQuota.check()
+	o.reportNamespacesOverQuota(overQuota)
+
+	// The owners of the namespaces over quota already get an event.
+	o.reportRejectedUserWorkloadResources(overQuota)
+
+	return nil
+}
+
+// updateUserWorkloadInformers starts the informers of the user-defined
+// resources when user workload monitoring gets enabled, stops them when it
+// gets disabled and restarts them when the monitored namespaces change.
+func (o *Operator) updateUserWorkloadInformers() error {
+	namespaces, err := userWorkloadNamespaces(o.userWorkloadConfig, o.namespaceStore, o.platformNamespaces)
 	if err != nil {
-		klog.Errorf("error occurred while setting status to in progress: %v", err)
+		return errors.Wrap(err, "listing the user workload namespaces failed")
+	}
+	if reflect.DeepEqual(namespaces, o.userWorkloadInfs.namespaces) {
+		return nil
 	}
 
-	taskName, err := tl.RunAll()
+	klog.V(4).Infof("Watching the user-defined resources of the namespaces %v", namespaces)
+	o.userWorkloadInfs.stop()
+	o.userWorkloadInfs = newUserWorkloadInformers(o.client, namespaces, cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { o.enqueue(userWorkloadResourcesKey) },
+		UpdateFunc: func(interface{}, interface{}) { o.enqueue(userWorkloadResourcesKey) },
+		DeleteFunc: func(interface{}) { o.enqueue(userWorkloadResourcesKey) },
+	})
+	o.userWorkloadInfs.start()
+	o.setUserWorkloadStores()
+
+	return nil
+}
+
+// setUserWorkloadStores makes the checks read the caches of the current
+// user workload informers.
+func (o *Operator) setUserWorkloadStores() {
+	o.namespaceQuota.serviceMonitors = o.userWorkloadInfs.serviceMonitors
+	o.namespaceQuota.podMonitors = o.userWorkloadInfs.podMonitors
+	o.userWorkloadResources.serviceMonitors = o.userWorkloadInfs.serviceMonitors
+	o.userWorkloadResources.podMonitors = o.userWorkloadInfs.podMonitors
+	o.userWorkloadResources.probes = o.userWorkloadInfs.probes
+	o.userWorkloadResources.prometheusRules = o.userWorkloadInfs.prometheusRules
+	o.userWorkloadUpgradeable.serviceMonitors = o.userWorkloadInfs.serviceMonitors
+	o.userWorkloadUpgradeable.hasSynced = o.userWorkloadInfs.hasSynced
+}
+
+// reportNamespacesOverQuota records an event on the namespaces which become
+// over quota and the list of these namespaces in the status of the
+// ClusterMonitoring resource.
+func (o *Operator) reportNamespacesOverQuota(overQuota map[string]string) {
+	if o.namespacesOverQuota != nil && reflect.DeepEqual(o.namespacesOverQuota, overQuota) {
+		return
+	}
+
+	namespaces := make([]string, 0, len(overQuota))
+	for ns, msg := range overQuota {
+		namespaces = append(namespaces, ns)
+		if o.namespacesOverQuota[ns] == msg {
+			continue
+		}
+		klog.Warningf("namespace %s: %s", ns, msg)
+		o.client.EventRecorder().NamespaceQuotaExceeded(ns, msg)
+	}
+	sort.Strings(namespaces)
+
+	if o.observedGeneration != 0 {
+		cond := metav1.Condition{
+			Type:               namespaceQuotaConditionType,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: o.observedGeneration,
+			LastTransitionTime: metav1.Now(),
+			Reason:             "NoNamespaceOverQuota",
+		}
+		if len(namespaces) > 0 {
+			cond.Status = metav1.ConditionTrue
+			cond.Reason = "NamespacesOverQuota"
+			cond.Message = fmt.Sprintf("Namespaces over their user workload monitoring quota: %s", strings.Join(namespaces, ", "))
+		}
+		if err := o.client.UpdateClusterMonitoringStatus(o.observedGeneration, []metav1.Condition{cond}); err != nil {
+			klog.Errorf("error occurred while updating the ClusterMonitoring status: %v", err)
+			return
+		}
+	}
+
+	o.namespacesOverQuota = overQuota
+}
+
+// reportRejectedUserWorkloadResources records an event on each user-defined
+// resource newly ignored by user workload monitoring or ignored for a new
+// reason. The resources of the skipped namespaces aren't checked.
+func (o *Operator) reportRejectedUserWorkloadResources(skip map[string]string) {
+	rejected, err := o.userWorkloadResources.rejected(o.userWorkloadConfig, skip)
 	if err != nil {
-		klog.Infof("Updating ClusterOperator status to failed. Err: %v", err)
-		failedTaskReason := strings.Join(strings.Fields(taskName+"Failed"), "")
-		reportErr := o.client.StatusReporter().SetFailed(err, failedTaskReason)
-		if reportErr != nil {
-			klog.Errorf("error occurred while setting status to failed: %v", reportErr)
+		klog.Warningf("error occurred while validating user workload resources: %v", err)
+		return
+	}
+
+	reported := make(map[string]string, len(rejected))
+	for _, r := range rejected {
+		key := r.kind + "/" + r.obj.GetNamespace() + "/" + r.obj.GetName()
+		reported[key] = r.reason
+		if prev, found := o.rejectedResources[key]; found && prev == r.reason {
+			continue
 		}
-		return err
+		klog.V(4).Infof("rejected user workload resource: %s", r)
+		o.client.EventRecorder().UserWorkloadResourceRejected(r.kind, r.obj, r.reason)
+	}
+	o.rejectedResources = reported
+}
+
+func (o *Operator) setTelemetryConfig(config *manifests.Config) {
+	o.telemetryMtx.Lock()
+	defer o.telemetryMtx.Unlock()
+
+	o.telemetryConfig = &telemetry.Config{
+		Matches:   config.ClusterMonitoringConfiguration.PrometheusK8sConfig.TelemetryMatches,
+		ClusterID: config.ClusterMonitoringConfiguration.TelemeterClientConfig.ClusterID,
+		Enabled:   config.ClusterMonitoringConfiguration.TelemeterClientConfig.IsEnabled(),
+	}
+}
+
+// TelemetryConfig returns the telemetry configuration of the last
+// reconciliation and false if there was none yet.
+func (o *Operator) TelemetryConfig() (telemetry.Config, bool) {
+	o.telemetryMtx.RLock()
+	defer o.telemetryMtx.RUnlock()
+
+	if o.telemetryConfig == nil {
+		return telemetry.Config{}, false
 	}
+	return *o.telemetryConfig, true
+}
 
-	klog.Info("Updating ClusterOperator status to done.")
-	err = o.client.StatusReporter().SetDone()
+// LintRules checks the PrometheusRule assets shipped with the operator. The
+// errors are reported in the Degraded condition after each reconciliation.
+func (o *Operator) LintRules() {
+	problems, err := rules.LintAssets(o.assets, strings.Join(o.telemetryMatches, "\n"))
 	if err != nil {
-		klog.Errorf("error occurred while setting status to done: %v", err)
+		o.rulesLintErr = errors.Wrap(err, "linting PrometheusRule assets failed")
+		return
 	}
 
-	return nil
+	for _, p := range problems.Warnings() {
+		klog.V(4).Infof("PrometheusRule lint warning: %s", p)
+	}
+
+	if errs := problems.Errors(); len(errs) > 0 {
+		o.rulesLintErr = errs
+	}
+}
+
+// updateUpgradeable evaluates the upgradeable checks and reports the outcome in
+// the Upgradeable condition of the ClusterOperator.
+func (o *Operator) updateUpgradeable(config *manifests.Config) {
+	cond, message, reason := runUpgradeableChecks(context.TODO(), o.upgradeableChecks, config)
+	o.upgradeablePending = !o.userWorkloadInfs.hasSynced()
+	if cond != configv1.ConditionTrue {
+		klog.Infof("Updating ClusterOperator upgradeable status to %s: %s", cond, message)
+	}
+
+	if err := o.client.StatusReporter().SetUpgradeable(cond, message, reason); err != nil {
+		klog.Errorf("error occurred while setting upgradeable status: %v", err)
+	}
+}
+
+// componentStatuses converts the task results into the component statuses
+// reported in the ClusterOperator status.
+func componentStatuses(results []tasks.TaskResult) []client.ComponentStatus {
+	components := make([]client.ComponentStatus, 0, len(results))
+	for _, r := range results {
+		cs := client.ComponentStatus{
+			Name:     r.Name,
+			Healthy:  r.Err == nil,
+			Critical: r.Criticality == tasks.CriticalTask,
+		}
+		if r.Err != nil {
+			cs.Message = r.Err.Error()
+		}
+		components = append(components, cs)
+	}
+
+	return components
 }
 
 func (o *Operator) loadInfrastructureConfig() *InfrastructureConfig {
@@ -560,7 +1298,35 @@ func (o *Operator) loadUserWorkloadConfig() (*manifests.UserWorkloadConfiguratio
 	return uwc, nil
 }
 
+// loadConfig returns the configuration of the monitoring stack. The
+// ClusterMonitoring resource is preferred over the legacy ConfigMap unless it
+// has been converted from the ConfigMap, in which case the ConfigMap remains
+// the source of truth and the resource is kept in sync.
 func (o *Operator) loadConfig(key string) (*manifests.Config, error) {
+	o.observedGeneration = 0
+
+	// The ClusterMonitoring resource is only considered once its informer
+	// has synced, it never does if the CRD isn't installed.
+	crdAvailable := o.clusterMonitoringInf.HasSynced()
+	var cm *unstructured.Unstructured
+	if crdAvailable {
+		obj, found, err := o.clusterMonitoringInf.GetStore().GetByKey(manifests.ClusterMonitoringName)
+		if err != nil {
+			return nil, errors.Wrap(err, "an error occurred when retrieving the ClusterMonitoring resource")
+		}
+		if found {
+			cm = obj.(*unstructured.Unstructured)
+		}
+	}
+	if cm != nil && !manifests.IsConvertedFromConfigMap(cm) {
+		c, err := manifests.NewConfigFromClusterMonitoring(cm)
+		if err != nil {
+			return nil, errors.Wrap(err, "the ClusterMonitoring resource could not be parsed")
+		}
+		o.observedGeneration = cm.GetGeneration()
+		return c, nil
+	}
+
 	obj, found, err := o.cmapInf.GetStore().GetByKey(key)
 	if err != nil {
 		return nil, errors.Wrap(err, "an error occurred when retrieving the Cluster Monitoring ConfigMap")
@@ -568,6 +1334,9 @@ func (o *Operator) loadConfig(key string) (*manifests.Config, error) {
 
 	if !found {
 		klog.Warning("No Cluster Monitoring ConfigMap was found. Using defaults.")
+		if crdAvailable {
+			o.convertConfigMap("", "", cm)
+		}
 		return manifests.NewDefaultConfig(), nil
 	}
 
@@ -582,10 +1351,73 @@ func (o *Operator) loadConfig(key string) (*manifests.Config, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "the Cluster Monitoring ConfigMap could not be parsed")
 	}
+	if crdAvailable {
+		o.convertConfigMap(configContent, cmap.GetResourceVersion(), cm)
+	}
 
 	return cParsed, nil
 }
 
+// convertConfigMap mirrors the content of the legacy Cluster Monitoring
+// ConfigMap into the ClusterMonitoring resource. The conversion only happens
+// when the ConfigMap has changed since the last conversion or when the
+// resource is missing. Failures don't prevent the reconciliation since the
+// ConfigMap is the source of truth.
+func (o *Operator) convertConfigMap(content, resourceVersion string, existing *unstructured.Unstructured) {
+	if existing != nil && o.convertedConfigMapVersion != nil && *o.convertedConfigMapVersion == resourceVersion {
+		return
+	}
+
+	cm, err := manifests.NewClusterMonitoringFromConfigMap(content)
+	if err != nil {
+		klog.Warningf("failed to convert the Cluster Monitoring ConfigMap: %v", err)
+		return
+	}
+
+	if err := o.client.CreateOrUpdateClusterMonitoring(cm); err != nil {
+		klog.Warningf("failed to convert the Cluster Monitoring ConfigMap: %v", err)
+		return
+	}
+	o.convertedConfigMapVersion = &resourceVersion
+}
+
+// updateClusterMonitoringStatus reports the outcome of the reconciliation in
+// the status of the ClusterMonitoring resource when it is the source of the
+// configuration.
+func (o *Operator) updateClusterMonitoringStatus(components []client.ComponentStatus) {
+	if o.observedGeneration == 0 {
+		return
+	}
+
+	conditions := make([]metav1.Condition, 0, len(components))
+	for _, c := range components {
+		cond := metav1.Condition{
+			Type:               componentConditionType(c.Name),
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: o.observedGeneration,
+			LastTransitionTime: metav1.Now(),
+			Reason:             "RolloutDone",
+			Message:            "",
+		}
+		if !c.Healthy {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = "RolloutFailed"
+			cond.Message = c.Message
+		}
+		conditions = append(conditions, cond)
+	}
+
+	if err := o.client.UpdateClusterMonitoringStatus(o.observedGeneration, conditions); err != nil {
+		klog.Errorf("error occurred while updating the ClusterMonitoring status: %v", err)
+	}
+}
+
+// componentConditionType returns the condition type of a component from its
+// task name, e.g. "Updating Grafana" becomes "GrafanaAvailable".
+func componentConditionType(name string) string {
+	return cmostrings.ToPascalCase(strings.TrimPrefix(name, "Updating ")) + "Available"
+}
+
 func (o *Operator) Config(key string) (*manifests.Config, error) {
 	c, err := o.loadConfig(key)
 	if err != nil {
@@ -601,6 +1433,10 @@ func (o *Operator) Config(key string) (*manifests.Config, error) {
 		if err != nil {
 			return nil, err
 		}
+
+		if err := c.ValidateUserWorkloadLimits(); err != nil {
+			return nil, errors.Wrap(err, "the User Workload Configuration exceeds the limits of the Cluster Monitoring Configuration")
+		}
 	}
 
 	// Only fetch the token and cluster ID if they have not been specified in the config.
@@ -15,10 +15,23 @@
 package operator
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/openshift/cluster-monitoring-operator/pkg/tasks"
 )
 
 func TestNewInfrastructureConfig(t *testing.T) {
@@ -149,3 +162,310 @@ func TestNewProxyConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleNamespaceEvent(t *testing.T) {
+	namespace := func(lbls map[string]string) *v1.Namespace {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: lbls}}
+	}
+	platform := map[string]string{"openshift.io/cluster-monitoring": "true"}
+	optOut := map[string]string{"openshift.io/user-monitoring": "false"}
+
+	for _, tc := range []struct {
+		name               string
+		synced             bool
+		userWorkload       bool
+		alertmanagerConfig labels.Selector
+		oldObj             interface{}
+		newObj             interface{}
+		expectEnqueue      bool
+	}{
+		{
+			name:   "first reconciliation not done",
+			oldObj: namespace(nil),
+			newObj: namespace(platform),
+		},
+		{
+			name:          "platform namespace added",
+			synced:        true,
+			newObj:        namespace(platform),
+			expectEnqueue: true,
+		},
+		{
+			name:   "user namespace added",
+			synced: true,
+			newObj: namespace(nil),
+		},
+		{
+			name:          "platform label added",
+			synced:        true,
+			oldObj:        namespace(nil),
+			newObj:        namespace(platform),
+			expectEnqueue: true,
+		},
+		{
+			name:          "platform namespace deleted",
+			synced:        true,
+			oldObj:        cache.DeletedFinalStateUnknown{Key: "foo", Obj: namespace(platform)},
+			expectEnqueue: true,
+		},
+		{
+			name:   "unrelated label changed",
+			synced: true,
+			oldObj: namespace(map[string]string{"a": "b"}),
+			newObj: namespace(map[string]string{"a": "c"}),
+		},
+		{
+			name:   "user workload opt-out",
+			synced: true,
+			oldObj: namespace(map[string]string{"team": "a"}),
+			newObj: namespace(map[string]string{"team": "a", "openshift.io/user-monitoring": "false"}),
+		},
+		{
+			name:               "user namespace added with AlertmanagerConfig processing",
+			synced:             true,
+			alertmanagerConfig: mustParseSelector(t, "openshift.io/cluster-monitoring notin (true)"),
+			newObj:             namespace(nil),
+			expectEnqueue:      true,
+		},
+		{
+			name:   "user workload opt-out with AlertmanagerConfig processing disabled",
+			synced: true,
+			oldObj: namespace(nil),
+			newObj: namespace(optOut),
+		},
+		{
+			name:          "user workload opt-out with user workload enabled",
+			synced:        true,
+			userWorkload:  true,
+			oldObj:        namespace(nil),
+			newObj:        namespace(optOut),
+			expectEnqueue: true,
+		},
+		{
+			name:          "user namespace added with user workload enabled",
+			synced:        true,
+			userWorkload:  true,
+			newObj:        namespace(nil),
+			expectEnqueue: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &Operator{
+				queue:                        workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+				platformNamespaces:           labels.SelectorFromSet(labels.Set(platform)),
+				namespacesSynced:             tc.synced,
+				userWorkloadEnabled:          tc.userWorkload,
+				alertmanagerConfigNamespaces: tc.alertmanagerConfig,
+			}
+
+			o.handleNamespaceEvent(tc.oldObj, tc.newObj)
+
+			if enqueued := o.queue.Len() > 0; enqueued != tc.expectEnqueue {
+				t.Fatalf("expected enqueue: %v, got %v", tc.expectEnqueue, enqueued)
+			}
+		})
+	}
+}
+
+func mustParseSelector(t *testing.T, s string) labels.Selector {
+	t.Helper()
+
+	selector, err := labels.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return selector
+}
+
+func TestTaskSpecsCriticality(t *testing.T) {
+	expected := map[string]tasks.Criticality{
+		"Updating Prometheus Operator":               tasks.CriticalTask,
+		"Updating user workload Prometheus Operator": tasks.OptionalTask,
+		"Updating Cluster Monitoring Operator":       tasks.OptionalTask,
+		"Updating Grafana":                           tasks.OptionalTask,
+		"Updating Prometheus-k8s":                    tasks.CriticalTask,
+		"Updating User Workload Alertmanager":        tasks.OptionalTask,
+		"Updating Prometheus-user-workload":          tasks.OptionalTask,
+		"Updating Alertmanager":                      tasks.CriticalTask,
+		"Updating node-exporter":                     tasks.OptionalTask,
+		"Updating kube-state-metrics":                tasks.OptionalTask,
+		"Updating openshift-state-metrics":           tasks.OptionalTask,
+		"Updating prometheus-adapter":                tasks.CriticalTask,
+		"Updating Telemeter client":                  tasks.OptionalTask,
+		"Updating configuration sharing":             tasks.OptionalTask,
+		"Updating Thanos Querier":                    tasks.CriticalTask,
+		"Updating User Workload Thanos Ruler":        tasks.OptionalTask,
+		"Updating Control Plane components":          tasks.OptionalTask,
+	}
+
+	o := &Operator{}
+	specs := o.taskSpecs(nil, manifests.NewDefaultConfig())
+	if len(specs) != len(expected) {
+		t.Fatalf("expected %d tasks, got %d", len(expected), len(specs))
+	}
+	for _, ts := range specs {
+		criticality, found := expected[ts.Name]
+		if !found {
+			t.Errorf("unexpected task %q", ts.Name)
+			continue
+		}
+		if ts.Criticality != criticality {
+			t.Errorf("task %q: expected criticality %v, got %v", ts.Name, criticality, ts.Criticality)
+		}
+	}
+}
+
+func TestHandleClusterMonitoringUpdate(t *testing.T) {
+	clusterMonitoring := func(generation int64, annotations map[string]string, status string) *unstructured.Unstructured {
+		cm := &unstructured.Unstructured{Object: map[string]interface{}{"status": map[string]interface{}{"foo": status}}}
+		cm.SetName(manifests.ClusterMonitoringName)
+		cm.SetGeneration(generation)
+		cm.SetAnnotations(annotations)
+		return cm
+	}
+
+	for _, tc := range []struct {
+		name          string
+		oldObj        interface{}
+		newObj        interface{}
+		expectEnqueue bool
+	}{
+		{
+			name:   "status updated",
+			oldObj: clusterMonitoring(1, nil, "a"),
+			newObj: clusterMonitoring(1, nil, "b"),
+		},
+		{
+			name:          "spec updated",
+			oldObj:        clusterMonitoring(1, nil, "a"),
+			newObj:        clusterMonitoring(2, nil, "a"),
+			expectEnqueue: true,
+		},
+		{
+			name:          "conversion annotation removed",
+			oldObj:        clusterMonitoring(1, map[string]string{manifests.ConvertedFromConfigMapAnnotation: "true"}, "a"),
+			newObj:        clusterMonitoring(1, nil, "a"),
+			expectEnqueue: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &Operator{
+				namespace:     "openshift-monitoring",
+				configMapName: "cluster-monitoring-config",
+				queue:         workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+			}
+
+			o.handleClusterMonitoringUpdate(tc.oldObj, tc.newObj)
+
+			if enqueued := o.queue.Len() > 0; enqueued != tc.expectEnqueue {
+				t.Fatalf("expected enqueue: %v, got %v", tc.expectEnqueue, enqueued)
+			}
+		})
+	}
+}
+
+func TestHandleAlertmanagerSecretEvent(t *testing.T) {
+	c, err := manifests.NewConfigFromString(`alertmanagerMain:
+  config:
+    route:
+      receiver: default
+    receivers:
+    - name: default
+      slackConfigs:
+      - channel: '#alerts'
+        apiURL:
+          name: alertmanager-credentials
+          key: slack
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := func(namespace, name string) *v1.Secret {
+		return &v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+
+	for _, tc := range []struct {
+		name          string
+		obj           interface{}
+		expectEnqueue bool
+	}{
+		{
+			name:          "referenced secret",
+			obj:           secret("openshift-monitoring", "alertmanager-credentials"),
+			expectEnqueue: true,
+		},
+		{
+			name:          "referenced secret deleted",
+			obj:           cache.DeletedFinalStateUnknown{Key: "openshift-monitoring/alertmanager-credentials", Obj: secret("openshift-monitoring", "alertmanager-credentials")},
+			expectEnqueue: true,
+		},
+		{
+			name: "unrelated secret",
+			obj:  secret("openshift-monitoring", "foo"),
+		},
+		{
+			name: "secret in another namespace",
+			obj:  secret("default", "alertmanager-credentials"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &Operator{
+				namespace:     "openshift-monitoring",
+				configMapName: "cluster-monitoring-config",
+				queue:         workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+			}
+			o.setAlertmanagerSecrets(c)
+
+			o.handleEvent(tc.obj)
+
+			if enqueued := o.queue.Len() > 0; enqueued != tc.expectEnqueue {
+				t.Fatalf("expected enqueue: %v, got %v", tc.expectEnqueue, enqueued)
+			}
+		})
+	}
+}
+
+func TestVolumeExpansionStatus(t *testing.T) {
+	now := time.Now()
+	since := map[string]time.Time{
+		"openshift-monitoring/stuck":    now.Add(-volumeResizeTimeout),
+		"openshift-monitoring/resizing": now.Add(-time.Minute),
+		"openshift-monitoring/resized":  now.Add(-time.Minute),
+	}
+
+	resizing, err := volumeExpansionStatus([]client.VolumeExpansion{
+		{Claim: "openshift-monitoring/blocked", Blocked: "no storage class"},
+		{Claim: "openshift-monitoring/new"},
+		{Claim: "openshift-monitoring/resizing"},
+		{Claim: "openshift-monitoring/stuck"},
+	}, since, now)
+
+	expected := []string{"openshift-monitoring/new", "openshift-monitoring/resizing"}
+	if !reflect.DeepEqual(expected, resizing) {
+		t.Errorf("expected resizing claims %v, got %v", expected, resizing)
+	}
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, claim := range []string{"openshift-monitoring/blocked", "openshift-monitoring/stuck"} {
+		if !strings.Contains(err.Error(), claim) {
+			t.Errorf("expected %q in the error, got %v", claim, err)
+		}
+	}
+
+	expectedSince := map[string]time.Time{
+		"openshift-monitoring/new":      now,
+		"openshift-monitoring/resizing": now.Add(-time.Minute),
+		"openshift-monitoring/stuck":    now.Add(-volumeResizeTimeout),
+	}
+	if !reflect.DeepEqual(expectedSince, since) {
+		t.Errorf("expected since %v, got %v", expectedSince, since)
+	}
+
+	resizing, err = volumeExpansionStatus(nil, since, now)
+	if len(resizing) != 0 || err != nil || len(since) != 0 {
+		t.Errorf("expected no expansion, got resizing %v, error %v and since %v", resizing, err, since)
+	}
+}
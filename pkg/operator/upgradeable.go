@@ -0,0 +1,264 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+// deprecatedConfigFields lists the top-level keys of the Cluster Monitoring
+// ConfigMap which are no longer honored by the operator.
+var deprecatedConfigFields = []string{
+	"etcd",
+	"techPreviewUserWorkload",
+}
+
+// upgradeBlocker explains why the monitoring stack shouldn't be upgraded.
+type upgradeBlocker struct {
+	reason  string
+	message string
+}
+
+// upgradeableCheck inspects the cluster and the operator's configuration
+// before an upgrade. It returns a non-nil upgradeBlocker when upgrading is
+// deemed risky.
+type upgradeableCheck interface {
+	check(ctx context.Context, c *manifests.Config) (*upgradeBlocker, error)
+}
+
+// runUpgradeableChecks runs all checks and aggregates their results into the
+// status, message and reason of the Upgradeable condition.
+func runUpgradeableChecks(ctx context.Context, checks []upgradeableCheck, c *manifests.Config) (configv1.ConditionStatus, string, string) {
+	var (
+		reasons  []string
+		messages []string
+		errs     []string
+	)
+	for _, uc := range checks {
+		b, err := uc.check(ctx, c)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if b != nil {
+			reasons = append(reasons, b.reason)
+			messages = append(messages, b.message)
+		}
+	}
+
+	switch {
+	case len(reasons) == 1:
+		return configv1.ConditionFalse, messages[0], reasons[0]
+	case len(reasons) > 1:
+		return configv1.ConditionFalse, strings.Join(messages, " "), "MultipleReasons"
+	case len(errs) > 0:
+		return configv1.ConditionUnknown, fmt.Sprintf("Failed to evaluate upgradeability: %s", strings.Join(errs, "; ")), "UpgradeableCheckFailed"
+	}
+
+	return configv1.ConditionTrue, "", ""
+}
+
+// deprecatedConfigCheck blocks upgrades while the Cluster Monitoring
+// configuration still uses deprecated fields. The configuration is read from
+// the informer caches, either from the ClusterMonitoring resource when it is
+// the source of truth or from the ConfigMap.
+type deprecatedConfigCheck struct {
+	configMaps cache.Store
+	// clusterMonitorings is nil when the ClusterMonitoring resource isn't
+	// watched.
+	clusterMonitorings cache.Store
+	configMapKey       string
+}
+
+func (d *deprecatedConfigCheck) check(_ context.Context, _ *manifests.Config) (*upgradeBlocker, error) {
+	source, raw, err := d.rawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var used []string
+	for _, f := range deprecatedConfigFields {
+		if _, ok := raw[f]; ok {
+			used = append(used, f)
+		}
+	}
+	if len(used) == 0 {
+		return nil, nil
+	}
+
+	return &upgradeBlocker{
+		reason:  "DeprecatedConfigurationInUse",
+		message: fmt.Sprintf("The %s uses deprecated fields which need to be removed before upgrading: %s.", source, strings.Join(used, ", ")),
+	}, nil
+}
+
+// rawConfig returns the name and the top-level fields of the configuration
+// source, the fields are nil if there is no configuration.
+func (d *deprecatedConfigCheck) rawConfig() (string, map[string]interface{}, error) {
+	if d.clusterMonitorings != nil {
+		obj, found, err := d.clusterMonitorings.GetByKey(manifests.ClusterMonitoringName)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "failed to get the ClusterMonitoring resource")
+		}
+		if found {
+			cm := obj.(*unstructured.Unstructured)
+			if !manifests.IsConvertedFromConfigMap(cm) {
+				spec, _, err := unstructured.NestedMap(cm.Object, "spec")
+				if err != nil {
+					return "", nil, errors.Wrap(err, "failed to parse the ClusterMonitoring resource")
+				}
+				return "ClusterMonitoring resource", spec, nil
+			}
+		}
+	}
+
+	const source = "Cluster Monitoring ConfigMap"
+	obj, found, err := d.configMaps.GetByKey(d.configMapKey)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get the Cluster Monitoring ConfigMap")
+	}
+	if !found {
+		return source, nil, nil
+	}
+
+	content, found := obj.(*v1.ConfigMap).Data["config.yaml"]
+	if !found {
+		return source, nil, nil
+	}
+
+	raw := map[string]interface{}{}
+	if err := k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(content), 100).Decode(&raw); err != nil {
+		return "", nil, errors.Wrap(err, "failed to parse the Cluster Monitoring ConfigMap")
+	}
+	return source, raw, nil
+}
+
+// persistentStorageCheck blocks upgrades when the StatefulSets of Prometheus
+// or Alertmanager run without persistent storage and have a pending rollout.
+// The upgrade would recreate the pods and all data would be lost.
+type persistentStorageCheck struct {
+	kclient   kubernetes.Interface
+	namespace string
+}
+
+func (p *persistentStorageCheck) check(ctx context.Context, _ *manifests.Config) (*upgradeBlocker, error) {
+	var names []string
+	for _, name := range []string{"prometheus-k8s", "alertmanager-main"} {
+		sts, err := p.kclient.AppsV1().StatefulSets(p.namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get StatefulSet %s", name)
+		}
+
+		if len(sts.Spec.VolumeClaimTemplates) > 0 || !rolloutPending(sts) {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	return &upgradeBlocker{
+		reason:  "PersistentStorageNotConfigured",
+		message: fmt.Sprintf("StatefulSets running without persistent storage are rolling out and their data would be lost: %s. Configure persistent storage or wait for the rollout to complete before upgrading.", strings.Join(names, ", ")),
+	}, nil
+}
+
+// rolloutPending returns true if the StatefulSet controller hasn't rolled
+// out the current spec to all pods yet.
+func rolloutPending(sts *appsv1.StatefulSet) bool {
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return true
+	}
+	return sts.Status.UpdateRevision != "" && sts.Status.CurrentRevision != sts.Status.UpdateRevision
+}
+
+// userWorkloadResourcesCheck blocks upgrades when user-defined resources rely
+// on features which the next version of the Prometheus operator rejects.
+// Currently it detects ServiceMonitors reading bearer tokens from files. The
+// ServiceMonitors are read from the caches of the user workload informers.
+type userWorkloadResourcesCheck struct {
+	namespaces      cache.Store
+	serviceMonitors lister
+	// hasSynced returns true once the caches of the user workload informers
+	// are filled.
+	hasSynced func() bool
+	// platformNamespaces selects the namespaces which aren't considered for
+	// user workload monitoring.
+	platformNamespaces labels.Selector
+}
+
+func (u *userWorkloadResourcesCheck) check(_ context.Context, c *manifests.Config) (*upgradeBlocker, error) {
+	if !*c.ClusterMonitoringConfiguration.UserWorkloadEnabled {
+		return nil, nil
+	}
+	if !u.hasSynced() {
+		return nil, errors.New("user workload resources informers not synced yet")
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(c.UserWorkloadNamespaceSelector())
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, obj := range u.serviceMonitors.List() {
+		sm := obj.(*monv1.ServiceMonitor)
+		ns, exists, err := u.namespaces.GetByKey(sm.Namespace)
+		if err != nil || !exists {
+			continue
+		}
+		lset := labels.Set(ns.(*v1.Namespace).Labels)
+		if u.platformNamespaces.Matches(lset) || !selector.Matches(lset) {
+			continue
+		}
+		for _, ep := range sm.Spec.Endpoints {
+			if ep.BearerTokenFile != "" {
+				names = append(names, sm.Namespace+"/"+sm.Name)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+
+	return &upgradeBlocker{
+		reason:  "UnsupportedUserWorkloadResources",
+		message: fmt.Sprintf("The following ServiceMonitors use bearerTokenFile which is rejected by the next version of the Prometheus operator: %s.", strings.Join(names, ", ")),
+	}, nil
+}
@@ -0,0 +1,366 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+type staticCheck struct {
+	blocker *upgradeBlocker
+	err     error
+}
+
+func (s *staticCheck) check(context.Context, *manifests.Config) (*upgradeBlocker, error) {
+	return s.blocker, s.err
+}
+
+func TestRunUpgradeableChecks(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		checks []upgradeableCheck
+		cond   configv1.ConditionStatus
+		reason string
+	}{
+		{
+			name:   "no checks",
+			cond:   configv1.ConditionTrue,
+			reason: "",
+		},
+		{
+			name: "passing checks",
+			checks: []upgradeableCheck{
+				&staticCheck{},
+				&staticCheck{},
+			},
+			cond:   configv1.ConditionTrue,
+			reason: "",
+		},
+		{
+			name: "one blocker",
+			checks: []upgradeableCheck{
+				&staticCheck{},
+				&staticCheck{blocker: &upgradeBlocker{reason: "Foo", message: "foo"}},
+			},
+			cond:   configv1.ConditionFalse,
+			reason: "Foo",
+		},
+		{
+			name: "multiple blockers",
+			checks: []upgradeableCheck{
+				&staticCheck{blocker: &upgradeBlocker{reason: "Foo", message: "foo"}},
+				&staticCheck{blocker: &upgradeBlocker{reason: "Bar", message: "bar"}},
+			},
+			cond:   configv1.ConditionFalse,
+			reason: "MultipleReasons",
+		},
+		{
+			name: "blocker and error",
+			checks: []upgradeableCheck{
+				&staticCheck{err: errors.New("failed")},
+				&staticCheck{blocker: &upgradeBlocker{reason: "Foo", message: "foo"}},
+			},
+			cond:   configv1.ConditionFalse,
+			reason: "Foo",
+		},
+		{
+			name: "error",
+			checks: []upgradeableCheck{
+				&staticCheck{},
+				&staticCheck{err: errors.New("failed")},
+			},
+			cond:   configv1.ConditionUnknown,
+			reason: "UpgradeableCheckFailed",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cond, _, reason := runUpgradeableChecks(context.Background(), tc.checks, manifests.NewDefaultConfig())
+			if cond != tc.cond {
+				t.Errorf("expected condition %q, got %q", tc.cond, cond)
+			}
+			if reason != tc.reason {
+				t.Errorf("expected reason %q, got %q", tc.reason, reason)
+			}
+		})
+	}
+}
+
+func TestDeprecatedConfigCheck(t *testing.T) {
+	configMap := func(content string) *v1.ConfigMap {
+		return &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-monitoring-config", Namespace: "openshift-monitoring"},
+			Data:       map[string]string{"config.yaml": content},
+		}
+	}
+	clusterMonitoring := func(spec map[string]interface{}, converted bool) *unstructured.Unstructured {
+		cm := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+		cm.SetName(manifests.ClusterMonitoringName)
+		if converted {
+			cm.SetAnnotations(map[string]string{manifests.ConvertedFromConfigMapAnnotation: "true"})
+		}
+		return cm
+	}
+
+	for _, tc := range []struct {
+		name              string
+		configMap         *v1.ConfigMap
+		clusterMonitoring *unstructured.Unstructured
+		blocked           bool
+	}{
+		{
+			name:    "no configmap",
+			blocked: false,
+		},
+		{
+			name:      "no deprecated fields",
+			configMap: configMap("enableUserWorkload: true\n"),
+			blocked:   false,
+		},
+		{
+			name:      "deprecated field",
+			configMap: configMap("techPreviewUserWorkload:\n  enabled: true\n"),
+			blocked:   true,
+		},
+		{
+			name:              "deprecated field in the resource",
+			configMap:         configMap("enableUserWorkload: true\n"),
+			clusterMonitoring: clusterMonitoring(map[string]interface{}{"etcd": map[string]interface{}{"enabled": true}}, false),
+			blocked:           true,
+		},
+		{
+			name:              "deprecated field in the ConfigMap of a converted resource",
+			configMap:         configMap("techPreviewUserWorkload:\n  enabled: true\n"),
+			clusterMonitoring: clusterMonitoring(map[string]interface{}{}, true),
+			blocked:           true,
+		},
+		{
+			name:              "resource is the source of truth",
+			configMap:         configMap("techPreviewUserWorkload:\n  enabled: true\n"),
+			clusterMonitoring: clusterMonitoring(map[string]interface{}{}, false),
+			blocked:           false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			configMaps := cache.NewStore(cache.MetaNamespaceKeyFunc)
+			if tc.configMap != nil {
+				if err := configMaps.Add(tc.configMap); err != nil {
+					t.Fatal(err)
+				}
+			}
+			clusterMonitorings := cache.NewStore(cache.MetaNamespaceKeyFunc)
+			if tc.clusterMonitoring != nil {
+				if err := clusterMonitorings.Add(tc.clusterMonitoring); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := &deprecatedConfigCheck{
+				configMaps:         configMaps,
+				clusterMonitorings: clusterMonitorings,
+				configMapKey:       "openshift-monitoring/cluster-monitoring-config",
+			}
+
+			b, err := c.check(context.Background(), manifests.NewDefaultConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b != nil; got != tc.blocked {
+				t.Fatalf("expected blocked %t, got %t", tc.blocked, got)
+			}
+		})
+	}
+}
+
+func TestPersistentStorageCheck(t *testing.T) {
+	sts := func(name string, storage bool, generation, observedGeneration int64, currentRevision, updateRevision string) runtime.Object {
+		s := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-monitoring", Generation: generation},
+			Status: appsv1.StatefulSetStatus{
+				ObservedGeneration: observedGeneration,
+				CurrentRevision:    currentRevision,
+				UpdateRevision:     updateRevision,
+			},
+		}
+		if storage {
+			s.Spec.VolumeClaimTemplates = []v1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: name + "-db"}}}
+		}
+		return s
+	}
+
+	for _, tc := range []struct {
+		name    string
+		objects []runtime.Object
+		blocked bool
+	}{
+		{
+			name:    "no statefulsets",
+			blocked: false,
+		},
+		{
+			name: "no pending rollout",
+			objects: []runtime.Object{
+				sts("prometheus-k8s", false, 2, 2, "rev-1", "rev-1"),
+				sts("alertmanager-main", false, 1, 1, "rev-1", "rev-1"),
+			},
+			blocked: false,
+		},
+		{
+			name: "revision rolling out without storage",
+			objects: []runtime.Object{
+				sts("prometheus-k8s", false, 2, 2, "rev-1", "rev-2"),
+			},
+			blocked: true,
+		},
+		{
+			name: "generation not observed without storage",
+			objects: []runtime.Object{
+				sts("alertmanager-main", false, 3, 2, "rev-1", "rev-1"),
+			},
+			blocked: true,
+		},
+		{
+			name: "revision rolling out with storage",
+			objects: []runtime.Object{
+				sts("prometheus-k8s", true, 2, 2, "rev-1", "rev-2"),
+			},
+			blocked: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &persistentStorageCheck{
+				kclient:   fake.NewSimpleClientset(tc.objects...),
+				namespace: "openshift-monitoring",
+			}
+			b, err := c.check(context.Background(), manifests.NewDefaultConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b != nil; got != tc.blocked {
+				t.Fatalf("expected blocked %t, got %t", tc.blocked, got)
+			}
+		})
+	}
+}
+
+func TestUserWorkloadResourcesCheck(t *testing.T) {
+	sm := func(namespace string, bearerTokenFile string) runtime.Object {
+		return &monv1.ServiceMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: namespace},
+			Spec: monv1.ServiceMonitorSpec{
+				Endpoints: []monv1.Endpoint{{Port: "web", BearerTokenFile: bearerTokenFile}},
+			},
+		}
+	}
+
+	namespaces := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for _, ns := range []*v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "openshift-monitoring", Labels: map[string]string{"openshift.io/cluster-monitoring": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "user"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "opted-out", Labels: map[string]string{manifests.UserWorkloadMonitoringNamespaceLabel: "false"}}},
+	} {
+		if err := namespaces.Add(ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		config    string
+		objects   []runtime.Object
+		unsynced  bool
+		blocked   bool
+		expectErr bool
+	}{
+		{
+			name:    "user workload disabled",
+			config:  "",
+			objects: []runtime.Object{sm("user", "/var/run/token")},
+			blocked: false,
+		},
+		{
+			name:    "no bearer token file",
+			config:  "enableUserWorkload: true",
+			objects: []runtime.Object{sm("user", "")},
+			blocked: false,
+		},
+		{
+			name:    "bearer token file in platform namespace",
+			config:  "enableUserWorkload: true",
+			objects: []runtime.Object{sm("openshift-monitoring", "/var/run/token")},
+			blocked: false,
+		},
+		{
+			name:    "bearer token file in opted-out namespace",
+			config:  "enableUserWorkload: true",
+			objects: []runtime.Object{sm("opted-out", "/var/run/token")},
+			blocked: false,
+		},
+		{
+			name:    "bearer token file in user namespace",
+			config:  "enableUserWorkload: true",
+			objects: []runtime.Object{sm("user", "/var/run/token")},
+			blocked: true,
+		},
+		{
+			name:      "informers not synced",
+			config:    "enableUserWorkload: true",
+			objects:   []runtime.Object{sm("user", "/var/run/token")},
+			unsynced:  true,
+			expectErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := manifests.NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := &userWorkloadResourcesCheck{
+				namespaces:         namespaces,
+				serviceMonitors:    newStore(t, tc.objects, &monv1.ServiceMonitor{}),
+				hasSynced:          func() bool { return !tc.unsynced },
+				platformNamespaces: labels.SelectorFromSet(labels.Set{"openshift.io/cluster-monitoring": "true"}),
+			}
+			b, err := c.check(context.Background(), config)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b != nil; got != tc.blocked {
+				t.Fatalf("expected blocked %t, got %t", tc.blocked, got)
+			}
+		})
+	}
+}
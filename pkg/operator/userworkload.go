@@ -0,0 +1,352 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	promql "github.com/prometheus/prometheus/promql/parser"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+// rejectedResource is a user-defined resource ignored by user workload
+// monitoring.
+type rejectedResource struct {
+	kind   string
+	obj    metav1.Object
+	reason string
+}
+
+func (r rejectedResource) String() string {
+	return fmt.Sprintf("%s %s/%s: %s", r.kind, r.obj.GetNamespace(), r.obj.GetName(), r.reason)
+}
+
+// userWorkloadResourcesValidator finds the ServiceMonitors, PodMonitors,
+// Probes and PrometheusRules of the user namespaces which are ignored by the
+// user workload Prometheus operator or which break the rule evaluation. The
+// Prometheus operator only logs them so their owners have no idea why their
+// targets or rules don't show up.
+type userWorkloadResourcesValidator struct {
+	namespaces cache.Store
+	// getSecret returns the Secrets referenced by the resources, they are
+	// read on demand rather than watched in all the namespaces.
+	getSecret       func(namespace, name string) (*v1.Secret, error)
+	serviceMonitors lister
+	podMonitors     lister
+	probes          lister
+	prometheusRules lister
+	// platformNamespaces selects the namespaces which aren't considered for
+	// user workload monitoring.
+	platformNamespaces labels.Selector
+}
+
+// rejected returns the rejected resources sorted by kind, namespace and name.
+// The resources of the skipped namespaces aren't validated.
+func (u *userWorkloadResourcesValidator) rejected(c *manifests.Config, skip map[string]string) ([]rejectedResource, error) {
+	if !*c.ClusterMonitoringConfiguration.UserWorkloadEnabled {
+		return nil, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(c.UserWorkloadNamespaceSelector())
+	if err != nil {
+		return nil, err
+	}
+	isMonitored := func(obj metav1.Object) bool {
+		if _, found := skip[obj.GetNamespace()]; found {
+			return false
+		}
+		ns, exists, err := u.namespaces.GetByKey(obj.GetNamespace())
+		if err != nil || !exists {
+			return false
+		}
+		lset := labels.Set(ns.(*v1.Namespace).Labels)
+		return !u.platformNamespaces.Matches(lset) && selector.Matches(lset)
+	}
+
+	var (
+		res     []rejectedResource
+		secrets = make(map[string]*v1.Secret)
+	)
+	for _, obj := range u.serviceMonitors.List() {
+		sm := obj.(*monv1.ServiceMonitor)
+		if !isMonitored(sm) {
+			continue
+		}
+		err := validateServiceMonitor(sm)
+		if err == nil {
+			err = u.validateSecrets(sm.Namespace, serviceMonitorSecrets(sm), secrets)
+		}
+		if err != nil {
+			res = append(res, rejectedResource{kind: monv1.ServiceMonitorsKind, obj: sm, reason: err.Error()})
+		}
+	}
+
+	for _, obj := range u.podMonitors.List() {
+		pm := obj.(*monv1.PodMonitor)
+		if !isMonitored(pm) {
+			continue
+		}
+		if err := u.validateSecrets(pm.Namespace, podMonitorSecrets(pm), secrets); err != nil {
+			res = append(res, rejectedResource{kind: monv1.PodMonitorsKind, obj: pm, reason: err.Error()})
+		}
+	}
+
+	for _, obj := range u.probes.List() {
+		probe := obj.(*monv1.Probe)
+		if !isMonitored(probe) {
+			continue
+		}
+		if probe.Spec.Targets.StaticConfig == nil && probe.Spec.Targets.Ingress == nil {
+			res = append(res, rejectedResource{kind: monv1.ProbesKind, obj: probe, reason: "the Probe needs at least one target of type staticConfig or ingress"})
+		}
+	}
+
+	for _, obj := range u.prometheusRules.List() {
+		pr := obj.(*monv1.PrometheusRule)
+		if !isMonitored(pr) {
+			continue
+		}
+		if err := validatePrometheusRule(pr); err != nil {
+			res = append(res, rejectedResource{kind: monv1.PrometheusRuleKind, obj: pr, reason: err.Error()})
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].String() < res[j].String()
+	})
+
+	return res, nil
+}
+
+// validateServiceMonitor returns an error if the ServiceMonitor reads files
+// from the Prometheus container, which the user workload Prometheus denies.
+func validateServiceMonitor(sm *monv1.ServiceMonitor) error {
+	for i, ep := range sm.Spec.Endpoints {
+		if ep.BearerTokenFile != "" {
+			return errors.Errorf("endpoint %d: bearerTokenFile isn't allowed, use bearerTokenSecret instead", i)
+		}
+		if tls := ep.TLSConfig; tls != nil && (tls.CAFile != "" || tls.CertFile != "" || tls.KeyFile != "") {
+			return errors.Errorf("endpoint %d: caFile, certFile and keyFile aren't allowed in tlsConfig, use ca, cert and keySecret instead", i)
+		}
+	}
+	return nil
+}
+
+// validatePrometheusRule returns an error if one of the rules has an invalid
+// expression.
+func validatePrometheusRule(pr *monv1.PrometheusRule) error {
+	for _, g := range pr.Spec.Groups {
+		for _, r := range g.Rules {
+			if _, err := promql.ParseExpr(r.Expr.String()); err != nil {
+				name := r.Alert
+				if name == "" {
+					name = r.Record
+				}
+				return errors.Errorf("group %q, rule %q: invalid expression: %v", g.Name, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// serviceMonitorSecrets returns the secret keys referenced by the
+// ServiceMonitor.
+func serviceMonitorSecrets(sm *monv1.ServiceMonitor) []v1.SecretKeySelector {
+	var res []v1.SecretKeySelector
+	for _, ep := range sm.Spec.Endpoints {
+		res = append(res, endpointSecrets(ep.BearerTokenSecret, ep.BasicAuth)...)
+		if ep.TLSConfig != nil {
+			res = append(res, tlsSecrets(ep.TLSConfig.SafeTLSConfig)...)
+		}
+	}
+	return res
+}
+
+// podMonitorSecrets returns the secret keys referenced by the PodMonitor.
+func podMonitorSecrets(pm *monv1.PodMonitor) []v1.SecretKeySelector {
+	var res []v1.SecretKeySelector
+	for _, ep := range pm.Spec.PodMetricsEndpoints {
+		res = append(res, endpointSecrets(ep.BearerTokenSecret, ep.BasicAuth)...)
+		if ep.TLSConfig != nil {
+			res = append(res, tlsSecrets(ep.TLSConfig.SafeTLSConfig)...)
+		}
+	}
+	return res
+}
+
+func endpointSecrets(bearerToken v1.SecretKeySelector, basicAuth *monv1.BasicAuth) []v1.SecretKeySelector {
+	var res []v1.SecretKeySelector
+	if bearerToken.Name != "" {
+		res = append(res, bearerToken)
+	}
+	if basicAuth != nil {
+		res = append(res, basicAuth.Username, basicAuth.Password)
+	}
+	return res
+}
+
+func tlsSecrets(tls monv1.SafeTLSConfig) []v1.SecretKeySelector {
+	var res []v1.SecretKeySelector
+	for _, sel := range []*v1.SecretKeySelector{tls.CA.Secret, tls.Cert.Secret, tls.KeySecret} {
+		if sel != nil {
+			res = append(res, *sel)
+		}
+	}
+	return res
+}
+
+// validateSecrets returns an error if one of the secret keys doesn't exist in
+// the namespace, the Prometheus operator rejects the resource in this case.
+// The Secrets already read are cached in secrets, a nil value recording a
+// missing Secret.
+func (u *userWorkloadResourcesValidator) validateSecrets(namespace string, sels []v1.SecretKeySelector, secrets map[string]*v1.Secret) error {
+	for _, sel := range sels {
+		key := namespace + "/" + sel.Name
+		secret, found := secrets[key]
+		if !found {
+			var err error
+			secret, err = u.getSecret(namespace, sel.Name)
+			if apierrors.IsNotFound(err) {
+				secret = nil
+			} else if err != nil {
+				return errors.Wrapf(err, "getting secret %s failed", key)
+			}
+			secrets[key] = secret
+		}
+		if secret == nil {
+			return errors.Errorf("secret %q not found", sel.Name)
+		}
+		if _, ok := secret.Data[sel.Key]; !ok {
+			return errors.Errorf("key %q not found in secret %q", sel.Key, sel.Name)
+		}
+	}
+	return nil
+}
+
+// lister lists the objects of informer caches.
+type lister interface {
+	List() []interface{}
+}
+
+// storeList lists the objects of several stores.
+type storeList []cache.Store
+
+func (s storeList) List() []interface{} {
+	var res []interface{}
+	for _, store := range s {
+		res = append(res, store.List()...)
+	}
+	return res
+}
+
+// userWorkloadInformers watch the ServiceMonitors, PodMonitors, Probes and
+// PrometheusRules of the namespaces monitored by the user workload stack.
+type userWorkloadInformers struct {
+	// namespaces are the sorted watched namespaces, metav1.NamespaceAll
+	// watches all of them.
+	namespaces []string
+	informers  []cache.SharedIndexInformer
+	stopc      chan struct{}
+
+	serviceMonitors storeList
+	podMonitors     storeList
+	probes          storeList
+	prometheusRules storeList
+}
+
+// newUserWorkloadInformers returns the informers of the given namespaces,
+// one per namespace and resource. The handler receives the events of all of
+// them.
+func newUserWorkloadInformers(c *client.Client, namespaces []string, handler cache.ResourceEventHandler) *userWorkloadInformers {
+	infs := &userWorkloadInformers{namespaces: namespaces}
+
+	newInformer := func(lw *cache.ListWatch, obj runtime.Object) cache.Store {
+		inf := cache.NewSharedIndexInformer(lw, obj, resyncPeriod, cache.Indexers{})
+		inf.AddEventHandler(handler)
+		infs.informers = append(infs.informers, inf)
+		return inf.GetStore()
+	}
+	for _, ns := range namespaces {
+		infs.serviceMonitors = append(infs.serviceMonitors, newInformer(c.ServiceMonitorListWatchForNamespace(ns), &monv1.ServiceMonitor{}))
+		infs.podMonitors = append(infs.podMonitors, newInformer(c.PodMonitorListWatchForNamespace(ns), &monv1.PodMonitor{}))
+		infs.probes = append(infs.probes, newInformer(c.ProbeListWatchForNamespace(ns), &monv1.Probe{}))
+		infs.prometheusRules = append(infs.prometheusRules, newInformer(c.PrometheusRuleListWatchForNamespace(ns), &monv1.PrometheusRule{}))
+	}
+
+	return infs
+}
+
+func (i *userWorkloadInformers) start() {
+	i.stopc = make(chan struct{})
+	for _, inf := range i.informers {
+		go inf.Run(i.stopc)
+	}
+}
+
+func (i *userWorkloadInformers) stop() {
+	if i.stopc != nil {
+		close(i.stopc)
+		i.stopc = nil
+	}
+}
+
+func (i *userWorkloadInformers) hasSynced() bool {
+	for _, inf := range i.informers {
+		if !inf.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// userWorkloadNamespaces returns the sorted namespaces monitored by the user
+// workload stack. All the namespaces are watched when no namespace selector
+// is configured, the excluded ones being filtered by the checks.
+func userWorkloadNamespaces(c *manifests.Config, namespaces cache.Store, platformNamespaces labels.Selector) ([]string, error) {
+	if !*c.ClusterMonitoringConfiguration.UserWorkloadEnabled {
+		return nil, nil
+	}
+	if c.ClusterMonitoringConfiguration.UserWorkloadConfig.NamespaceSelector == nil {
+		return []string{metav1.NamespaceAll}, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(c.UserWorkloadNamespaceSelector())
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, obj := range namespaces.List() {
+		ns := obj.(*v1.Namespace)
+		lset := labels.Set(ns.Labels)
+		if !platformNamespaces.Matches(lset) && selector.Matches(lset) {
+			res = append(res, ns.Name)
+		}
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
@@ -0,0 +1,248 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+func TestUserWorkloadResourcesValidator(t *testing.T) {
+	namespace := func(name string, lbls map[string]string) runtime.Object {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: lbls}}
+	}
+	meta := func(namespace, name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: namespace}
+	}
+	secretKey := func(name, key string) v1.SecretKeySelector {
+		return v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: name}, Key: key}
+	}
+
+	kobjects := []runtime.Object{
+		namespace("openshift-monitoring", map[string]string{"openshift.io/cluster-monitoring": "true"}),
+		namespace("user", nil),
+		namespace("opted-out", map[string]string{manifests.UserWorkloadMonitoringNamespaceLabel: "false"}),
+		&v1.Secret{ObjectMeta: meta("user", "token"), Data: map[string][]byte{"token": []byte("foo")}},
+	}
+	mobjects := []runtime.Object{
+		&monv1.ServiceMonitor{
+			ObjectMeta: meta("user", "bearer-token-file"),
+			Spec: monv1.ServiceMonitorSpec{
+				Endpoints: []monv1.Endpoint{{Port: "web", BearerTokenFile: "/var/run/token"}},
+			},
+		},
+		&monv1.ServiceMonitor{
+			ObjectMeta: meta("user", "valid"),
+			Spec: monv1.ServiceMonitorSpec{
+				Endpoints: []monv1.Endpoint{{Port: "web", BearerTokenSecret: secretKey("token", "token")}},
+			},
+		},
+		&monv1.ServiceMonitor{
+			ObjectMeta: meta("opted-out", "bearer-token-file"),
+			Spec: monv1.ServiceMonitorSpec{
+				Endpoints: []monv1.Endpoint{{Port: "web", BearerTokenFile: "/var/run/token"}},
+			},
+		},
+		&monv1.ServiceMonitor{
+			ObjectMeta: meta("openshift-monitoring", "bearer-token-file"),
+			Spec: monv1.ServiceMonitorSpec{
+				Endpoints: []monv1.Endpoint{{Port: "web", BearerTokenFile: "/var/run/token"}},
+			},
+		},
+		&monv1.PodMonitor{
+			ObjectMeta: meta("user", "missing-key"),
+			Spec: monv1.PodMonitorSpec{
+				PodMetricsEndpoints: []monv1.PodMetricsEndpoint{{Port: "web", BearerTokenSecret: secretKey("token", "missing")}},
+			},
+		},
+		&monv1.PodMonitor{
+			ObjectMeta: meta("user", "missing-secret"),
+			Spec: monv1.PodMonitorSpec{
+				PodMetricsEndpoints: []monv1.PodMetricsEndpoint{{
+					Port:      "web",
+					BasicAuth: &monv1.BasicAuth{Username: secretKey("auth", "user"), Password: secretKey("auth", "password")},
+				}},
+			},
+		},
+		&monv1.Probe{ObjectMeta: meta("user", "no-target")},
+		&monv1.PrometheusRule{
+			ObjectMeta: meta("user", "invalid-expr"),
+			Spec: monv1.PrometheusRuleSpec{
+				Groups: []monv1.RuleGroup{{
+					Name:  "group",
+					Rules: []monv1.Rule{{Alert: "Foo", Expr: intstr.FromString("up ==")}},
+				}},
+			},
+		},
+		&monv1.PrometheusRule{
+			ObjectMeta: meta("user", "valid"),
+			Spec: monv1.PrometheusRuleSpec{
+				Groups: []monv1.RuleGroup{{
+					Name:  "group",
+					Rules: []monv1.Rule{{Alert: "Foo", Expr: intstr.FromString("up == 0")}},
+				}},
+			},
+		},
+	}
+
+	kclient := fake.NewSimpleClientset(kobjects...)
+	v := &userWorkloadResourcesValidator{
+		namespaces: newStore(t, kobjects, &v1.Namespace{}),
+		getSecret: func(namespace, name string) (*v1.Secret, error) {
+			return kclient.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		},
+		serviceMonitors:    newStore(t, mobjects, &monv1.ServiceMonitor{}),
+		podMonitors:        newStore(t, mobjects, &monv1.PodMonitor{}),
+		probes:             newStore(t, mobjects, &monv1.Probe{}),
+		prometheusRules:    newStore(t, mobjects, &monv1.PrometheusRule{}),
+		platformNamespaces: labels.SelectorFromSet(labels.Set{"openshift.io/cluster-monitoring": "true"}),
+	}
+
+	for _, tc := range []struct {
+		name     string
+		config   string
+		skip     map[string]string
+		expected []string
+	}{
+		{
+			name:   "user workload disabled",
+			config: "",
+		},
+		{
+			name:   "user workload enabled",
+			config: "enableUserWorkload: true",
+			expected: []string{
+				`PodMonitor user/missing-key: key "missing" not found in secret "token"`,
+				`PodMonitor user/missing-secret: secret "auth" not found`,
+				`Probe user/no-target: the Probe needs at least one target of type staticConfig or ingress`,
+				`PrometheusRule user/invalid-expr: group "group", rule "Foo": invalid expression: 1:6: parse error: unexpected end of input`,
+				`ServiceMonitor user/bearer-token-file: endpoint 0: bearerTokenFile isn't allowed, use bearerTokenSecret instead`,
+			},
+		},
+		{
+			name:   "namespace skipped",
+			config: "enableUserWorkload: true",
+			skip:   map[string]string{"user": "over quota"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := manifests.NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rejected, err := v.rejected(config, tc.skip)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, r := range rejected {
+				got = append(got, r.String())
+			}
+			if !reflect.DeepEqual(tc.expected, got) {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestUserWorkloadNamespaces(t *testing.T) {
+	namespaces := newStore(t, []runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "openshift-monitoring", Labels: map[string]string{"openshift.io/cluster-monitoring": "true", "team": "a"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "b", Labels: map[string]string{"team": "a"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"team": "a"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "opted-out", Labels: map[string]string{"team": "a", manifests.UserWorkloadMonitoringNamespaceLabel: "false"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"team": "b"}}},
+	}, &v1.Namespace{})
+	platformNamespaces := labels.SelectorFromSet(labels.Set{"openshift.io/cluster-monitoring": "true"})
+
+	for _, tc := range []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name: "user workload disabled",
+		},
+		{
+			name:     "no namespace selector",
+			config:   "enableUserWorkload: true",
+			expected: []string{metav1.NamespaceAll},
+		},
+		{
+			name: "namespace selector",
+			config: `enableUserWorkload: true
+userWorkload:
+  namespaceSelector:
+    matchLabels:
+      team: a
+`,
+			expected: []string{"a", "b"},
+		},
+		{
+			name: "no namespace selected",
+			config: `enableUserWorkload: true
+userWorkload:
+  namespaceSelector:
+    matchLabels:
+      team: c
+`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := manifests.NewConfigFromString(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := userWorkloadNamespaces(config, namespaces, platformNamespaces)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.expected, got) {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// newStore returns a store holding the objects of the same type as obj.
+func newStore(t *testing.T, objects []runtime.Object, obj runtime.Object) cache.Store {
+	t.Helper()
+
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for _, o := range objects {
+		if reflect.TypeOf(o) != reflect.TypeOf(obj) {
+			continue
+		}
+		if err := store.Add(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
@@ -15,95 +15,217 @@
 package promqlgen
 
 import (
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
 	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
 	promql "github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v2"
 )
 
-func LabelSelectorsToRelabelConfig(matches []string) (*monv1.RelabelConfig, error) {
+const (
+	// matchedLabel is set to "1" on the series selected by at least one
+	// selector.
+	matchedLabel = "__tmp_telemetry_matched"
+	// matchersLabel counts the positive matchers of the current selector
+	// which match the series. It is set to "no" when a negative matcher
+	// doesn't match.
+	matchersLabel = "__tmp_telemetry_matchers"
+)
+
+// LabelSelectorsToRelabelConfig returns the relabel configs keeping only the
+// series selected by at least one of the given metric selectors.
+//
+// When all the selectors are made of a single positive matcher on the same
+// label, a single keep action is enough. Otherwise each selector is evaluated
+// by a chain of replace actions marking the selected series with a temporary
+// label and the chain ends with keep and labeldrop actions.
+func LabelSelectorsToRelabelConfig(matches []string) ([]monv1.RelabelConfig, error) {
 	labelSets, err := parseMetricSelectorFromArray(matches)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not parse metric selectors from matches array")
 	}
 
-	labelPositions := map[string]int{}
-	sourceLabels := []string{}
-	i := 0
-	for _, ls := range labelSets {
-		for _, lm := range ls {
-			_, exists := labelPositions[lm.Name]
-			if exists {
-				continue
-			}
+	grouped, others := groupSingleMatcherSelectors(labelSets)
+	if len(grouped) == 1 && len(others) == 0 {
+		for name, values := range grouped {
+			return []monv1.RelabelConfig{{
+				Action:       "keep",
+				SourceLabels: []string{name},
+				Regex:        strings.Join(values, "|"),
+			}}, nil
+		}
+	}
+
+	var cfgs []monv1.RelabelConfig
+	for _, name := range sortedKeys(grouped) {
+		cfgs = append(cfgs, monv1.RelabelConfig{
+			Action:       "replace",
+			SourceLabels: []string{name},
+			Regex:        strings.Join(grouped[name], "|"),
+			TargetLabel:  matchedLabel,
+			Replacement:  "1",
+		})
+	}
+
+	for _, ls := range others {
+		cfgs = append(cfgs, selectorRelabelConfigs(ls)...)
+	}
+
+	return append(cfgs,
+		monv1.RelabelConfig{
+			Action:       "keep",
+			SourceLabels: []string{matchedLabel},
+			Regex:        "1",
+		},
+		monv1.RelabelConfig{
+			Action: "labeldrop",
+			Regex:  regexp.QuoteMeta(matchedLabel) + "|" + regexp.QuoteMeta(matchersLabel),
+		},
+	), nil
+}
+
+// selectorRelabelConfigs returns the relabel configs setting matchedLabel on
+// the series selected by the matchers.
+//
+// The positive matchers are chained through matchersLabel: the nth matcher
+// only matches when matchersLabel is "n-1" so that the concatenation of the
+// source labels can't be ambiguous. The negative matchers are applied last.
+func selectorRelabelConfigs(ls []*labels.Matcher) []monv1.RelabelConfig {
+	cfgs := []monv1.RelabelConfig{{
+		Action:      "replace",
+		Regex:       "(.*)",
+		TargetLabel: matchersLabel,
+		Replacement: "0",
+	}}
 
-			sourceLabels = append(sourceLabels, lm.Name)
-			labelPositions[lm.Name] = i
-			i++
+	n := 0
+	for _, m := range ls {
+		if isNegative(m) {
+			continue
 		}
+		cfgs = append(cfgs, monv1.RelabelConfig{
+			Action:       "replace",
+			SourceLabels: []string{matchersLabel, m.Name},
+			Regex:        strconv.Itoa(n) + ";(?:" + matcherRegex(m) + ")",
+			TargetLabel:  matchersLabel,
+			Replacement:  strconv.Itoa(n + 1),
+		})
+		n++
 	}
 
-	regexParts := []string{}
-	for _, ls := range labelSets {
-		labelValues := make([]string, len(sourceLabels))
-		for _, lm := range ls {
-			labelValues[labelPositions[lm.Name]] = lm.Value
+	for _, m := range ls {
+		if !isNegative(m) {
+			continue
+		}
+		regex := matcherRegex(m)
+		if regex == "" {
+			// An empty regex would be replaced by the default "(.*)".
+			regex = "()"
 		}
-		regexParts = append(regexParts, strings.Join(labelValues, ";"))
+		cfgs = append(cfgs, monv1.RelabelConfig{
+			Action:       "replace",
+			SourceLabels: []string{m.Name},
+			Regex:        regex,
+			TargetLabel:  matchersLabel,
+			Replacement:  "no",
+		})
 	}
-	regex := "(" + strings.Join(regexParts, "|") + ")"
 
-	return &monv1.RelabelConfig{
-		Action:       "keep",
-		SourceLabels: sourceLabels,
-		Regex:        regex,
-	}, nil
+	return append(cfgs, monv1.RelabelConfig{
+		Action:       "replace",
+		SourceLabels: []string{matchersLabel},
+		Regex:        strconv.Itoa(n),
+		TargetLabel:  matchedLabel,
+		Replacement:  "1",
+	})
 }
 
+// GroupLabelSelectors returns a PromQL expression selecting the same series
+// as the given metric selectors. The selectors made of a single positive
+// matcher are grouped by label name, the other selectors are joined with the
+// "or" operator.
 func GroupLabelSelectors(matches []string) (string, error) {
 	labelSets, err := parseMetricSelectorFromArray(matches)
 	if err != nil {
 		return "", errors.Wrap(err, "could not parse metric selectors from matches array")
 	}
-	newLabelSet := map[string][]string{}
-	for _, ls := range labelSets {
-		for _, lm := range ls {
-			_, exists := newLabelSet[lm.Name]
-			if exists {
-				newLabelSet[lm.Name] = append(newLabelSet[lm.Name], lm.Value)
-			} else {
-				newLabelSet[lm.Name] = []string{lm.Value}
-			}
-		}
+	if len(labelSets) == 0 {
+		return "", errors.New("no metric selector")
+	}
 
+	grouped, others := groupSingleMatcherSelectors(labelSets)
+
+	var exprs []string
+	for _, name := range sortedKeys(grouped) {
+		values := grouped[name]
+		if len(values) == 1 && values[0] == regexp.QuoteMeta(values[0]) {
+			// Use an equality matcher when possible for readability.
+			exprs = append(exprs, "{"+name+"="+strconv.Quote(values[0])+"}")
+			continue
+		}
+		exprs = append(exprs, "{"+name+"=~"+strconv.Quote(strings.Join(values, "|"))+"}")
 	}
 
-	keys := make([]string, 0, len(newLabelSet))
-	for k := range newLabelSet {
-		keys = append(keys, k)
+	for _, ls := range others {
+		matchers := make([]string, 0, len(ls))
+		for _, m := range ls {
+			matchers = append(matchers, m.String())
+		}
+		exprs = append(exprs, "{"+strings.Join(matchers, ",")+"}")
 	}
-	sort.Strings(keys)
 
-	res := "{"
-	i := 0
-	for _, k := range keys {
-		res += k + `=~"`
-		res += strings.Join(newLabelSet[k], "|")
-		i++
-		if k != "__name__" {
-			res += `|`
-		}
-		if i == len(newLabelSet) {
-			res += `"`
+	return strings.Join(exprs, " or "), nil
+}
+
+// groupSingleMatcherSelectors returns the regular expressions of the
+// selectors made of a single positive matcher indexed by label name and the
+// remaining selectors with their matchers sorted by label name.
+func groupSingleMatcherSelectors(labelSets [][]*labels.Matcher) (map[string][]string, [][]*labels.Matcher) {
+	grouped := map[string][]string{}
+	var others [][]*labels.Matcher
+	for _, ls := range labelSets {
+		if len(ls) == 1 && !isNegative(ls[0]) {
+			grouped[ls[0].Name] = append(grouped[ls[0].Name], matcherRegex(ls[0]))
 			continue
 		}
-		res += `",`
+
+		sort.SliceStable(ls, func(i, j int) bool {
+			return ls[i].Name < ls[j].Name
+		})
+		others = append(others, ls)
+	}
+	return grouped, others
+}
+
+// matcherRegex returns the regular expression matching the values selected by
+// the matcher, or not selected for negative matchers. Like PromQL, relabel
+// configs anchor the regular expressions.
+func matcherRegex(m *labels.Matcher) string {
+	switch m.Type {
+	case labels.MatchEqual, labels.MatchNotEqual:
+		return regexp.QuoteMeta(m.Value)
+	default:
+		return m.Value
 	}
+}
 
-	return res + "}", nil
+func isNegative(m *labels.Matcher) bool {
+	return m.Type == labels.MatchNotEqual || m.Type == labels.MatchNotRegexp
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func parseMetricSelectorFromArray(matches []string) ([][]*labels.Matcher, error) {
@@ -114,6 +236,59 @@ func parseMetricSelectorFromArray(matches []string) ([][]*labels.Matcher, error)
 		if err != nil {
 			return nil, err
 		}
+
+		// Like PromQL, reject the selectors which would match all series.
+		empty := true
+		for _, lm := range labelSets[i] {
+			if !lm.Matches("") {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			return nil, errors.Errorf("%s: metric selector must contain at least one non-empty matcher", m)
+		}
 	}
 	return labelSets, nil
 }
+
+// RelabelConfigs converts the relabel configs like the Prometheus operator
+// does: the empty fields are omitted and get the Prometheus defaults.
+func RelabelConfigs(cfgs []monv1.RelabelConfig) ([]*relabel.Config, error) {
+	res := make([]*relabel.Config, 0, len(cfgs))
+	for _, c := range cfgs {
+		item := yaml.MapSlice{}
+		if len(c.SourceLabels) > 0 {
+			item = append(item, yaml.MapItem{Key: "source_labels", Value: c.SourceLabels})
+		}
+		if c.Separator != "" {
+			item = append(item, yaml.MapItem{Key: "separator", Value: c.Separator})
+		}
+		if c.TargetLabel != "" {
+			item = append(item, yaml.MapItem{Key: "target_label", Value: c.TargetLabel})
+		}
+		if c.Regex != "" {
+			item = append(item, yaml.MapItem{Key: "regex", Value: c.Regex})
+		}
+		if c.Modulus != 0 {
+			item = append(item, yaml.MapItem{Key: "modulus", Value: c.Modulus})
+		}
+		if c.Replacement != "" {
+			item = append(item, yaml.MapItem{Key: "replacement", Value: c.Replacement})
+		}
+		if c.Action != "" {
+			item = append(item, yaml.MapItem{Key: "action", Value: c.Action})
+		}
+
+		b, err := yaml.Marshal(item)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshaling relabel config failed")
+		}
+		var rc relabel.Config
+		if err := yaml.UnmarshalStrict(b, &rc); err != nil {
+			return nil, errors.Wrap(err, "invalid relabel config")
+		}
+		res = append(res, &rc)
+	}
+	return res, nil
+}
@@ -15,30 +15,110 @@
 package promqlgen
 
 import (
+	"math/rand"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/quick"
 
 	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	promql "github.com/prometheus/prometheus/promql/parser"
 )
 
 func TestLabelSelectorsToRelabelConfig(t *testing.T) {
-	matches := []string{
-		`{__name__="metric1"}`,
-		`{alertstate="firing",__name__="ALERTS"}`,
-		`{__name__="metric2"}`,
-	}
-	r, err := LabelSelectorsToRelabelConfig(matches)
-	if err != nil {
-		t.Fatal(err)
-	}
+	for _, tc := range []struct {
+		name     string
+		matches  []string
+		expected []monv1.RelabelConfig
+	}{
+		{
+			name: "single label",
+			matches: []string{
+				`{__name__="metric1"}`,
+				`{__name__=~"cluster:usage:.*"}`,
+				`{__name__="metric.2"}`,
+			},
+			expected: []monv1.RelabelConfig{
+				{
+					Action:       "keep",
+					SourceLabels: []string{"__name__"},
+					Regex:        `metric1|cluster:usage:.*|metric\.2`,
+				},
+			},
+		},
+		{
+			name: "several labels",
+			matches: []string{
+				`{__name__="metric1"}`,
+				`{alertstate="firing",__name__="ALERTS",alertname!~"Watchdog|"}`,
+			},
+			expected: []monv1.RelabelConfig{
+				{
+					Action:       "replace",
+					SourceLabels: []string{"__name__"},
+					Regex:        "metric1",
+					TargetLabel:  matchedLabel,
+					Replacement:  "1",
+				},
+				{
+					Action:      "replace",
+					Regex:       "(.*)",
+					TargetLabel: matchersLabel,
+					Replacement: "0",
+				},
+				{
+					Action:       "replace",
+					SourceLabels: []string{matchersLabel, "__name__"},
+					Regex:        "0;(?:ALERTS)",
+					TargetLabel:  matchersLabel,
+					Replacement:  "1",
+				},
+				{
+					Action:       "replace",
+					SourceLabels: []string{matchersLabel, "alertstate"},
+					Regex:        "1;(?:firing)",
+					TargetLabel:  matchersLabel,
+					Replacement:  "2",
+				},
+				{
+					Action:       "replace",
+					SourceLabels: []string{"alertname"},
+					Regex:        "Watchdog|",
+					TargetLabel:  matchersLabel,
+					Replacement:  "no",
+				},
+				{
+					Action:       "replace",
+					SourceLabels: []string{matchersLabel},
+					Regex:        "2",
+					TargetLabel:  matchedLabel,
+					Replacement:  "1",
+				},
+				{
+					Action:       "keep",
+					SourceLabels: []string{matchedLabel},
+					Regex:        "1",
+				},
+				{
+					Action: "labeldrop",
+					Regex:  "__tmp_telemetry_matched|__tmp_telemetry_matchers",
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := LabelSelectorsToRelabelConfig(tc.matches)
+			if err != nil {
+				t.Fatal(err)
+			}
 
-	expected := &monv1.RelabelConfig{
-		Action:       "keep",
-		SourceLabels: []string{"__name__", "alertstate"},
-		Regex:        "(metric1;|ALERTS;firing|metric2;)",
-	}
-	if !reflect.DeepEqual(expected, r) {
-		t.Fatal("unexpected result")
+			if !reflect.DeepEqual(tc.expected, r) {
+				t.Fatalf("expected:\n%v\ngot:\n%v", tc.expected, r)
+			}
+		})
 	}
 }
 
@@ -55,7 +135,7 @@ func TestGenerateTelemeterWhitelistRec(t *testing.T) {
 			`{__name__="csv_abnormal"}`,
 		},
 	}
-	expected := `{__name__=~"ALERTS|node_uname_info|csv_abnormal",alertstate=~"firing|"}`
+	expected := `{__name__=~"node_uname_info|csv_abnormal"} or {__name__="ALERTS",alertstate="firing"}`
 	for _, i := range cases {
 
 		expr, err := GroupLabelSelectors(i)
@@ -67,3 +147,143 @@ func TestGenerateTelemeterWhitelistRec(t *testing.T) {
 		}
 	}
 }
+
+var (
+	testLabelNames  = []string{"__name__", "a", "b"}
+	testLabelValues = []string{"", "x", "y", "x;y", "0", "1", "no", "a.b", "axb", `a\b`}
+	testRegexps     = []string{"", "x", "x|y", ".*", ".+", "x.*", "[xy]", "a.b", `a\.b`, "1|no", ".*;y"}
+)
+
+// testSelectors is a list of random metric selectors.
+type testSelectors []string
+
+func (testSelectors) Generate(r *rand.Rand, size int) reflect.Value {
+	types := []string{"=", "!=", "=~", "!~"}
+
+	selectors := make(testSelectors, 1+r.Intn(4))
+	for i := range selectors {
+		var matchers []string
+		// A selector needs at least one matcher which doesn't match the
+		// empty string.
+		for len(matchers) == 0 || promqlMatchesEmpty(matchers) {
+			matchers = matchers[:0]
+			for j := 0; j < 1+r.Intn(3); j++ {
+				typ := types[r.Intn(len(types))]
+				value := testLabelValues[r.Intn(len(testLabelValues))]
+				if strings.HasSuffix(typ, "~") {
+					value = testRegexps[r.Intn(len(testRegexps))]
+				}
+				matchers = append(matchers, testLabelNames[r.Intn(len(testLabelNames))]+typ+strconv.Quote(value))
+			}
+		}
+		selectors[i] = "{" + strings.Join(matchers, ",") + "}"
+	}
+
+	return reflect.ValueOf(selectors)
+}
+
+func promqlMatchesEmpty(matchers []string) bool {
+	_, err := parseMetricSelectorFromArray([]string{"{" + strings.Join(matchers, ",") + "}"})
+	return err != nil
+}
+
+// testLabelSets is a list of random label sets.
+type testLabelSets []labels.Labels
+
+func (testLabelSets) Generate(r *rand.Rand, size int) reflect.Value {
+	lsets := make(testLabelSets, 50)
+	for i := range lsets {
+		m := map[string]string{}
+		for _, name := range testLabelNames {
+			// Empty values are the same as missing labels.
+			if v := testLabelValues[r.Intn(len(testLabelValues))]; v != "" {
+				m[name] = v
+			}
+		}
+		lsets[i] = labels.FromMap(m)
+	}
+	return reflect.ValueOf(lsets)
+}
+
+// selects returns true if at least one of the selectors matches the labels.
+func selects(selectors [][]*labels.Matcher, lset labels.Labels) bool {
+	for _, matchers := range selectors {
+		matched := true
+		for _, m := range matchers {
+			if !m.Matches(lset.Get(m.Name)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// TestLabelSelectorsProperties checks that the generated relabel configs and
+// PromQL expression select exactly the same series as the selectors.
+func TestLabelSelectorsProperties(t *testing.T) {
+	f := func(matches testSelectors, lsets testLabelSets) bool {
+		selectors, err := parseMetricSelectorFromArray(matches)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfgs, err := LabelSelectorsToRelabelConfig(matches)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rcfgs, err := RelabelConfigs(cfgs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expr, err := GroupLabelSelectors(matches)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := promql.ParseExpr(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		// The expression is a union of vector selectors.
+		var exprSelectors [][]*labels.Matcher
+		promql.Inspect(e, func(node promql.Node, _ []promql.Node) error {
+			switch n := node.(type) {
+			case *promql.VectorSelector:
+				exprSelectors = append(exprSelectors, n.LabelMatchers)
+			case *promql.BinaryExpr:
+				if n.Op != promql.LOR {
+					t.Fatalf("%s: unexpected operator %s", expr, n.Op)
+				}
+			}
+			return nil
+		})
+
+		for _, lset := range lsets {
+			exp := selects(selectors, lset)
+
+			got := relabel.Process(lset.Copy(), rcfgs...)
+			if exp != (got != nil) {
+				t.Logf("matches: %v, labels: %v: expected selected=%v from %v", matches, lset, exp, cfgs)
+				return false
+			}
+			if got != nil && !labels.Equal(lset, got) {
+				t.Logf("matches: %v: expected labels %v, got %v", matches, lset, got)
+				return false
+			}
+
+			if selects(exprSelectors, lset) != exp {
+				t.Logf("matches: %v, labels: %v: expected selected=%v from %s", matches, lset, exp, expr)
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+}
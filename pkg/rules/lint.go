@@ -0,0 +1,263 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rules checks the alerting and recording rules shipped with the
+// platform stack.
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/prometheus/pkg/labels"
+	promql "github.com/prometheus/prometheus/promql/parser"
+)
+
+var (
+	requiredLabels      = []string{"severity"}
+	requiredAnnotations = []string{"description", "summary"}
+
+	// knownMissingAnnotations lists the shipped alerts which don't have the
+	// required annotations yet. New alerts must not be added here.
+	knownMissingAnnotations = map[string]struct{}{
+		"cluster-monitoring-operator-prometheus-rules/AlertmanagerReceiversNotConfigured":            {},
+		"cluster-monitoring-operator-prometheus-rules/ClusterMonitoringOperatorReconciliationErrors": {},
+		"cluster-monitoring-operator-prometheus-rules/MultipleContainersOOMKilled":                   {},
+		"cluster-monitoring-operator-prometheus-rules/NodeNetworkInterfaceFlapping":                  {},
+		"cluster-monitoring-operator-prometheus-rules/Watchdog":                                      {},
+		"cluster-monitoring-operator-prometheus-rules/etcdInsufficientMembers":                       {},
+		"etcd-prometheus-rules/etcdBackendQuotaLowSpace":                                             {},
+		"etcd-prometheus-rules/etcdExcessiveDatabaseGrowth":                                          {},
+		"etcd-prometheus-rules/etcdHighFsyncDurations":                                               {},
+	}
+)
+
+// Problem describes a rule which doesn't pass the checks.
+type Problem struct {
+	// PrometheusRule is the name of the PrometheusRule object defining the
+	// rule.
+	PrometheusRule string
+	Group          string
+	// Rule is the name of the alert or recording rule.
+	Rule    string
+	Message string
+	// Warning is true when the problem shouldn't fail the checks.
+	Warning bool
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s/%s/%s: %s", p.PrometheusRule, p.Group, p.Rule, p.Message)
+}
+
+// Problems is a list of problems which can be used as an error.
+type Problems []Problem
+
+// Errors returns the problems which aren't warnings.
+func (ps Problems) Errors() Problems {
+	var errs Problems
+	for _, p := range ps {
+		if !p.Warning {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// Warnings returns the problems which are warnings.
+func (ps Problems) Warnings() Problems {
+	var warnings Problems
+	for _, p := range ps {
+		if p.Warning {
+			warnings = append(warnings, p)
+		}
+	}
+	return warnings
+}
+
+func (ps Problems) Error() string {
+	msgs := make([]string, len(ps))
+	for i, p := range ps {
+		msgs[i] = p.String()
+	}
+	return fmt.Sprintf("%d invalid rule(s): %s", len(ps), strings.Join(msgs, "; "))
+}
+
+// LintAssets loads the platform PrometheusRule assets and checks them. The
+// Grafana dashboards and the given consumers (e.g. the telemetry matches)
+// are searched when looking for unused recording rules.
+func LintAssets(a *manifests.Assets, consumers ...string) (Problems, error) {
+	prs, err := loadAssets(a)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboards, err := a.GetAsset(manifests.GrafanaDashboardDefinitions)
+	if err != nil {
+		return nil, err
+	}
+
+	return Lint(prs, append(consumers, string(dashboards))...), nil
+}
+
+// loadAssets returns the platform PrometheusRule objects.
+func loadAssets(a *manifests.Assets) ([]*monv1.PrometheusRule, error) {
+	prs := make([]*monv1.PrometheusRule, 0, len(manifests.PlatformPrometheusRules))
+	for _, asset := range manifests.PlatformPrometheusRules {
+		pr, err := manifests.NewPrometheusRule(a.MustNewAssetReader(asset))
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading %s failed", asset)
+		}
+		prs = append(prs, pr)
+	}
+	return prs, nil
+}
+
+// Lint checks the given PrometheusRule objects:
+// * all expressions must be valid PromQL expressions.
+// * alerts must have the required labels and annotations.
+// * an alert name can't be defined more than once with the same labels.
+// * recording rules should be used by a rule or one of the consumers. Some
+// recording rules are only used outside of the cluster (e.g. telemetry) or
+// by other components so they are reported as warnings.
+func Lint(prs []*monv1.PrometheusRule, consumers ...string) Problems {
+	var (
+		problems Problems
+		// used tracks the metric names referenced by the rules.
+		used = map[string]struct{}{}
+		// alerts tracks the alerts by name and labels.
+		alerts = map[string]string{}
+	)
+
+	type recordingRule struct {
+		pr, group, name string
+	}
+	var (
+		records     []recordingRule
+		seenRecords = map[recordingRule]struct{}{}
+	)
+
+	for _, pr := range prs {
+		for _, g := range pr.Spec.Groups {
+			for _, r := range g.Rules {
+				name := r.Alert
+				if name == "" {
+					name = r.Record
+				}
+				problem := func(format string, args ...interface{}) {
+					problems = append(problems, Problem{
+						PrometheusRule: pr.Name,
+						Group:          g.Name,
+						Rule:           name,
+						Message:        fmt.Sprintf(format, args...),
+					})
+				}
+				_, knownMissing := knownMissingAnnotations[pr.Name+"/"+name]
+
+				expr, err := promql.ParseExpr(r.Expr.String())
+				if err != nil {
+					problem("invalid expression: %v", err)
+				} else {
+					for _, metric := range metricNames(expr) {
+						used[metric] = struct{}{}
+					}
+				}
+
+				if r.Record != "" {
+					// The same metric can be recorded by several rules.
+					rr := recordingRule{pr: pr.Name, group: g.Name, name: r.Record}
+					if _, found := seenRecords[rr]; !found {
+						seenRecords[rr] = struct{}{}
+						records = append(records, rr)
+					}
+					continue
+				}
+
+				for _, l := range requiredLabels {
+					if r.Labels[l] == "" {
+						problem("missing %q label", l)
+					}
+				}
+				for _, a := range requiredAnnotations {
+					if r.Annotations[a] == "" && !knownMissing {
+						problem("missing %q annotation", a)
+					}
+				}
+
+				key := r.Alert + labels.FromMap(r.Labels).String()
+				if previous, found := alerts[key]; found {
+					problem("alert already defined with the same labels in %s", previous)
+					continue
+				}
+				alerts[key] = pr.Name + "/" + g.Name
+			}
+		}
+	}
+
+	for _, r := range records {
+		if _, found := used[r.name]; found {
+			continue
+		}
+
+		found := false
+		for _, c := range consumers {
+			if strings.Contains(c, r.name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, Problem{
+				PrometheusRule: r.pr,
+				Group:          r.group,
+				Rule:           r.name,
+				Message:        "recording rule isn't used",
+				Warning:        true,
+			})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].String() < problems[j].String()
+	})
+
+	return problems
+}
+
+// metricNames returns the metric names selected by the expression.
+func metricNames(expr promql.Expr) []string {
+	var names []string
+	promql.Inspect(expr, func(node promql.Node, _ []promql.Node) error {
+		vs, ok := node.(*promql.VectorSelector)
+		if !ok {
+			return nil
+		}
+
+		if vs.Name != "" {
+			names = append(names, vs.Name)
+			return nil
+		}
+
+		for _, m := range vs.LabelMatchers {
+			if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+				names = append(names, m.Value)
+			}
+		}
+		return nil
+	})
+	return names
+}
@@ -0,0 +1,168 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	assetsPath          = "../../assets"
+	telemetryConfigPath = "../../manifests/0000_50_cluster-monitoring-operator_04-config.yaml"
+)
+
+func TestLintAssets(t *testing.T) {
+	telemetryConfig, err := ioutil.ReadFile(telemetryConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := LintAssets(manifests.NewAssets(assetsPath), string(telemetryConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range problems.Warnings() {
+		t.Logf("warning: %s", p)
+	}
+	for _, p := range problems.Errors() {
+		t.Error(p)
+	}
+}
+
+func alert(name, expr string, labels map[string]string) monv1.Rule {
+	return monv1.Rule{
+		Alert:  name,
+		Expr:   intstr.FromString(expr),
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary":     "summary",
+			"description": "description",
+		},
+	}
+}
+
+func record(name, expr string) monv1.Rule {
+	return monv1.Rule{
+		Record: name,
+		Expr:   intstr.FromString(expr),
+	}
+}
+
+func TestLint(t *testing.T) {
+	critical := map[string]string{"severity": "critical"}
+
+	for _, tc := range []struct {
+		name      string
+		rules     []monv1.Rule
+		consumers []string
+		expected  []string
+	}{
+		{
+			name: "valid rules",
+			rules: []monv1.Rule{
+				record("job:up:sum", "sum by (job) (up)"),
+				record("job:up:count", "count by (job) (up)"),
+				alert("TargetDown", "job:up:sum == 0", critical),
+				alert("TargetDown", "job:up:sum == 0", map[string]string{"severity": "warning"}),
+			},
+			consumers: []string{`expr: job:up:count`},
+		},
+		{
+			name: "invalid expression",
+			rules: []monv1.Rule{
+				alert("Broken", "sum(up", critical),
+			},
+			expected: []string{`test/group/Broken: invalid expression`},
+		},
+		{
+			name: "missing label and annotations",
+			rules: []monv1.Rule{
+				{Alert: "Incomplete", Expr: intstr.FromString("up == 0")},
+			},
+			expected: []string{
+				`test/group/Incomplete: missing "description" annotation`,
+				`test/group/Incomplete: missing "severity" label`,
+				`test/group/Incomplete: missing "summary" annotation`,
+			},
+		},
+		{
+			name: "duplicate alert",
+			rules: []monv1.Rule{
+				alert("TargetDown", "up == 0", critical),
+				alert("TargetDown", "absent(up)", critical),
+			},
+			expected: []string{`test/group/TargetDown: alert already defined with the same labels in test/group`},
+		},
+		{
+			name: "unused recording rule",
+			rules: []monv1.Rule{
+				record("job:up:sum", "sum by (job) (up)"),
+				record("job:up:sum", "sum by (job) (up{job=\"foo\"})"),
+				alert("TargetDown", `{__name__="job:up:sum"} == 0`, critical),
+				record("job:up:count", "count by (job) (up)"),
+			},
+			expected: []string{`test/group/job:up:count: recording rule isn't used`},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pr := &monv1.PrometheusRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec: monv1.PrometheusRuleSpec{
+					Groups: []monv1.RuleGroup{{Name: "group", Rules: tc.rules}},
+				},
+			}
+
+			var got []string
+			for _, p := range Lint([]*monv1.PrometheusRule{pr}, tc.consumers...) {
+				got = append(got, p.String())
+			}
+
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %d problem(s), got %d: %v", len(tc.expected), len(got), got)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tc.expected[i]) {
+					t.Errorf("expected problem %q, got %q", tc.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProblems(t *testing.T) {
+	ps := Problems{
+		{PrometheusRule: "a", Group: "g", Rule: "r1", Message: "error"},
+		{PrometheusRule: "a", Group: "g", Rule: "r2", Message: "warning", Warning: true},
+	}
+
+	if got := ps.Errors(); !reflect.DeepEqual(got, ps[:1]) {
+		t.Errorf("unexpected errors: %v", got)
+	}
+	if got := ps.Warnings(); !reflect.DeepEqual(got, ps[1:]) {
+		t.Errorf("unexpected warnings: %v", got)
+	}
+	if got := ps.Errors().Error(); got != "1 invalid rule(s): a/g/r1: error" {
+		t.Errorf("unexpected error message: %q", got)
+	}
+}
@@ -0,0 +1,444 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/pkg/errors"
+	monv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	prules "github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"gopkg.in/yaml.v2"
+)
+
+const unitTestsPath = "../../test/rules"
+
+// The types below follow the promtool unit testing format, see
+// https://prometheus.io/docs/prometheus/latest/configuration/unit_testing_rules/.
+// The rule_files field is ignored: the tests always run against the shipped
+// PrometheusRule assets.
+type unitTestFile struct {
+	RuleFiles          []string       `yaml:"rule_files"`
+	EvaluationInterval model.Duration `yaml:"evaluation_interval,omitempty"`
+	GroupEvalOrder     []string       `yaml:"group_eval_order"`
+	Tests              []testGroup    `yaml:"tests"`
+}
+
+type testGroup struct {
+	Interval        model.Duration   `yaml:"interval"`
+	InputSeries     []inputSeries    `yaml:"input_series"`
+	AlertRuleTests  []alertTestCase  `yaml:"alert_rule_test,omitempty"`
+	PromqlExprTests []promqlTestCase `yaml:"promql_expr_test,omitempty"`
+	ExternalLabels  labels.Labels    `yaml:"external_labels,omitempty"`
+}
+
+type inputSeries struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+}
+
+type alertTestCase struct {
+	EvalTime  model.Duration  `yaml:"eval_time"`
+	Alertname string          `yaml:"alertname"`
+	ExpAlerts []expectedAlert `yaml:"exp_alerts"`
+}
+
+type expectedAlert struct {
+	ExpLabels      map[string]string `yaml:"exp_labels"`
+	ExpAnnotations map[string]string `yaml:"exp_annotations"`
+}
+
+type promqlTestCase struct {
+	Expr       string           `yaml:"expr"`
+	EvalTime   model.Duration   `yaml:"eval_time"`
+	ExpSamples []expectedSample `yaml:"exp_samples"`
+}
+
+type expectedSample struct {
+	Labels string  `yaml:"labels"`
+	Value  float64 `yaml:"value"`
+}
+
+// TestRulesUnitTests runs the unit tests from test/rules against the shipped
+// rules without requiring promtool.
+func TestRulesUnitTests(t *testing.T) {
+	prs, err := loadAssets(manifests.NewAssets(assetsPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(unitTestsPath, "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no unit test file found in %s", unitTestsPath)
+	}
+
+	for _, f := range files {
+		f := f
+		t.Run(filepath.Base(f), func(t *testing.T) {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var utf unitTestFile
+			if err := yaml.UnmarshalStrict(b, &utf); err != nil {
+				t.Fatal(err)
+			}
+
+			runUnitTestFile(t, prs, &utf)
+		})
+	}
+}
+
+func runUnitTestFile(t *testing.T, prs []*monv1.PrometheusRule, utf *unitTestFile) {
+	if utf.EvaluationInterval == 0 {
+		utf.EvaluationInterval = model.Duration(time.Minute)
+	}
+
+	groupOrder := map[string]int{}
+	for i, name := range utf.GroupEvalOrder {
+		if _, found := groupOrder[name]; found {
+			t.Fatalf("group name repeated in evaluation order: %s", name)
+		}
+		groupOrder[name] = i
+	}
+
+	evalInterval := time.Duration(utf.EvaluationInterval)
+	for i := range utf.Tests {
+		tg := &utf.Tests[i]
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			tg.run(t, prs, evalInterval, groupOrder)
+		})
+	}
+}
+
+// run loads the input series, evaluates the rules until the last evaluation
+// time of the test group and checks the alerts and the PromQL expressions.
+func (tg *testGroup) run(t *testing.T, prs []*monv1.PrometheusRule, evalInterval time.Duration, groupOrder map[string]int) {
+	suite, err := promql.NewLazyLoader(t, tg.seriesLoadingString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer suite.Close()
+
+	opts := &prules.ManagerOptions{
+		QueryFunc:  prules.EngineQueryFunc(suite.QueryEngine(), suite.Storage()),
+		Appendable: suite.Storage(),
+		Context:    context.Background(),
+		NotifyFunc: func(ctx context.Context, expr string, alerts ...*prules.Alert) {},
+		Logger:     log.NewNopLogger(),
+	}
+	groups, err := newRuleGroups(prs, time.Duration(tg.Interval), tg.ExternalLabels, groupOrder, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The alerts are checked while the rules are evaluated, the expected
+	// alerts are indexed by evaluation time.
+	alertTests := map[time.Duration][]alertTestCase{}
+	var alertEvalTimes []time.Duration
+	for _, tc := range tg.AlertRuleTests {
+		d := time.Duration(tc.EvalTime)
+		if _, found := alertTests[d]; !found {
+			alertEvalTimes = append(alertEvalTimes, d)
+		}
+		alertTests[d] = append(alertTests[d], tc)
+	}
+	sort.Slice(alertEvalTimes, func(i, j int) bool { return alertEvalTimes[i] < alertEvalTimes[j] })
+
+	mint := time.Unix(0, 0).UTC()
+	maxt := mint.Add(tg.maxEvalTime())
+	// Round to the next evaluation after the last evaluation time.
+	maxt = maxt.Add(evalInterval / 2).Round(evalInterval)
+
+	curr := 0
+	for ts := mint; ts.Before(maxt); ts = ts.Add(evalInterval) {
+		suite.WithSamplesTill(ts, func(err error) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, g := range groups {
+				g.Eval(suite.Context(), ts)
+				for _, r := range g.Rules() {
+					if r.LastError() != nil {
+						t.Fatalf("rule: %s, time: %s, err: %v", r.Name(), ts.Sub(mint), r.LastError())
+					}
+				}
+			}
+		})
+
+		// The alerts expected at eval_time are compared with the evaluation
+		// at ts when ts <= eval_time < ts+evalInterval.
+		for curr < len(alertEvalTimes) &&
+			ts.Sub(mint) <= alertEvalTimes[curr] &&
+			alertEvalTimes[curr] < ts.Add(evalInterval).Sub(mint) {
+			for _, tc := range alertTests[alertEvalTimes[curr]] {
+				tc := tc
+				t.Run(tc.Alertname, func(t *testing.T) {
+					if err := tc.check(groups); err != nil {
+						t.Error(err)
+					}
+				})
+			}
+			curr++
+		}
+	}
+
+	for _, tc := range tg.PromqlExprTests {
+		if err := tc.check(suite.Context(), suite.QueryEngine(), suite.Queryable(), mint); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// seriesLoadingString returns the input series in the PromQL test notation.
+func (tg *testGroup) seriesLoadingString() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "load %dms\n", time.Duration(tg.Interval).Milliseconds())
+	for _, is := range tg.InputSeries {
+		fmt.Fprintf(&sb, "  %s %s\n", is.Series, is.Values)
+	}
+	return sb.String()
+}
+
+// maxEvalTime returns the last evaluation time of the test group.
+func (tg *testGroup) maxEvalTime() time.Duration {
+	var maxd model.Duration
+	for _, tc := range tg.AlertRuleTests {
+		if tc.EvalTime > maxd {
+			maxd = tc.EvalTime
+		}
+	}
+	for _, tc := range tg.PromqlExprTests {
+		if tc.EvalTime > maxd {
+			maxd = tc.EvalTime
+		}
+	}
+	return time.Duration(maxd)
+}
+
+// newRuleGroups converts the PrometheusRule objects into rule groups ordered
+// by the given evaluation order.
+func newRuleGroups(prs []*monv1.PrometheusRule, interval time.Duration, externalLabels labels.Labels, groupOrder map[string]int, opts *prules.ManagerOptions) ([]*prules.Group, error) {
+	var groups []*prules.Group
+	for _, pr := range prs {
+		for _, g := range pr.Spec.Groups {
+			rs := make([]prules.Rule, 0, len(g.Rules))
+			for _, r := range g.Rules {
+				expr, err := parser.ParseExpr(r.Expr.String())
+				if err != nil {
+					return nil, errors.Wrapf(err, "%s/%s: invalid expression", pr.Name, g.Name)
+				}
+
+				if r.Record != "" {
+					rs = append(rs, prules.NewRecordingRule(r.Record, expr, labels.FromMap(r.Labels)))
+					continue
+				}
+
+				var hold time.Duration
+				if r.For != "" {
+					d, err := model.ParseDuration(r.For)
+					if err != nil {
+						return nil, errors.Wrapf(err, "%s/%s/%s: invalid 'for' duration", pr.Name, g.Name, r.Alert)
+					}
+					hold = time.Duration(d)
+				}
+				// The alerts are marked as restored to get the ALERTS series
+				// as soon as the rules are evaluated.
+				rs = append(rs, prules.NewAlertingRule(
+					r.Alert,
+					expr,
+					hold,
+					labels.FromMap(r.Labels),
+					labels.FromMap(r.Annotations),
+					externalLabels,
+					true,
+					log.NewNopLogger(),
+				))
+			}
+
+			groups = append(groups, prules.NewGroup(prules.GroupOptions{
+				Name:     g.Name,
+				File:     pr.Name,
+				Interval: interval,
+				Rules:    rs,
+				Opts:     opts,
+			}))
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groupOrder[groups[i].Name()] < groupOrder[groups[j].Name()]
+	})
+
+	return groups, nil
+}
+
+type labelsAndAnnotations struct {
+	Labels      labels.Labels
+	Annotations labels.Labels
+}
+
+func (la labelsAndAnnotations) String() string {
+	return "labels: " + la.Labels.String() + " annotations: " + la.Annotations.String()
+}
+
+func sortLabelsAndAnnotations(las []labelsAndAnnotations) {
+	sort.Slice(las, func(i, j int) bool {
+		if diff := labels.Compare(las[i].Labels, las[j].Labels); diff != 0 {
+			return diff < 0
+		}
+		return labels.Compare(las[i].Annotations, las[j].Annotations) < 0
+	})
+}
+
+// check compares the firing alerts with the expected alerts. The same alert
+// can be defined in several groups.
+func (tc alertTestCase) check(groups []*prules.Group) error {
+	var got []labelsAndAnnotations
+	for _, g := range groups {
+		for _, r := range g.Rules() {
+			ar, ok := r.(*prules.AlertingRule)
+			if !ok || ar.Name() != tc.Alertname {
+				continue
+			}
+			for _, a := range ar.ActiveAlerts() {
+				if a.State != prules.StateFiring {
+					continue
+				}
+				got = append(got, labelsAndAnnotations{
+					Labels:      a.Labels.Copy(),
+					Annotations: a.Annotations.Copy(),
+				})
+			}
+		}
+	}
+
+	exp := make([]labelsAndAnnotations, 0, len(tc.ExpAlerts))
+	for _, a := range tc.ExpAlerts {
+		// The alertname label is added by Prometheus during the evaluation.
+		lset := labels.FromMap(a.ExpLabels)
+		lset = append(lset, labels.Label{Name: labels.AlertName, Value: tc.Alertname})
+		sort.Sort(lset)
+
+		exp = append(exp, labelsAndAnnotations{
+			Labels:      lset,
+			Annotations: labels.FromMap(a.ExpAnnotations),
+		})
+	}
+
+	sortLabelsAndAnnotations(got)
+	sortLabelsAndAnnotations(exp)
+
+	if len(got) == len(exp) {
+		equal := true
+		for i := range got {
+			if !labels.Equal(got[i].Labels, exp[i].Labels) || !labels.Equal(got[i].Annotations, exp[i].Annotations) {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("alertname: %s, time: %s\n  exp: %v\n  got: %v", tc.Alertname, tc.EvalTime, exp, got)
+}
+
+type sample struct {
+	Labels labels.Labels
+	Value  float64
+}
+
+func (s sample) String() string {
+	return s.Labels.String() + " " + strconv.FormatFloat(s.Value, 'E', -1, 64)
+}
+
+func sortSamples(samples []sample) {
+	sort.Slice(samples, func(i, j int) bool {
+		return labels.Compare(samples[i].Labels, samples[j].Labels) < 0
+	})
+}
+
+// check evaluates the expression and compares the result with the expected
+// samples.
+func (tc promqlTestCase) check(ctx context.Context, engine *promql.Engine, q storage.Queryable, mint time.Time) error {
+	exp := make([]sample, 0, len(tc.ExpSamples))
+	for _, s := range tc.ExpSamples {
+		lset, err := parser.ParseMetric(s.Labels)
+		if err != nil {
+			return errors.Wrapf(err, "expr: %q, time: %s, invalid labels %q", tc.Expr, tc.EvalTime, s.Labels)
+		}
+		exp = append(exp, sample{Labels: lset, Value: s.Value})
+	}
+
+	query, err := engine.NewInstantQuery(q, tc.Expr, mint.Add(time.Duration(tc.EvalTime)))
+	if err != nil {
+		return errors.Wrapf(err, "expr: %q, time: %s", tc.Expr, tc.EvalTime)
+	}
+	res := query.Exec(ctx)
+	if res.Err != nil {
+		return errors.Wrapf(res.Err, "expr: %q, time: %s", tc.Expr, tc.EvalTime)
+	}
+
+	var got []sample
+	switch v := res.Value.(type) {
+	case promql.Vector:
+		for _, s := range v {
+			got = append(got, sample{Labels: s.Metric.Copy(), Value: s.V})
+		}
+	case promql.Scalar:
+		got = append(got, sample{Labels: labels.Labels{}, Value: v.V})
+	default:
+		return fmt.Errorf("expr: %q, time: %s, result is not a vector or scalar", tc.Expr, tc.EvalTime)
+	}
+
+	sortSamples(got)
+	sortSamples(exp)
+
+	if len(got) == len(exp) {
+		equal := true
+		for i := range got {
+			if !labels.Equal(got[i].Labels, exp[i].Labels) || got[i].Value != exp[i].Value {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("expr: %q, time: %s\n  exp: %v\n  got: %v", tc.Expr, tc.EvalTime, exp, got)
+}
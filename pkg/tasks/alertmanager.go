@@ -18,17 +18,20 @@ import (
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
 )
 
 type AlertmanagerTask struct {
 	client  *client.Client
 	factory *manifests.Factory
+	config  *manifests.Config
 }
 
-func NewAlertmanagerTask(client *client.Client, factory *manifests.Factory) *AlertmanagerTask {
+func NewAlertmanagerTask(client *client.Client, factory *manifests.Factory, cfg *manifests.Config) *AlertmanagerTask {
 	return &AlertmanagerTask{
 		client:  client,
 		factory: factory,
+		config:  cfg,
 	}
 }
 
@@ -48,14 +51,23 @@ func (t *AlertmanagerTask) Run() error {
 		return errors.Wrap(err, "waiting for Alertmanager Route to become ready failed")
 	}
 
-	s, err := t.factory.AlertmanagerConfig()
+	s, err := t.factory.AlertmanagerConfig(t.secretValue)
 	if err != nil {
 		return errors.Wrap(err, "initializing Alertmanager configuration Secret failed")
 	}
 
-	err = t.client.CreateIfNotExistSecret(s)
-	if err != nil {
-		return errors.Wrap(err, "creating Alertmanager configuration Secret failed")
+	// Without structured configuration, the Secret is owned by the cluster
+	// admin once created.
+	if t.config.ClusterMonitoringConfiguration.AlertmanagerMainConfig.Config != nil {
+		err = t.client.CreateOrUpdateSecret(s)
+		if err != nil {
+			return errors.Wrap(err, "reconciling Alertmanager configuration Secret failed")
+		}
+	} else {
+		err = t.client.CreateIfNotExistSecret(s)
+		if err != nil {
+			return errors.Wrap(err, "creating Alertmanager configuration Secret failed")
+		}
 	}
 
 	rs, err := t.factory.AlertmanagerRBACProxySecret()
@@ -143,6 +155,22 @@ func (t *AlertmanagerTask) Run() error {
 		if err != nil {
 			return errors.Wrap(err, "reconciling Alertmanager object failed")
 		}
+
+		err = t.client.ExpandAlertmanagerVolumes(a)
+		if err != nil {
+			return errors.Wrap(err, "expanding Alertmanager volumes failed")
+		}
+
+		pdb, err := t.factory.AlertmanagerPodDisruptionBudget()
+		if err != nil {
+			return errors.Wrap(err, "initializing Alertmanager PodDisruptionBudget failed")
+		}
+
+		err = syncPodDisruptionBudget(t.client, pdb, *a.Spec.Replicas)
+		if err != nil {
+			return errors.Wrap(err, "reconciling Alertmanager PodDisruptionBudget failed")
+		}
+
 		err = t.client.WaitForAlertmanager(a)
 		if err != nil {
 			return errors.Wrap(err, "waiting for Alertmanager object changes failed")
@@ -165,3 +193,17 @@ func (t *AlertmanagerTask) Run() error {
 	err = t.client.CreateOrUpdateServiceMonitor(smam)
 	return errors.Wrap(err, "reconciling Alertmanager ServiceMonitor failed")
 }
+
+// secretValue returns the value of a key of a Secret in the namespace of the
+// Alertmanager.
+func (t *AlertmanagerTask) secretValue(sel *v1.SecretKeySelector) (string, error) {
+	s, err := t.client.GetSecret(t.client.Namespace(), sel.Name)
+	if err != nil {
+		return "", err
+	}
+	v, found := s.Data[sel.Key]
+	if !found {
+		return "", errors.Errorf("key %q not found in secret %s/%s", sel.Key, s.Namespace, sel.Name)
+	}
+	return string(v), nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/pkg/errors"
+)
+
+type AlertmanagerUserWorkloadTask struct {
+	client  *client.Client
+	factory *manifests.Factory
+	config  *manifests.Config
+}
+
+func NewAlertmanagerUserWorkloadTask(client *client.Client, factory *manifests.Factory, config *manifests.Config) *AlertmanagerUserWorkloadTask {
+	return &AlertmanagerUserWorkloadTask{
+		client:  client,
+		factory: factory,
+		config:  config,
+	}
+}
+
+func (t *AlertmanagerUserWorkloadTask) Run() error {
+	if t.config.UserWorkloadAlertmanagerEnabled() {
+		return t.create()
+	}
+
+	return t.destroy()
+}
+
+func (t *AlertmanagerUserWorkloadTask) create() error {
+	s, err := t.factory.AlertmanagerUserWorkloadSecret()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager configuration Secret failed")
+	}
+
+	err = t.client.CreateIfNotExistSecret(s)
+	if err != nil {
+		return errors.Wrap(err, "creating UserWorkload Alertmanager configuration Secret failed")
+	}
+
+	cr, err := t.factory.AlertmanagerUserWorkloadClusterRole()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager ClusterRole failed")
+	}
+
+	err = t.client.CreateOrUpdateClusterRole(cr)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Alertmanager ClusterRole failed")
+	}
+
+	crb, err := t.factory.AlertmanagerUserWorkloadClusterRoleBinding()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager ClusterRoleBinding failed")
+	}
+
+	err = t.client.CreateOrUpdateClusterRoleBinding(crb)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Alertmanager ClusterRoleBinding failed")
+	}
+
+	ascr, err := t.factory.AlertmanagerUserWorkloadAlertsSenderClusterRole()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager alerts sender ClusterRole failed")
+	}
+
+	err = t.client.CreateOrUpdateClusterRole(ascr)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Alertmanager alerts sender ClusterRole failed")
+	}
+
+	ascrb, err := t.factory.AlertmanagerUserWorkloadAlertsSenderClusterRoleBinding()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager alerts sender ClusterRoleBinding failed")
+	}
+
+	err = t.client.CreateOrUpdateClusterRoleBinding(ascrb)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Alertmanager alerts sender ClusterRoleBinding failed")
+	}
+
+	sa, err := t.factory.AlertmanagerUserWorkloadServiceAccount()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager ServiceAccount failed")
+	}
+
+	err = t.client.CreateOrUpdateServiceAccount(sa)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Alertmanager ServiceAccount failed")
+	}
+
+	svc, err := t.factory.AlertmanagerUserWorkloadService()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager Service failed")
+	}
+
+	err = t.client.CreateOrUpdateService(svc)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Alertmanager Service failed")
+	}
+
+	a, err := t.factory.AlertmanagerUserWorkload()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager object failed")
+	}
+
+	err = t.client.CreateOrUpdateAlertmanager(a)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Alertmanager object failed")
+	}
+
+	err = t.client.ExpandAlertmanagerVolumes(a)
+	if err != nil {
+		return errors.Wrap(err, "expanding UserWorkload Alertmanager volumes failed")
+	}
+
+	pdb, err := t.factory.AlertmanagerUserWorkloadPodDisruptionBudget()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager PodDisruptionBudget failed")
+	}
+
+	err = syncPodDisruptionBudget(t.client, pdb, *a.Spec.Replicas)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Alertmanager PodDisruptionBudget failed")
+	}
+
+	err = t.client.WaitForAlertmanager(a)
+	return errors.Wrap(err, "waiting for UserWorkload Alertmanager object changes failed")
+}
+
+// destroy removes the UserWorkload Alertmanager. The configuration Secret is
+// owned by the cluster admins and is kept so that it is reused when the
+// Alertmanager is enabled again.
+func (t *AlertmanagerUserWorkloadTask) destroy() error {
+	a, err := t.factory.AlertmanagerUserWorkload()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager object failed")
+	}
+
+	err = t.client.DeleteAlertmanager(a)
+	if err != nil {
+		return errors.Wrap(err, "deleting UserWorkload Alertmanager object failed")
+	}
+
+	pdb, err := t.factory.AlertmanagerUserWorkloadPodDisruptionBudget()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager PodDisruptionBudget failed")
+	}
+
+	err = t.client.DeletePodDisruptionBudget(pdb)
+	if err != nil {
+		return errors.Wrap(err, "deleting UserWorkload Alertmanager PodDisruptionBudget failed")
+	}
+
+	svc, err := t.factory.AlertmanagerUserWorkloadService()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager Service failed")
+	}
+
+	err = t.client.DeleteService(svc)
+	if err != nil {
+		return errors.Wrap(err, "deleting UserWorkload Alertmanager Service failed")
+	}
+
+	sa, err := t.factory.AlertmanagerUserWorkloadServiceAccount()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager ServiceAccount failed")
+	}
+
+	err = t.client.DeleteServiceAccount(sa)
+	if err != nil {
+		return errors.Wrap(err, "deleting UserWorkload Alertmanager ServiceAccount failed")
+	}
+
+	ascrb, err := t.factory.AlertmanagerUserWorkloadAlertsSenderClusterRoleBinding()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager alerts sender ClusterRoleBinding failed")
+	}
+
+	err = t.client.DeleteClusterRoleBinding(ascrb)
+	if err != nil {
+		return errors.Wrap(err, "deleting UserWorkload Alertmanager alerts sender ClusterRoleBinding failed")
+	}
+
+	ascr, err := t.factory.AlertmanagerUserWorkloadAlertsSenderClusterRole()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager alerts sender ClusterRole failed")
+	}
+
+	err = t.client.DeleteClusterRole(ascr)
+	if err != nil {
+		return errors.Wrap(err, "deleting UserWorkload Alertmanager alerts sender ClusterRole failed")
+	}
+
+	crb, err := t.factory.AlertmanagerUserWorkloadClusterRoleBinding()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager ClusterRoleBinding failed")
+	}
+
+	err = t.client.DeleteClusterRoleBinding(crb)
+	if err != nil {
+		return errors.Wrap(err, "deleting UserWorkload Alertmanager ClusterRoleBinding failed")
+	}
+
+	cr, err := t.factory.AlertmanagerUserWorkloadClusterRole()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Alertmanager ClusterRole failed")
+	}
+
+	err = t.client.DeleteClusterRole(cr)
+	return errors.Wrap(err, "deleting UserWorkload Alertmanager ClusterRole failed")
+}
@@ -51,6 +51,7 @@ func (t *ClusterMonitoringOperatorTask) Run() error {
 		"monitoring-rules-edit":   t.factory.ClusterMonitoringRulesEditClusterRole,
 		"monitoring-rules-view":   t.factory.ClusterMonitoringRulesViewClusterRole,
 		"monitoring-edit":         t.factory.ClusterMonitoringEditClusterRole,
+		"alert-routing-edit":      t.factory.ClusterMonitoringAlertRoutingEditClusterRole,
 	} {
 		cr, err := crf()
 		if err != nil {
@@ -163,6 +163,16 @@ func (t *GrafanaTask) Run() error {
 		if err != nil {
 			return errors.Wrap(err, "reconciling Grafana Deployment failed")
 		}
+
+		pdb, err := t.factory.GrafanaPodDisruptionBudget()
+		if err != nil {
+			return errors.Wrap(err, "initializing Grafana PodDisruptionBudget failed")
+		}
+
+		err = syncPodDisruptionBudget(t.client, pdb, *d.Spec.Replicas)
+		if err != nil {
+			return errors.Wrap(err, "reconciling Grafana PodDisruptionBudget failed")
+		}
 	}
 
 	sm, err := t.factory.GrafanaServiceMonitor()
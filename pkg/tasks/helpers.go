@@ -21,6 +21,7 @@ import (
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -85,3 +86,13 @@ func (cbs *caBundleSyncer) syncTrustedCABundle(trustedCA *v1.ConfigMap) (*v1.Con
 	)
 	return hashedCM, errors.Wrap(err, "deleting old trusted CA bundle configmaps failed")
 }
+
+// syncPodDisruptionBudget creates the PodDisruptionBudget when the component
+// runs several replicas and deletes it otherwise, since it would block the
+// node drains with a single replica.
+func syncPodDisruptionBudget(c *client.Client, pdb *policyv1beta1.PodDisruptionBudget, replicas int32) error {
+	if replicas > 1 {
+		return c.CreateOrUpdatePodDisruptionBudget(pdb)
+	}
+	return c.DeletePodDisruptionBudget(pdb)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 )
 
@@ -286,18 +287,50 @@ func (t *PrometheusTask) Run() error {
 			return errors.Wrap(err, "syncing Prometheus trusted CA bundle ConfigMap failed")
 		}
 
+		amcs, err := t.factory.PrometheusK8sAdditionalAlertmanagerConfigsSecret()
+		if err != nil {
+			return errors.Wrap(err, "initializing Prometheus additional Alertmanager configs Secret failed")
+		}
+
+		err = t.client.CreateOrUpdateSecret(amcs)
+		if err != nil {
+			return errors.Wrap(err, "reconciling Prometheus additional Alertmanager configs Secret failed")
+		}
+
 		klog.V(4).Info("initializing Prometheus object")
 		p, err := t.factory.PrometheusK8s(host, s, trustedCA)
 		if err != nil {
 			return errors.Wrap(err, "initializing Prometheus object failed")
 		}
 
+		if sel := p.Spec.Thanos.ObjectStorageConfig; sel != nil {
+			err = t.validateObjectStorageSecret(p.Namespace, sel)
+			if err != nil {
+				return errors.Wrap(err, "invalid Thanos object storage configuration")
+			}
+		}
+
 		klog.V(4).Info("reconciling Prometheus object")
 		err = t.client.CreateOrUpdatePrometheus(p)
 		if err != nil {
 			return errors.Wrap(err, "reconciling Prometheus object failed")
 		}
 
+		err = t.client.ExpandPrometheusVolumes(p)
+		if err != nil {
+			return errors.Wrap(err, "expanding Prometheus volumes failed")
+		}
+
+		pdb, err := t.factory.PrometheusK8sPodDisruptionBudget()
+		if err != nil {
+			return errors.Wrap(err, "initializing Prometheus PodDisruptionBudget failed")
+		}
+
+		err = syncPodDisruptionBudget(t.client, pdb, *p.Spec.Replicas)
+		if err != nil {
+			return errors.Wrap(err, "reconciling Prometheus PodDisruptionBudget failed")
+		}
+
 		klog.V(4).Info("waiting for Prometheus object changes")
 		err = t.client.WaitForPrometheus(p)
 		if err != nil {
@@ -334,3 +367,17 @@ func (t *PrometheusTask) Run() error {
 	}
 	return nil
 }
+
+// validateObjectStorageSecret returns an error if the secret holding the
+// Thanos object storage configuration is missing. The Prometheus pods
+// wouldn't start otherwise.
+func (t *PrometheusTask) validateObjectStorageSecret(namespace string, sel *v1.SecretKeySelector) error {
+	s, err := t.client.GetSecret(namespace, sel.Name)
+	if err != nil {
+		return errors.Wrapf(err, "getting secret %s/%s failed", namespace, sel.Name)
+	}
+	if _, found := s.Data[sel.Key]; !found {
+		return errors.Errorf("key %q not found in secret %s/%s", sel.Key, namespace, sel.Name)
+	}
+	return nil
+}
@@ -187,6 +187,16 @@ func (t *PrometheusUserWorkloadTask) create() error {
 		return errors.Wrap(err, "error creating UserWorkload Prometheus Client GRPC TLS secret")
 	}
 
+	amcs, err := t.factory.PrometheusUserWorkloadAdditionalAlertmanagerConfigsSecret()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Prometheus additional Alertmanager configs Secret failed")
+	}
+
+	err = t.client.CreateOrUpdateSecret(amcs)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Prometheus additional Alertmanager configs Secret failed")
+	}
+
 	klog.V(4).Info("initializing UserWorkload Prometheus object")
 	p, err := t.factory.PrometheusUserWorkload(s)
 	if err != nil {
@@ -199,6 +209,21 @@ func (t *PrometheusUserWorkloadTask) create() error {
 		return errors.Wrap(err, "reconciling UserWorkload Prometheus object failed")
 	}
 
+	err = t.client.ExpandPrometheusVolumes(p)
+	if err != nil {
+		return errors.Wrap(err, "expanding UserWorkload Prometheus volumes failed")
+	}
+
+	pdb, err := t.factory.PrometheusUserWorkloadPodDisruptionBudget()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Prometheus PodDisruptionBudget failed")
+	}
+
+	err = syncPodDisruptionBudget(t.client, pdb, *p.Spec.Replicas)
+	if err != nil {
+		return errors.Wrap(err, "reconciling UserWorkload Prometheus PodDisruptionBudget failed")
+	}
+
 	klog.V(4).Info("waiting for UserWorkload Prometheus object changes")
 	err = t.client.WaitForPrometheus(p)
 	if err != nil {
@@ -280,11 +305,31 @@ func (t *PrometheusUserWorkloadTask) destroy() error {
 		return errors.Wrap(err, "deleting UserWorkload Prometheus object failed")
 	}
 
+	pdb, err := t.factory.PrometheusUserWorkloadPodDisruptionBudget()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Prometheus PodDisruptionBudget failed")
+	}
+
+	err = t.client.DeletePodDisruptionBudget(pdb)
+	if err != nil {
+		return errors.Wrap(err, "deleting UserWorkload Prometheus PodDisruptionBudget failed")
+	}
+
 	err = t.client.DeleteSecret(s)
 	if err != nil {
 		return errors.Wrap(err, "deleting UserWorkload Prometheus TLS secret failed")
 	}
 
+	amcs, err := t.factory.PrometheusUserWorkloadAdditionalAlertmanagerConfigsSecret()
+	if err != nil {
+		return errors.Wrap(err, "initializing UserWorkload Prometheus additional Alertmanager configs Secret failed")
+	}
+
+	err = t.client.DeleteSecret(amcs)
+	if err != nil {
+		return errors.Wrap(err, "deleting UserWorkload Prometheus additional Alertmanager configs Secret failed")
+	}
+
 	svc, err := t.factory.PrometheusUserWorkloadService()
 	if err != nil {
 		return errors.Wrap(err, "initializing UserWorkload Prometheus Service failed")
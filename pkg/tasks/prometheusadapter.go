@@ -180,6 +180,16 @@ func (t *PrometheusAdapterTask) Run() error {
 		if err != nil {
 			return errors.Wrap(err, "reconciling PrometheusAdapter Deployment failed")
 		}
+
+		pdb, err := t.factory.PrometheusAdapterPodDisruptionBudget()
+		if err != nil {
+			return errors.Wrap(err, "initializing PrometheusAdapter PodDisruptionBudget failed")
+		}
+
+		err = syncPodDisruptionBudget(t.client, pdb, *dep.Spec.Replicas)
+		if err != nil {
+			return errors.Wrap(err, "reconciling PrometheusAdapter PodDisruptionBudget failed")
+		}
 	}
 	{
 		sm, err := t.factory.PrometheusAdapterServiceMonitor()
@@ -23,12 +23,14 @@ import (
 type PrometheusOperatorTask struct {
 	client  *client.Client
 	factory *manifests.Factory
+	config  *manifests.Config
 }
 
-func NewPrometheusOperatorTask(client *client.Client, factory *manifests.Factory) *PrometheusOperatorTask {
+func NewPrometheusOperatorTask(client *client.Client, factory *manifests.Factory, config *manifests.Config) *PrometheusOperatorTask {
 	return &PrometheusOperatorTask{
 		client:  client,
 		factory: factory,
+		config:  config,
 	}
 }
 
@@ -88,6 +90,16 @@ func (t *PrometheusOperatorTask) Run() error {
 		return errors.Wrap(err, "listing namespaces to monitor failed")
 	}
 
+	if t.config.AlertmanagerMainUserConfigEnabled() {
+		// The operator discovers the AlertmanagerConfig resources of the
+		// user namespaces processed by the platform Alertmanager.
+		userNamespaces, err := t.client.NamespacesSelected(t.config.AlertmanagerConfigNamespaceSelector())
+		if err != nil {
+			return errors.Wrap(err, "listing user namespaces failed")
+		}
+		clusterNamespaces = append(clusterNamespaces, userNamespaces...)
+	}
+
 	d, err := t.factory.PrometheusOperatorDeployment(clusterNamespaces)
 	if err != nil {
 		return errors.Wrap(err, "initializing Prometheus Operator Deployment failed")
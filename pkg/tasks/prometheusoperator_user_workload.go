@@ -84,6 +84,10 @@ func (t *PrometheusOperatorUserWorkloadTask) create() error {
 		return errors.Wrap(err, "reconciling UserWorkload Prometheus Operator Service failed")
 	}
 
+	// Only the platform namespaces are denied. The namespaces excluded from
+	// user workload monitoring are filtered by the namespace selectors of
+	// the user workload resources, which doesn't restart the operator when
+	// namespaces come and go.
 	denyNamespaces, err := t.client.NamespacesToMonitor()
 	if err != nil {
 		return errors.Wrap(err, "initializing UserWorkload denied namespaces list failed")
@@ -22,8 +22,9 @@ import (
 )
 
 type TaskRunner struct {
-	client *client.Client
-	tasks  []*TaskSpec
+	client  *client.Client
+	tasks   []*TaskSpec
+	results []TaskResult
 }
 
 func NewTaskRunner(client *client.Client, tasks []*TaskSpec) *TaskRunner {
@@ -34,6 +35,8 @@ func NewTaskRunner(client *client.Client, tasks []*TaskSpec) *TaskRunner {
 }
 
 func (tl *TaskRunner) RunAll() (string, error) {
+	tl.results = make([]TaskResult, len(tl.tasks))
+
 	var g errgroup.Group
 	for i, ts := range tl.tasks {
 		// shadow vars due to concurrency
@@ -44,6 +47,7 @@ func (tl *TaskRunner) RunAll() (string, error) {
 			klog.V(2).Infof("running task %d of %d: %v", i+1, len(tl.tasks), ts.Name)
 			err := tl.ExecuteTask(ts)
 			klog.V(2).Infof("ran task %d of %d: %v", i+1, len(tl.tasks), ts.Name)
+			tl.results[i] = TaskResult{Name: ts.Name, Criticality: ts.Criticality, Err: err}
 			if err != nil {
 				return taskErr{error: errors.Wrapf(err, "running task %v failed", ts.Name), name: ts.Name}
 			}
@@ -62,20 +66,56 @@ func (tl *TaskRunner) RunAll() (string, error) {
 	return "", nil
 }
 
+// Results returns the outcome of every task executed by the last RunAll
+// call, in the order in which the tasks were given to the runner.
+func (tl *TaskRunner) Results() []TaskResult {
+	return tl.results
+}
+
 func (tl *TaskRunner) ExecuteTask(ts *TaskSpec) error {
 	return ts.Task.Run()
 }
 
+// Criticality defines how the failure of a task affects the availability of
+// the monitoring stack.
+type Criticality int
+
+const (
+	// CriticalTask failures make the monitoring stack unavailable.
+	CriticalTask Criticality = iota
+	// OptionalTask failures only degrade the monitoring stack.
+	OptionalTask
+)
+
+// NewTaskSpec returns a TaskSpec for a critical task.
 func NewTaskSpec(name string, task Task) *TaskSpec {
 	return &TaskSpec{
-		Name: name,
-		Task: task,
+		Name:        name,
+		Task:        task,
+		Criticality: CriticalTask,
+	}
+}
+
+// NewOptionalTaskSpec returns a TaskSpec for an optional task.
+func NewOptionalTaskSpec(name string, task Task) *TaskSpec {
+	return &TaskSpec{
+		Name:        name,
+		Task:        task,
+		Criticality: OptionalTask,
 	}
 }
 
 type TaskSpec struct {
-	Name string
-	Task Task
+	Name        string
+	Task        Task
+	Criticality Criticality
+}
+
+// TaskResult holds the outcome of a task execution.
+type TaskResult struct {
+	Name        string
+	Criticality Criticality
+	Err         error
 }
 
 type Task interface {
@@ -256,7 +256,7 @@ func (t *TelemeterClientTask) destroy() error {
 func generateTelemeterWhitelistRec(telemetryMatches []string) (string, error) {
 	expr, err := promqlgen.GroupLabelSelectors(telemetryMatches)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 	return fmt.Sprintf(`count(%s)`, expr), nil
 }
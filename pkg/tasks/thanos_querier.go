@@ -212,6 +212,16 @@ func (t *ThanosQuerierTask) Run() error {
 		if err != nil {
 			return errors.Wrap(err, "reconciling Thanos Querier Deployment failed")
 		}
+
+		pdb, err := t.factory.ThanosQuerierPodDisruptionBudget()
+		if err != nil {
+			return errors.Wrap(err, "initializing Thanos Querier PodDisruptionBudget failed")
+		}
+
+		err = syncPodDisruptionBudget(t.client, pdb, *dep.Spec.Replicas)
+		if err != nil {
+			return errors.Wrap(err, "reconciling Thanos Querier PodDisruptionBudget failed")
+		}
 	}
 
 	tqsm, err := t.factory.ThanosQuerierServiceMonitor()
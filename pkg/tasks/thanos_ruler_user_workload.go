@@ -139,9 +139,9 @@ func (t *ThanosRulerUserWorkloadTask) create() error {
 		return errors.Wrap(err, "initializing Thanos Ruler Alertmanager config Secret failed")
 	}
 
-	err = t.client.CreateIfNotExistSecret(acs)
+	err = t.client.CreateOrUpdateSecret(acs)
 	if err != nil {
-		return errors.Wrap(err, "creating Thanos Ruler alertmanager config Secret failed")
+		return errors.Wrap(err, "reconciling Thanos Ruler alertmanager config Secret failed")
 	}
 
 	{
@@ -215,6 +215,21 @@ func (t *ThanosRulerUserWorkloadTask) create() error {
 			return errors.Wrap(err, "reconciling ThanosRuler object failed")
 		}
 
+		err = t.client.ExpandThanosRulerVolumes(tr)
+		if err != nil {
+			return errors.Wrap(err, "expanding ThanosRuler volumes failed")
+		}
+
+		pdb, err := t.factory.ThanosRulerPodDisruptionBudget()
+		if err != nil {
+			return errors.Wrap(err, "initializing Thanos Ruler PodDisruptionBudget failed")
+		}
+
+		err = syncPodDisruptionBudget(t.client, pdb, *tr.Spec.Replicas)
+		if err != nil {
+			return errors.Wrap(err, "reconciling Thanos Ruler PodDisruptionBudget failed")
+		}
+
 		err = t.client.WaitForThanosRuler(tr)
 		if err != nil {
 			return errors.Wrap(err, "waiting for ThanosRuler object changes failed")
@@ -363,6 +378,16 @@ func (t *ThanosRulerUserWorkloadTask) destroy() error {
 		return errors.Wrap(err, "deleting ThanosRuler object failed")
 	}
 
+	pdb, err := t.factory.ThanosRulerPodDisruptionBudget()
+	if err != nil {
+		return errors.Wrap(err, "initializing Thanos Ruler PodDisruptionBudget failed")
+	}
+
+	err = t.client.DeletePodDisruptionBudget(pdb)
+	if err != nil {
+		return errors.Wrap(err, "deleting Thanos Ruler PodDisruptionBudget failed")
+	}
+
 	err = t.client.DeleteSecret(grpcSecret)
 	if err != nil {
 		return errors.Wrap(err, "error deleting UserWorkload Thanos Ruler GRPC TLS secret")
@@ -0,0 +1,48 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k8s.io/klog/v2"
+)
+
+// NewHandler returns an HTTP handler serving the telemetry preview as JSON.
+// The config function returns false until the configuration is known.
+func NewHandler(q Querier, config func() (Config, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg, ok := config()
+		if !ok {
+			http.Error(w, "telemetry configuration not loaded yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		p, err := NewPreview(r.Context(), q, cfg)
+		if err != nil {
+			klog.Warningf("telemetry preview failed: %v", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(p); err != nil {
+			klog.Warningf("writing telemetry preview failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry previews the series sent to the Telemeter server.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/openshift/cluster-monitoring-operator/pkg/promqlgen"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Querier runs instant queries.
+type Querier interface {
+	Query(ctx context.Context, query string) (model.Vector, error)
+}
+
+// Config is the telemetry configuration used by the last reconciliation.
+type Config struct {
+	Matches   []string
+	ClusterID string
+	Enabled   bool
+}
+
+// Preview describes the series which would be sent to the Telemeter server.
+type Preview struct {
+	// Enabled is false when telemetry is disabled, the preview still shows
+	// what would be sent.
+	Enabled   bool      `json:"enabled"`
+	Timestamp time.Time `json:"timestamp"`
+	// Query is the PromQL expression selecting the telemetry series.
+	Query     string `json:"query"`
+	ClusterID string `json:"clusterID"`
+	// Series is the number of series after relabeling.
+	Series int `json:"series"`
+	// SizeBytes is the estimated size of the series in the Prometheus text
+	// format.
+	SizeBytes int `json:"sizeBytes"`
+	// LimitBytes is the maximum payload size of telemeter-client.
+	LimitBytes int             `json:"limitBytes"`
+	Metrics    []MetricSummary `json:"metrics"`
+	// LabelSets are the label sets of the series after relabeling.
+	LabelSets []map[string]string `json:"labelSets"`
+}
+
+// MetricSummary is the number of series sent for a metric name.
+type MetricSummary struct {
+	Name   string `json:"name"`
+	Series int    `json:"series"`
+}
+
+// NewPreview queries the series selected by the telemetry matches and applies
+// the same relabeling as the remote write configuration.
+func NewPreview(ctx context.Context, q Querier, cfg Config) (*Preview, error) {
+	query, err := promqlgen.GroupLabelSelectors(cfg.Matches)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating telemetry query failed")
+	}
+
+	cfgs, err := manifests.TelemeterWriteRelabelConfigs(cfg.Matches, cfg.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	rcfgs, err := promqlgen.RelabelConfigs(cfgs)
+	if err != nil {
+		return nil, err
+	}
+
+	vector, err := q.Query(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying telemetry series failed")
+	}
+
+	p := &Preview{
+		Enabled:    cfg.Enabled,
+		Query:      query,
+		ClusterID:  cfg.ClusterID,
+		LimitBytes: manifests.TelemeterClientLimitBytes,
+		LabelSets:  []map[string]string{},
+		Metrics:    []MetricSummary{},
+	}
+
+	var (
+		lsets   []labels.Labels
+		metrics = map[string]int{}
+	)
+	for _, s := range vector {
+		if p.Timestamp.IsZero() {
+			p.Timestamp = s.Timestamp.Time().UTC()
+		}
+
+		lset := relabel.Process(metricToLabels(s.Metric), rcfgs...)
+		if lset == nil {
+			continue
+		}
+		lsets = append(lsets, lset)
+		metrics[lset.Get(labels.MetricName)]++
+
+		p.SizeBytes += len(formatSample(lset, s))
+	}
+
+	sort.Slice(lsets, func(i, j int) bool { return labels.Compare(lsets[i], lsets[j]) < 0 })
+	for _, lset := range lsets {
+		p.LabelSets = append(p.LabelSets, lset.Map())
+	}
+	p.Series = len(lsets)
+
+	for name, n := range metrics {
+		p.Metrics = append(p.Metrics, MetricSummary{Name: name, Series: n})
+	}
+	sort.Slice(p.Metrics, func(i, j int) bool { return p.Metrics[i].Name < p.Metrics[j].Name })
+
+	return p, nil
+}
+
+// formatSample returns the sample in the Prometheus text format.
+func formatSample(lset labels.Labels, s *model.Sample) string {
+	var b strings.Builder
+	b.WriteString(lset.Get(labels.MetricName))
+	b.WriteByte('{')
+	first := true
+	for _, l := range lset {
+		if l.Name == labels.MetricName {
+			continue
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		b.WriteString(l.Name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l.Value))
+	}
+	fmt.Fprintf(&b, "} %s %d\n", s.Value.String(), int64(s.Timestamp))
+	return b.String()
+}
+
+func metricToLabels(m model.Metric) labels.Labels {
+	lset := make(labels.Labels, 0, len(m))
+	for k, v := range m {
+		lset = append(lset, labels.Label{Name: string(k), Value: string(v)})
+	}
+	sort.Sort(lset)
+	return lset
+}
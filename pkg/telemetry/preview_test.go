@@ -0,0 +1,104 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/common/model"
+)
+
+type fakeQuerier struct {
+	query  string
+	vector model.Vector
+}
+
+func (f *fakeQuerier) Query(_ context.Context, query string) (model.Vector, error) {
+	f.query = query
+	return f.vector, nil
+}
+
+func TestNewPreview(t *testing.T) {
+	q := &fakeQuerier{
+		vector: model.Vector{
+			{
+				Metric:    model.Metric{"__name__": "ALERTS", "alertname": "Watchdog", "alertstate": "firing"},
+				Value:     1,
+				Timestamp: 1000,
+			},
+			{
+				Metric:    model.Metric{"__name__": "cluster_version", "version": "4.8.0"},
+				Value:     2,
+				Timestamp: 1000,
+			},
+			{
+				Metric:    model.Metric{"__name__": "cluster_version", "version": "4.7.0"},
+				Value:     3,
+				Timestamp: 1000,
+			},
+			// Not selected by the matches.
+			{
+				Metric:    model.Metric{"__name__": "up", "job": "foo"},
+				Value:     1,
+				Timestamp: 1000,
+			},
+		},
+	}
+
+	p, err := NewPreview(context.Background(), q, Config{
+		Matches:   []string{`{__name__="cluster_version"}`, `{__name__="ALERTS",alertstate="firing"}`},
+		ClusterID: "123",
+		Enabled:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Query != q.query {
+		t.Fatalf("expected query %q, got %q", q.query, p.Query)
+	}
+	if p.Series != 3 {
+		t.Fatalf("expected 3 series, got %d", p.Series)
+	}
+	if p.LimitBytes != 5242880 {
+		t.Fatalf("expected limit of 5242880 bytes, got %d", p.LimitBytes)
+	}
+
+	expectedSize := len(`alerts{_id="123",alertname="Watchdog",alertstate="firing"} 1 1000`+"\n") +
+		len(`cluster_version{_id="123",version="4.7.0"} 3 1000`+"\n") +
+		len(`cluster_version{_id="123",version="4.8.0"} 2 1000`+"\n")
+	if p.SizeBytes != expectedSize {
+		t.Fatalf("expected size of %d bytes, got %d", expectedSize, p.SizeBytes)
+	}
+
+	expectedMetrics := []MetricSummary{
+		{Name: "alerts", Series: 1},
+		{Name: "cluster_version", Series: 2},
+	}
+	if !reflect.DeepEqual(expectedMetrics, p.Metrics) {
+		t.Fatalf("expected metrics %v, got %v", expectedMetrics, p.Metrics)
+	}
+
+	expectedLabelSets := []map[string]string{
+		{"__name__": "alerts", "_id": "123", "alertname": "Watchdog", "alertstate": "firing"},
+		{"__name__": "cluster_version", "_id": "123", "version": "4.7.0"},
+		{"__name__": "cluster_version", "_id": "123", "version": "4.8.0"},
+	}
+	if !reflect.DeepEqual(expectedLabelSets, p.LabelSets) {
+		t.Fatalf("expected label sets %v, got %v", expectedLabelSets, p.LabelSets)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceCAFile           = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+)
+
+// PrometheusQuerier runs instant queries against the API of the platform
+// Prometheus using the service account of the operator. Like the Telemeter
+// client, it doesn't go through Thanos Querier which also exposes the user
+// workload series.
+type PrometheusQuerier struct {
+	url       *url.URL
+	client    *http.Client
+	tokenFile string
+}
+
+// NewPrometheusQuerier returns a querier for the platform Prometheus service
+// in the given namespace.
+func NewPrometheusQuerier(namespace string) (*PrometheusQuerier, error) {
+	u, err := url.Parse(fmt.Sprintf("https://prometheus-k8s.%s.svc:9091", namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := ioutil.ReadFile(serviceCAFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading service CA failed")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.Errorf("no certificate found in %s", serviceCAFile)
+	}
+
+	return &PrometheusQuerier{
+		url: u,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		},
+		tokenFile: serviceAccountTokenFile,
+	}, nil
+}
+
+// Query implements the Querier interface.
+func (q *PrometheusQuerier) Query(ctx context.Context, query string) (model.Vector, error) {
+	u := *q.url
+	u.Path = "/api/v1/query"
+	u.RawQuery = url.Values{"query": []string{query}}.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	// The token is read for every request since it can be rotated.
+	token, err := ioutil.ReadFile(q.tokenFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading service account token failed")
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeVector(resp)
+}
+
+// apiResponse is the envelope of the Prometheus HTTP API responses.
+type apiResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType model.ValueType `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func decodeVector(resp *http.Response) (model.Vector, error) {
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response failed")
+	}
+
+	var r apiResponse
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, errors.Errorf("unexpected response (status %d): %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	if r.Status != "success" {
+		return nil, errors.Errorf("query failed (status %d): %s", resp.StatusCode, r.Error)
+	}
+	if r.Data.ResultType != model.ValVector {
+		return nil, errors.Errorf("unexpected result type %q", r.Data.ResultType)
+	}
+
+	var v model.Vector
+	if err := json.Unmarshal(r.Data.Result, &v); err != nil {
+		return nil, errors.Wrap(err, "decoding vector failed")
+	}
+	return v, nil
+}
@@ -191,3 +191,106 @@ func TestPrometheusAlertmanagerAntiAffinity(t *testing.T) {
 		t.Fatal("Can not find pods: prometheus-k8s or alertmanager-main")
 	}
 }
+
+func TestPrometheusThanosObjectStorage(t *testing.T) {
+	// The FILESYSTEM provider writes the bucket to the Prometheus volume,
+	// which the sidecar mounts when the object storage is configured.
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "thanos-objstore-e2e",
+			Namespace: f.Ns,
+		},
+		StringData: map[string]string{
+			"thanos.yaml": `type: FILESYSTEM
+config:
+  directory: /prometheus/thanos-bucket
+`,
+		},
+	}
+	if _, err := f.KubeClient.CoreV1().Secrets(f.Ns).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := f.KubeClient.CoreV1().Secrets(f.Ns).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-monitoring-config",
+			Namespace: f.Ns,
+		},
+		Data: map[string]string{
+			"config.yaml": `prometheusK8s:
+  thanosObjectStorage:
+    name: thanos-objstore-e2e
+    key: thanos.yaml
+`,
+		},
+	}
+	if err := f.OperatorClient.CreateOrUpdateConfigMap(cm); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cm.Data["config.yaml"] = ""
+		if err := f.OperatorClient.CreateOrUpdateConfigMap(cm); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	err := framework.Poll(time.Second, 5*time.Minute, func() error {
+		sts, err := f.KubeClient.AppsV1().StatefulSets(f.Ns).Get(context.TODO(), "prometheus-k8s", metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		for _, c := range sts.Spec.Template.Spec.Containers {
+			switch c.Name {
+			case "prometheus":
+				if !containsArg(c.Args, "--storage.tsdb.max-block-duration=2h") {
+					return errors.New("expected the compaction of Prometheus to be disabled")
+				}
+			case "thanos-sidecar":
+				if !containsArg(c.Args, "--objstore.config=$(OBJSTORE_CONFIG)") {
+					return errors.New("expected the Thanos sidecar to upload the blocks")
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.OperatorClient.WaitForStatefulsetRollout(&appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prometheus-k8s",
+			Namespace: f.Ns,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The shipper metrics are only registered when the uploads are enabled,
+	// the first block is cut after 2 hours.
+	f.ThanosQuerierClient.WaitForQueryReturn(
+		t, 10*time.Minute, `count(thanos_shipper_uploads_total{service="prometheus-k8s-thanos-sidecar",namespace="openshift-monitoring"})`,
+		func(i int) error {
+			if i != 2 {
+				return fmt.Errorf("expected 2 Thanos sidecars shipping blocks but got %d", i)
+			}
+			return nil
+		},
+	)
+}
+
+func containsArg(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/cluster-monitoring-operator/test/e2e/framework"
+)
+
+// The telemetry preview should be protected by kube-rbac-proxy.
+func TestTelemetryPreviewKubeRbacProxy(t *testing.T) {
+	const testNs = "test-telemetry-preview"
+
+	host, cleanUp, err := f.ForwardPort(t, "cluster-monitoring-operator", 8443)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+
+	t.Logf("creating namespace %q", testNs)
+	ns := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: testNs,
+		},
+	}
+	_, err = f.KubeClient.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := f.KubeClient.CoreV1().Namespaces().Delete(context.TODO(), testNs, metav1.DeleteOptions{})
+		t.Logf("deleting namespace %s: %v", testNs, err)
+	}()
+
+	// Non-resource URLs can only be granted by cluster role bindings.
+	clients := make(map[string]*framework.PrometheusClient)
+	for sa, cr := range map[string]string{
+		"viewer":    "cluster-monitoring-view",
+		"anonymous": "",
+	} {
+		t.Logf("creating service account %q", sa)
+		_, err = f.CreateServiceAccount(testNs, sa)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cr != "" {
+			t.Logf("creating cluster role binding %q -> %q", sa, cr)
+			deleteBinding, err := f.CreateClusterRoleBinding(testNs, sa, cr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer deleteBinding()
+		}
+
+		err = framework.Poll(5*time.Second, 5*time.Minute, func() error {
+			token, err := f.GetServiceAccountToken(testNs, sa)
+			if err != nil {
+				return err
+			}
+			clients[sa] = framework.NewPrometheusClient(host, token)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for sa, expectedCode := range map[string]int{
+		"anonymous": http.StatusForbidden,
+		"viewer":    http.StatusOK,
+	} {
+		t.Logf("getting the telemetry preview as %q", sa)
+		err = framework.Poll(5*time.Second, 5*time.Minute, func() error {
+			resp, err := clients[sa].Do("GET", "/telemetry/preview", nil)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return err
+			}
+
+			if resp.StatusCode != expectedCode {
+				return fmt.Errorf("expecting %d status code, got %d (%q)", expectedCode, resp.StatusCode, framework.ClampMax(b))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}